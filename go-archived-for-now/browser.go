@@ -0,0 +1,554 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"arrogance/firebase"
+
+	"cloud.google.com/go/firestore"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// browserScreen lists the collections under a document, or the root
+// collections for an empty path, or the documents of a collection. Rows open
+// another browserScreen or a documentTreeScreen, so any path can be reached.
+type browserScreen struct {
+	frame
+	storeSvc firebase.DocumentStore
+	keys     browserKeyMap
+
+	path        string
+	collections []string
+	docs        []firebase.Document
+	table       table.Model
+	loading     bool
+	err         string
+}
+
+// browserKeyMap holds the keys of the browser screen
+type browserKeyMap struct {
+	Up   key.Binding
+	Down key.Binding
+	Open key.Binding
+}
+
+var browserKeys = browserKeyMap{
+	Up:   key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "up")),
+	Down: key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "down")),
+	Open: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "open")),
+}
+
+// ShortHelp returns the keys shown in the footer
+func (k browserKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Up, k.Down, k.Open}
+}
+
+// FullHelp returns every key of the screen
+func (k browserKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{{k.Up, k.Down, k.Open}}
+}
+
+// BrowserLoadedMsg is sent when the collections or documents of a path are loaded
+type browserLoadedMsg struct {
+	path        string
+	collections []string
+	docs        []firebase.Document
+	err         error
+}
+
+// newBrowserScreen creates the browser for path: empty for the root
+// collections, a document path for its subcollections or a collection path
+// for its documents
+func newBrowserScreen(storeSvc firebase.DocumentStore, path string) browserScreen {
+	s := browserScreen{
+		storeSvc: storeSvc,
+		keys:     browserKeys,
+		path:     strings.Trim(path, "/"),
+		table:    withRowKeys(initUserTable(), browserKeys.Up, browserKeys.Down),
+		loading:  true,
+	}
+	s.table.SetColumns(s.columns())
+	return s
+}
+
+// listsDocuments reports whether the screen shows the documents of a collection
+func (s browserScreen) listsDocuments() bool {
+	return s.path != "" && strings.Count(s.path, "/")%2 == 0
+}
+
+// Init loads the screen when it's opened on top of another
+func (s browserScreen) Init() tea.Cmd {
+	return fetchBrowser(s.storeSvc, s.path)
+}
+
+// KeyMap returns the keys of the browser screen
+func (s browserScreen) KeyMap() help.KeyMap {
+	k := s.keys
+	hasRows := !s.loading && s.err == "" && len(s.table.Rows()) > 0
+	k.Up.SetEnabled(hasRows)
+	k.Down.SetEnabled(hasRows)
+	k.Open.SetEnabled(hasRows)
+	return k
+}
+
+// Loading reports whether the path is loading
+func (s browserScreen) Loading() bool {
+	return s.loading
+}
+
+// fetchBrowser loads the subcollections of a document path, or the documents
+// of a collection path
+func fetchBrowser(storeSvc firebase.DocumentStore, path string) tea.Cmd {
+	return func() tea.Msg {
+		msg := browserLoadedMsg{path: path}
+		if storeSvc == nil {
+			msg.err = errors.New("firestore service not initialized")
+			return msg
+		}
+
+		ctx := context.Background()
+		if path == "" || strings.Count(path, "/")%2 == 1 {
+			msg.collections, msg.err = storeSvc.ListCollections(ctx, path)
+			if msg.err != nil {
+				msg.err = fmt.Errorf("failed to list collections: %w", msg.err)
+			}
+			return msg
+		}
+
+		listed, err := storeSvc.List(ctx, path)
+		if err != nil {
+			msg.err = fmt.Errorf("failed to list %s: %w", path, err)
+			return msg
+		}
+		for _, data := range listed {
+			// List adds the document ID to the fields
+			id, _ := data["id"].(string)
+			delete(data, "id")
+			msg.docs = append(msg.docs, firebase.Document{ID: id, Path: path + "/" + id, Data: data})
+		}
+		return msg
+	}
+}
+
+// columns returns the table columns for collections or documents
+func (s browserScreen) columns() []table.Column {
+	if s.listsDocuments() {
+		return []table.Column{{Title: "ID", Width: 30}, {Title: "Fields", Width: 80}}
+	}
+	return []table.Column{{Title: "Collection", Width: 30}, {Title: "Path", Width: 80}}
+}
+
+// rows returns a table row for each collection or document
+func (s browserScreen) rows() []table.Row {
+	var rows []table.Row
+	if s.listsDocuments() {
+		for _, d := range s.docs {
+			rows = append(rows, table.Row{d.ID, fieldSummary(d.Data)})
+		}
+		return rows
+	}
+	for _, id := range s.collections {
+		rows = append(rows, table.Row{id, s.childPath(id)})
+	}
+	return rows
+}
+
+// childPath returns the path of a collection or document under the screen's path
+func (s browserScreen) childPath(id string) string {
+	if s.path == "" {
+		return id
+	}
+	return s.path + "/" + id
+}
+
+// Update handles loading and opening rows
+func (s browserScreen) Update(msg tea.Msg) (Screen, tea.Cmd) {
+	s.track(msg)
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.table.SetHeight(max(3, s.height-14))
+
+	case screenActivatedMsg:
+		// Reload whenever the tab is shown
+		s.loading = true
+		return s, fetchBrowser(s.storeSvc, s.path)
+
+	case browserLoadedMsg:
+		// Ignore other paths
+		if msg.path != s.path {
+			return s, nil
+		}
+		s.loading = false
+		s.err = ""
+		if msg.err != nil {
+			s.err = msg.err.Error()
+			return s, nil
+		}
+		s.collections = msg.collections
+		s.docs = msg.docs
+		s.table = setSortedRows(s.table, s.columns(), s.rows())
+		s.table.GotoTop()
+
+	case tea.KeyMsg:
+		if s.loading {
+			return s, nil
+		}
+		if key.Matches(msg, s.keys.Open) {
+			return s, s.open()
+		}
+		var cmd tea.Cmd
+		s.table, cmd = s.table.Update(msg)
+		return s, cmd
+	}
+
+	return s, nil
+}
+
+// open opens the collection or document under the cursor
+func (s browserScreen) open() tea.Cmd {
+	i := s.table.Cursor()
+	if s.listsDocuments() {
+		if i < 0 || i >= len(s.docs) {
+			return nil
+		}
+		return pushScreen(newDocumentTreeScreen(s.storeSvc, s.docs[i].Path))
+	}
+	if i < 0 || i >= len(s.collections) {
+		return nil
+	}
+	return pushScreen(newBrowserScreen(s.storeSvc, s.childPath(s.collections[i])))
+}
+
+// View shows the collections or documents of the path
+func (s browserScreen) View() string {
+	where := "/" + s.path
+	if s.loading {
+		return s.statusBox(loadingStyle.Render(s.spinner() + " Loading " + where + "..."))
+	}
+	if s.err != "" {
+		return s.statusBox(errorStyle.Render("Error loading " + where + ": " + s.err))
+	}
+
+	noun := "collections"
+	if s.listsDocuments() {
+		noun = "documents"
+	}
+	if len(s.table.Rows()) == 0 {
+		switch {
+		case s.path == "":
+			return s.statusBox("No collections found in Firestore.")
+		case s.listsDocuments():
+			return s.statusBox("No documents in " + where + ".")
+		default:
+			return s.statusBox("No subcollections under " + where + ".")
+		}
+	}
+
+	status := fmt.Sprintf("\n%s · %d %s", where, len(s.table.Rows()), noun)
+	return lipgloss.NewStyle().
+		Width(s.width-8).
+		Padding(1, 2).
+		Render(s.table.View() + status)
+}
+
+// fieldSummary shows the top-level fields of a document on one line
+func fieldSummary(data map[string]interface{}) string {
+	keys := sortedKeys(data)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		value, kind := treeValue(data[k])
+		if value == "" {
+			value = kind
+		}
+		parts[i] = k + ": " + value
+	}
+	return strings.Join(parts, " · ")
+}
+
+// DocumentTreeMsg is sent when a document and its subcollections are loaded
+type documentTreeMsg struct {
+	path           string
+	doc            firebase.Document
+	subcollections []string
+	err            error
+}
+
+// documentTreeScreen shows every field of any document as a tree, with the
+// type of each value, and leads on to its editor and subcollections
+type documentTreeScreen struct {
+	frame
+	storeSvc firebase.DocumentStore
+	keys     documentTreeKeyMap
+
+	path           string
+	doc            firebase.Document
+	subcollections []string
+	loading        bool
+	err            string
+	viewport       viewport.Model
+}
+
+// documentTreeKeyMap holds the keys of the document tree screen
+type documentTreeKeyMap struct {
+	Up             key.Binding
+	Down           key.Binding
+	Edit           key.Binding
+	Subcollections key.Binding
+}
+
+var documentTreeKeys = documentTreeKeyMap{
+	Up:             key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "scroll up")),
+	Down:           key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "scroll down")),
+	Edit:           key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit")),
+	Subcollections: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "subcollections")),
+}
+
+// ShortHelp returns the keys shown in the footer
+func (k documentTreeKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Up, k.Down, k.Edit, k.Subcollections}
+}
+
+// FullHelp returns every key of the screen
+func (k documentTreeKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{{k.Up, k.Down, k.Edit, k.Subcollections}}
+}
+
+// newDocumentTreeScreen creates the tree screen for the document at path
+func newDocumentTreeScreen(storeSvc firebase.DocumentStore, path string) documentTreeScreen {
+	return documentTreeScreen{
+		storeSvc: storeSvc,
+		keys:     documentTreeKeys,
+		path:     path,
+		loading:  true,
+		viewport: withScrollKeys(viewport.New(0, 0), documentTreeKeys.Up, documentTreeKeys.Down),
+	}
+}
+
+// Init loads the document and its subcollections
+func (s documentTreeScreen) Init() tea.Cmd {
+	return fetchDocumentTree(s.storeSvc, s.path)
+}
+
+// KeyMap returns the keys of the document tree screen
+func (s documentTreeScreen) KeyMap() help.KeyMap {
+	k := s.keys
+	k.Edit.SetEnabled(!s.loading && s.err == "")
+	k.Subcollections.SetEnabled(!s.loading && len(s.subcollections) > 0)
+	return k
+}
+
+// Loading reports whether the document is loading
+func (s documentTreeScreen) Loading() bool {
+	return s.loading
+}
+
+// fetchDocumentTree loads a document by path, along with the IDs of its subcollections
+func fetchDocumentTree(storeSvc firebase.DocumentStore, path string) tea.Cmd {
+	return func() tea.Msg {
+		msg := documentTreeMsg{path: path}
+		if storeSvc == nil {
+			msg.err = errors.New("firestore service not initialized")
+			return msg
+		}
+		collection, id, err := firebase.SplitDocumentPath(path)
+		if err != nil {
+			msg.err = err
+			return msg
+		}
+
+		// Load the document again, as List hides any field named id and
+		// the editor needs its update time
+		ctx := context.Background()
+		msg.doc, err = storeSvc.GetDocument(ctx, collection, id)
+		if err != nil {
+			msg.err = fmt.Errorf("failed to fetch %s: %w", path, err)
+			return msg
+		}
+		msg.subcollections, err = storeSvc.ListCollections(ctx, path)
+		if err != nil {
+			msg.err = fmt.Errorf("failed to list the subcollections of %s: %w", path, err)
+		}
+		return msg
+	}
+}
+
+// Update handles loading the document, scrolling and reloading it once edited
+func (s documentTreeScreen) Update(msg tea.Msg) (Screen, tea.Cmd) {
+	s.track(msg)
+
+	switch msg := msg.(type) {
+	case documentEditSavedMsg:
+		if msg.path != s.path || msg.err != nil {
+			return s, nil
+		}
+		s.loading = true
+		s.err = ""
+		return s, fetchDocumentTree(s.storeSvc, s.path)
+
+	case tea.WindowSizeMsg:
+		s.viewport.Width = s.width - 8
+		s.viewport.Height = s.height - 12
+		s.viewport.SetContent(s.render())
+
+	case documentTreeMsg:
+		// Ignore other documents
+		if msg.path != s.path {
+			return s, nil
+		}
+		s.loading = false
+		if msg.err != nil {
+			s.err = msg.err.Error()
+			return s, nil
+		}
+		s.doc = msg.doc
+		s.subcollections = msg.subcollections
+		s.viewport.SetContent(s.render())
+
+	case tea.KeyMsg:
+		if key.Matches(msg, s.keys.Edit) {
+			if s.loading || s.err != "" {
+				return s, nil
+			}
+			return s, pushScreen(newDocumentEditScreen(s.storeSvc, s.doc))
+		}
+		if key.Matches(msg, s.keys.Subcollections) {
+			if s.loading || len(s.subcollections) == 0 {
+				return s, nil
+			}
+			return s, pushScreen(newBrowserScreen(s.storeSvc, s.path))
+		}
+		var cmd tea.Cmd
+		s.viewport, cmd = s.viewport.Update(msg)
+		return s, cmd
+	}
+
+	return s, nil
+}
+
+// View shows the document tree
+func (s documentTreeScreen) View() string {
+	if s.loading {
+		return s.statusBox(loadingStyle.Render(s.spinner() + " Loading " + s.path + "..."))
+	}
+	if s.err != "" {
+		return s.statusBox(errorStyle.Render("Error loading document: " + s.err))
+	}
+
+	return lipgloss.NewStyle().
+		Height(s.height-12).
+		Padding(0, 2).
+		Render(s.viewport.View())
+}
+
+// render renders the scrollable body of the document tree screen
+func (s documentTreeScreen) render() string {
+	var sb strings.Builder
+	sb.WriteString(sectionStyle.Render(s.path) + "\n")
+	sb.WriteString(labelStyle.Render("Created") + formatTime(s.doc.CreateTime) + "\n")
+	sb.WriteString(labelStyle.Render("Last updated") + formatTime(s.doc.UpdateTime) + "\n\n")
+	sb.WriteString(renderFieldTree(s.doc.Data))
+
+	sb.WriteString("\n" + sectionStyle.Render(fmt.Sprintf("Subcollections (%d)", len(s.subcollections))) + "\n")
+	if len(s.subcollections) == 0 {
+		sb.WriteString("-\n")
+	}
+	for _, sub := range s.subcollections {
+		sb.WriteString(s.path + "/" + sub + "\n")
+	}
+	return sb.String()
+}
+
+// renderFieldTree renders document fields as an indented tree in key order,
+// with the Firestore type of every value
+func renderFieldTree(data map[string]interface{}) string {
+	if len(data) == 0 {
+		return "(no fields)\n"
+	}
+
+	typeStyle := labelStyle.UnsetWidth()
+	var sb strings.Builder
+	var walk func(depth int, label string, value interface{})
+	walk = func(depth int, label string, value interface{}) {
+		text, kind := treeValue(value)
+		line := strings.Repeat("  ", depth) + label + ": "
+		if text != "" {
+			line += text + "  "
+		}
+		sb.WriteString(line + typeStyle.Render(kind) + "\n")
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for _, k := range sortedKeys(v) {
+				walk(depth+1, k, v[k])
+			}
+		case []interface{}:
+			for i, elem := range v {
+				walk(depth+1, fmt.Sprintf("[%d]", i), elem)
+			}
+		}
+	}
+	for _, k := range sortedKeys(data) {
+		walk(0, k, data[k])
+	}
+	return sb.String()
+}
+
+// treeValue formats a Firestore value and names its type. Maps and arrays
+// have no text of their own; their type includes their size.
+func treeValue(value interface{}) (text, kind string) {
+	switch v := value.(type) {
+	case nil:
+		return "null", "null"
+	case bool:
+		return strconv.FormatBool(v), "boolean"
+	case int64:
+		return strconv.FormatInt(v, 10), "integer"
+	case int:
+		return strconv.Itoa(v), "integer"
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), "number"
+	case string:
+		return strconv.Quote(v), "string"
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano), "timestamp"
+	case *firestore.DocumentRef:
+		path, _ := firebase.ExportValue(v).(string)
+		return path, "reference"
+	case *latlng.LatLng:
+		if v == nil {
+			return "null", "geopoint"
+		}
+		return fmt.Sprintf("%g, %g", v.Latitude, v.Longitude), "geopoint"
+	case []byte:
+		return truncate(base64.StdEncoding.EncodeToString(v), maxCellWidth), fmt.Sprintf("bytes · %d", len(v))
+	case []interface{}:
+		return "", fmt.Sprintf("array · %d", len(v))
+	case map[string]interface{}:
+		return "", fmt.Sprintf("map · %d", len(v))
+	}
+	return fmt.Sprint(value), fmt.Sprintf("%T", value)
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
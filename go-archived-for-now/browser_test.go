@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	tea "github.com/charmbracelet/bubbletea"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// moveTo moves the cursor of the browser on top to the row whose first cell is id
+func moveTo(t *testing.T, m Model, id string) Model {
+	t.Helper()
+	s, ok := m.router.top().(browserScreen)
+	if !ok {
+		t.Fatalf("Expected a browser screen, got %T", m.router.top())
+	}
+	for i, row := range s.table.Rows() {
+		if row[0] == id {
+			for range i {
+				m = settle(t, m, tea.KeyMsg{Type: tea.KeyDown})
+			}
+			return m
+		}
+	}
+	t.Fatalf("No row %q in %s", id, s.path)
+	return m
+}
+
+// TestBrowser tests drilling from the root collections down to a subcollection
+func TestBrowser(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, BrowseTab)
+
+	view := m.View()
+	for _, want := range []string{"profiles", "routines", "exercises", "histories"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Root collections do not include %q", want)
+		}
+	}
+
+	m = moveTo(t, m, "profiles")
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	m = moveTo(t, m, "pMaya01")
+	if !strings.Contains(m.View(), `name: "Maya"`) {
+		t.Error("Expected a summary of each profile's fields")
+	}
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	tree, ok := m.router.top().(documentTreeScreen)
+	if !ok || tree.loading || tree.err != "" {
+		t.Fatalf("Expected the profile to open, got %T", m.router.top())
+	}
+	body := tree.render()
+	for _, want := range []string{`name: "Maya"`, "createdAt: 2024-01-08T09:20:00Z", "timestamp", "Subcollections (1)", "profiles/pMaya01/records"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Document tree does not contain %q", want)
+		}
+	}
+
+	// Enter lists the subcollections, which open like root collections
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	s, ok := m.router.top().(browserScreen)
+	if !ok || s.path != "profiles/pMaya01/records" || len(s.docs) != 3 {
+		t.Fatalf("Expected the 3 records of pMaya01, got %T", m.router.top())
+	}
+
+	// Back returns through each level
+	for range 4 {
+		m = settle(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	}
+	if m.router.depth() != 1 {
+		t.Errorf("Expected to be back at the root collections, depth %d", m.router.depth())
+	}
+}
+
+// TestRenderFieldTree tests the text and type shown for each kind of value
+func TestRenderFieldTree(t *testing.T) {
+	tree := renderFieldTree(map[string]interface{}{
+		"when":  time.Date(2024, 4, 11, 7, 5, 0, 0, time.UTC),
+		"ref":   &firestore.DocumentRef{Path: "projects/p/databases/(default)/documents/exercises/eBench01"},
+		"where": &latlng.LatLng{Latitude: -6.2, Longitude: 106.8},
+		"reps":  int64(5),
+		"sets":  []interface{}{"a", 2.5},
+		"meta":  map[string]interface{}{"draft": true, "note": nil},
+	})
+
+	for _, want := range []string{
+		"meta: map · 2",
+		"  draft: true",
+		"  note: null",
+		"ref: exercises/eBench01  reference",
+		"reps: 5  integer",
+		"sets: array · 2",
+		`  [0]: "a"  string`,
+		"  [1]: 2.5  number",
+		"when: 2024-04-11T07:05:00Z  timestamp",
+		"where: -6.2, 106.8  geopoint",
+	} {
+		if !strings.Contains(tree, want) {
+			t.Errorf("Tree does not contain %q:\n%s", want, tree)
+		}
+	}
+	if strings.Index(tree, "meta") > strings.Index(tree, "when") {
+		t.Error("Expected fields in key order")
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// clipboardOutput receives the OSC52 sequences that copy text. Terminals read
+// them from stderr too, which the renderer doesn't draw on.
+var clipboardOutput io.Writer = os.Stderr
+
+// ClipboardCopiedMsg is sent once text has been sent to the clipboard
+type clipboardCopiedMsg struct {
+	err error
+}
+
+// copyToClipboard copies text to the clipboard of the terminal with an OSC52
+// escape sequence, which also works over SSH when the terminal supports it
+func copyToClipboard(text string) tea.Cmd {
+	return func() tea.Msg {
+		seq := osc52.New(text)
+		switch {
+		case os.Getenv("TMUX") != "":
+			seq = seq.Tmux()
+		case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+			seq = seq.Screen()
+		}
+		_, err := seq.WriteTo(clipboardOutput)
+		return clipboardCopiedMsg{err: err}
+	}
+}
@@ -0,0 +1,553 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"arrogance/firebase"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// collectionColumn is a column of a collection table
+type collectionColumn struct {
+	title string
+	width int
+	// value renders the cell of a document
+	value func(s collectionScreen, d firebase.Document) string
+	// sortKey orders documents by this column, defaulting to the lowercased value
+	sortKey func(s collectionScreen, d firebase.Document) string
+}
+
+// collectionSpec describes how a Firestore collection is shown in its tab
+type collectionSpec struct {
+	collection string
+	title      string
+	columns    []collectionColumn
+
+	// item converts a document to the item its columns show
+	item func(d firebase.Document) (collectionItem, error)
+
+	// Initial sort column and direction
+	sort tableSort
+
+	// sections listed under each document when it's opened
+	sections []documentSection
+
+	// lookup resolves the names of documents referenced by the loaded ones,
+	// keyed by document ID
+	lookup func(ctx context.Context, storeSvc firebase.DocumentStore, docs []firebase.Document) (map[string]string, error)
+
+	// preview renders the selected document under the table, using at most
+	// previewHeight lines
+	preview       func(s collectionScreen, d firebase.Document) string
+	previewHeight int
+}
+
+// collectionScreen is a tab showing one collection as a sortable table that
+// can be filtered to a single user. Documents open in a documentScreen.
+type collectionScreen struct {
+	frame
+	authSvc  firebase.UserStore
+	storeSvc firebase.DocumentStore
+	spec     *collectionSpec
+	keys     collectionKeyMap
+
+	// Keys of the user filter input
+	filterKeys inputKeyMap
+
+	table   table.Model
+	docs    []firebase.Document
+	items   map[string]collectionItem // document path -> item
+	owners  map[string]string         // uid -> email
+	lookups map[string]string         // referenced document ID -> name
+	sort    tableSort
+	loading bool
+	err     string
+
+	// Per-user filter, by UID or email
+	owner       string
+	filterInput textinput.Model
+	filtering   bool
+}
+
+// collectionKeyMap holds the keys of a collection screen
+type collectionKeyMap struct {
+	Up      key.Binding
+	Down    key.Binding
+	Open    key.Binding
+	Sort    key.Binding
+	Reverse key.Binding
+	Filter  key.Binding
+	Export  key.Binding
+}
+
+var collectionKeys = collectionKeyMap{
+	Up:      key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "up")),
+	Down:    key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "down")),
+	Open:    key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "open")),
+	Sort:    key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "sort column")),
+	Reverse: key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "reverse sort")),
+	Filter:  key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "filter by user")),
+	Export:  key.NewBinding(key.WithKeys("E"), key.WithHelp("E", "export")),
+}
+
+// ShortHelp returns the keys shown in the footer
+func (k collectionKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Open, k.Sort, k.Reverse, k.Filter}
+}
+
+// FullHelp returns every key of the screen
+func (k collectionKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{{k.Up, k.Down, k.Open}, {k.Sort, k.Reverse, k.Filter}, {k.Export}}
+}
+
+// CollectionLoadedMsg is sent when a collection tab's documents are loaded
+type collectionLoadedMsg struct {
+	collection string
+	owner      string
+	docs       []firebase.Document
+	items      map[string]collectionItem
+	owners     map[string]string
+	lookups    map[string]string
+}
+
+// CollectionErrorMsg is sent when there's an error loading a collection tab
+type collectionErrorMsg struct {
+	collection string
+	owner      string
+	err        error
+}
+
+// newCollectionScreen creates the screen for a collection spec. Documents are
+// loaded when the tab is shown.
+func newCollectionScreen(spec *collectionSpec, authSvc firebase.UserStore, storeSvc firebase.DocumentStore) collectionScreen {
+	s := collectionScreen{
+		authSvc:    authSvc,
+		storeSvc:   storeSvc,
+		spec:       spec,
+		keys:       collectionKeys,
+		filterKeys: filterKeys,
+		table:      withRowKeys(initUserTable(), collectionKeys.Up, collectionKeys.Down),
+		sort:       spec.sort,
+	}
+	s.table.SetColumns(s.columns())
+	return s
+}
+
+// Init has nothing to load until the tab is shown
+func (s collectionScreen) Init() tea.Cmd {
+	return nil
+}
+
+// KeyMap returns the keys of the collection screen, or those of the user
+// filter while it's open
+func (s collectionScreen) KeyMap() help.KeyMap {
+	if s.filtering {
+		return s.filterKeys
+	}
+	k := s.keys
+	ready := !s.loading && s.err == ""
+	hasRows := ready && len(s.docs) > 0
+	k.Up.SetEnabled(hasRows)
+	k.Down.SetEnabled(hasRows)
+	k.Open.SetEnabled(hasRows)
+	k.Sort.SetEnabled(hasRows)
+	k.Reverse.SetEnabled(hasRows)
+	k.Filter.SetEnabled(ready)
+	k.Export.SetEnabled(hasRows)
+	return k
+}
+
+// Loading reports whether documents are loading
+func (s collectionScreen) Loading() bool {
+	return s.loading
+}
+
+// CapturesInput keeps every key on the screen while typing a user filter
+func (s collectionScreen) CapturesInput() bool {
+	return s.filtering
+}
+
+// fetchCollection loads the documents of a collection, optionally only those
+// of one user, along with their owners' emails and any referenced names
+func fetchCollection(spec *collectionSpec, authSvc firebase.UserStore, storeSvc firebase.DocumentStore, owner string) tea.Cmd {
+	return func() tea.Msg {
+		fail := func(err error) tea.Msg {
+			return collectionErrorMsg{collection: spec.collection, owner: owner, err: err}
+		}
+		if storeSvc == nil {
+			return fail(errors.New("firestore service not initialized"))
+		}
+
+		ctx := context.Background()
+
+		q := firebase.From(spec.collection)
+		if owner != "" {
+			uid := owner
+			if strings.Contains(owner, "@") {
+				if authSvc == nil {
+					return fail(errors.New("auth service not initialized"))
+				}
+				user, err := authSvc.GetUserByEmail(ctx, owner)
+				if err != nil {
+					return fail(fmt.Errorf("failed to find user %s: %w", owner, err))
+				}
+				uid = user.UID
+			}
+			q = q.Where("uid", "==", uid)
+		}
+
+		docs, err := storeSvc.Query(ctx, q)
+		if err != nil {
+			return fail(fmt.Errorf("failed to fetch %s: %w", spec.collection, err))
+		}
+
+		// Fields of the wrong type are shown as unset
+		items := make(map[string]collectionItem, len(docs))
+		for _, d := range docs {
+			items[d.Path], _ = spec.item(d)
+		}
+
+		// Owners that can't be looked up are shown by UID
+		owners := make(map[string]string)
+		for _, it := range items {
+			uid := it.uid
+			if _, seen := owners[uid]; uid == "" || seen || authSvc == nil {
+				continue
+			}
+			owners[uid] = ""
+			if user, err := authSvc.GetUser(ctx, uid); err == nil {
+				owners[uid] = user.Email
+			}
+		}
+
+		var lookups map[string]string
+		if spec.lookup != nil {
+			lookups, err = spec.lookup(ctx, storeSvc, docs)
+			if err != nil {
+				return fail(err)
+			}
+		}
+
+		return collectionLoadedMsg{
+			collection: spec.collection,
+			owner:      owner,
+			docs:       docs,
+			items:      items,
+			owners:     owners,
+			lookups:    lookups,
+		}
+	}
+}
+
+// columns returns the table columns, marking the sorted one
+func (s collectionScreen) columns() []table.Column {
+	columns := make([]table.Column, len(s.spec.columns))
+	for i, c := range s.spec.columns {
+		columns[i] = table.Column{Title: c.title, Width: c.width}
+	}
+	return s.sort.headers(columns)
+}
+
+// sorted orders the documents by the current sort column and refreshes the rows
+func (s collectionScreen) sorted() collectionScreen {
+	docs := make([]firebase.Document, len(s.docs))
+	copy(docs, s.docs)
+	sortItems(docs, s.sort, s.sortKey, func(d firebase.Document) string { return d.Path })
+	s.docs = docs
+
+	rows := make([]table.Row, 0, len(docs))
+	for _, d := range docs {
+		row := make(table.Row, len(s.spec.columns))
+		for i, c := range s.spec.columns {
+			row[i] = c.value(s, d)
+		}
+		rows = append(rows, row)
+	}
+
+	s.table = setSortedRows(s.table, s.columns(), rows)
+	return s
+}
+
+// sortKey returns the key a document is sorted by in column col
+func (s collectionScreen) sortKey(d firebase.Document, col int) string {
+	c := s.spec.columns[col]
+	if c.sortKey != nil {
+		return c.sortKey(s, d)
+	}
+	return strings.ToLower(c.value(s, d))
+}
+
+// selected returns the document under the table cursor
+func (s collectionScreen) selected() (firebase.Document, bool) {
+	i := s.table.Cursor()
+	if i < 0 || i >= len(s.docs) {
+		return firebase.Document{}, false
+	}
+	return s.docs[i], true
+}
+
+// ownerOf returns the email of a document's owner, or its UID when the
+// owner can't be found
+func (s collectionScreen) ownerOf(d firebase.Document) string {
+	uid := s.items[d.Path].uid
+	if email := s.owners[uid]; email != "" {
+		return email
+	}
+	if uid == "" {
+		return "-"
+	}
+	return uid
+}
+
+// Update handles loading, sorting, filtering and opening documents
+func (s collectionScreen) Update(msg tea.Msg) (Screen, tea.Cmd) {
+	s.track(msg)
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.table.SetHeight(max(3, s.height-14-s.spec.previewHeight))
+
+	case screenActivatedMsg:
+		// Reload the documents whenever the tab is shown
+		s.loading = true
+		return s, fetchCollection(s.spec, s.authSvc, s.storeSvc, s.owner)
+
+	case collectionLoadedMsg:
+		// Ignore other collections and results for a user filter that has since changed
+		if msg.collection != s.spec.collection || msg.owner != s.owner {
+			return s, nil
+		}
+		s.loading = false
+		s.err = ""
+		s.docs = msg.docs
+		s.items = msg.items
+		s.owners = msg.owners
+		s.lookups = msg.lookups
+		s = s.sorted()
+		s.table.GotoTop()
+
+	case collectionErrorMsg:
+		if msg.collection != s.spec.collection || msg.owner != s.owner {
+			return s, nil
+		}
+		s.loading = false
+		s.err = msg.err.Error()
+
+	case tea.KeyMsg:
+		return s.handleKey(msg)
+	}
+
+	return s, nil
+}
+
+// handleKey handles the keys of the collection screen
+func (s collectionScreen) handleKey(msg tea.KeyMsg) (Screen, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if s.filtering {
+		switch {
+		case key.Matches(msg, s.filterKeys.Cancel):
+			s.filtering = false
+			return s, nil
+		case key.Matches(msg, s.filterKeys.Submit):
+			// An empty filter shows every user's documents again
+			s.filtering = false
+			s.owner = strings.TrimSpace(s.filterInput.Value())
+			s.loading = true
+			return s, fetchCollection(s.spec, s.authSvc, s.storeSvc, s.owner)
+		}
+		s.filterInput, cmd = s.filterInput.Update(msg)
+		return s, cmd
+	}
+
+	if s.loading {
+		return s, nil
+	}
+
+	switch {
+	case key.Matches(msg, s.keys.Sort):
+		// Sort by the next column
+		s.sort = s.sort.nextColumn(len(s.spec.columns))
+		s = s.sorted()
+		s.table.GotoTop()
+		return s, nil
+	case key.Matches(msg, s.keys.Reverse):
+		// Reverse the sort order
+		s.sort = s.sort.reversed()
+		s = s.sorted()
+		s.table.GotoTop()
+		return s, nil
+	case key.Matches(msg, s.keys.Filter):
+		// Filter by user
+		input := textinput.New()
+		input.Placeholder = "UID or email, empty for all users"
+		input.SetValue(s.owner)
+		input.CharLimit = 256
+		input.Width = 40
+		input.Focus()
+		s.filterInput = input
+		s.filtering = true
+		return s, textinput.Blink
+	case key.Matches(msg, s.keys.Export):
+		if len(s.docs) > 0 {
+			return s, pushScreen(newExportScreen(s.spec.collection, documentRecords(s.docs), nil))
+		}
+		return s, nil
+	case key.Matches(msg, s.keys.Open):
+		if d, ok := s.selected(); ok {
+			return s, pushScreen(newDocumentScreen(s.storeSvc, d, s.ownerOf(d), s.lookups, s.spec.sections))
+		}
+		return s, nil
+	}
+
+	s.table, cmd = s.table.Update(msg)
+	return s, cmd
+}
+
+// View shows the collection table
+func (s collectionScreen) View() string {
+	noun := s.spec.collection
+
+	if s.loading {
+		return s.statusBox(loadingStyle.Render(s.spinner() + " Loading " + noun + "..."))
+	}
+	if s.err != "" {
+		return s.statusBox(errorStyle.Render("Error loading " + noun + ": " + s.err))
+	}
+	if len(s.docs) == 0 && !s.filtering {
+		empty := "No " + noun + " found in Firestore."
+		if s.owner != "" {
+			empty = fmt.Sprintf("No %s found for user %s.\n\nPress u to change the user filter.", noun, s.owner)
+		}
+		return s.statusBox(empty)
+	}
+
+	tableView := s.table.View()
+	status := fmt.Sprintf("\nTotal %s: %d", noun, len(s.docs))
+	if s.owner != "" {
+		status += " · user " + s.owner
+	}
+	if s.filtering {
+		status += "\n\nFilter by user: " + s.filterInput.View()
+	}
+
+	preview := ""
+	if d, ok := s.selected(); ok && s.spec.preview != nil && !s.filtering {
+		preview = "\n\n" + s.spec.preview(s, d)
+	}
+
+	return lipgloss.NewStyle().
+		Width(s.width-8).
+		Padding(1, 2).
+		Render(tableView + status + preview)
+}
+
+// documentField is a single, possibly nested, field of a document
+type documentField struct {
+	path  string
+	value interface{}
+}
+
+// flattenFields lists the fields of a document in path order, with nested
+// maps flattened into dotted paths
+func flattenFields(data map[string]interface{}) []documentField {
+	var fields []documentField
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for k, value := range m {
+			if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+				walk(prefix+k+".", nested)
+				continue
+			}
+			fields = append(fields, documentField{path: prefix + k, value: value})
+		}
+	}
+	walk("", data)
+
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].path < fields[j].path
+	})
+	return fields
+}
+
+// textColumn is a column showing a text field of the items
+func textColumn(title string, width int, text func(collectionItem) string) collectionColumn {
+	return collectionColumn{
+		title: title,
+		width: width,
+		value: func(s collectionScreen, d firebase.Document) string {
+			if t := text(s.items[d.Path]); t != "" {
+				return t
+			}
+			return "-"
+		},
+	}
+}
+
+// timeColumn is a column showing a timestamp field of the items
+func timeColumn(title string, width int, field func(collectionItem) time.Time) collectionColumn {
+	return collectionColumn{
+		title: title,
+		width: width,
+		value: func(s collectionScreen, d firebase.Document) string {
+			return formatTime(field(s.items[d.Path]))
+		},
+		sortKey: func(s collectionScreen, d firebase.Document) string {
+			return timeKey(field(s.items[d.Path]))
+		},
+	}
+}
+
+// createdColumn is a column showing when items were created
+func createdColumn(width int) collectionColumn {
+	return timeColumn("Created", width, func(it collectionItem) time.Time { return it.createdAt })
+}
+
+// updatedColumn is a column showing when items were last updated
+func updatedColumn(width int) collectionColumn {
+	return timeColumn("Updated", width, func(it collectionItem) time.Time { return it.updatedAt })
+}
+
+// countColumn is a column showing a count of the items
+func countColumn(title string, width int, count func(collectionItem) int) collectionColumn {
+	return collectionColumn{
+		title: title,
+		width: width,
+		value: func(s collectionScreen, d firebase.Document) string {
+			return fmt.Sprint(count(s.items[d.Path]))
+		},
+		sortKey: func(s collectionScreen, d firebase.Document) string {
+			return fmt.Sprintf("%09d", count(s.items[d.Path]))
+		},
+	}
+}
+
+// ownerColumn is a column showing the email of a document's owner
+func ownerColumn(width int) collectionColumn {
+	return collectionColumn{
+		title: "Owner",
+		width: width,
+		value: func(s collectionScreen, d firebase.Document) string {
+			return s.ownerOf(d)
+		},
+	}
+}
+
+// formatTime formats a timestamp for tables, or "-" when unset
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("02 Jan 2006, 15:04")
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"arrogance/firebase"
+)
+
+// collectionItem holds the fields of a user collection's model that the
+// collection tabs and the user detail screen show
+type collectionItem struct {
+	id        string
+	name      string
+	uid       string
+	date      time.Time         // the workout date of a history
+	exercises []string          // the exercise IDs of a routine
+	records   []firebase.Record // the personal records of a profile, loaded by the user detail screen
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+// title returns the name of an item, or a placeholder when it has none
+func (it collectionItem) title() string {
+	if it.name == "" {
+		return "(unnamed)"
+	}
+	return it.name
+}
+
+// profileItem returns the item of a profile
+func profileItem(p firebase.Profile) collectionItem {
+	return collectionItem{id: p.ID, name: p.Name, uid: p.UID, createdAt: p.CreatedAt, updatedAt: p.UpdatedAt}
+}
+
+// exerciseItem returns the item of an exercise
+func exerciseItem(e firebase.Exercise) collectionItem {
+	return collectionItem{id: e.ID, name: e.Name, uid: e.UID, createdAt: e.CreatedAt, updatedAt: e.UpdatedAt}
+}
+
+// historyItem returns the item of a history, named after its workout
+func historyItem(h firebase.History) collectionItem {
+	return collectionItem{id: h.ID, name: h.Workout.Name, uid: h.UID, date: h.Workout.Date, createdAt: h.CreatedAt, updatedAt: h.UpdatedAt}
+}
+
+// routineItem returns the item of a routine
+func routineItem(r firebase.Routine) collectionItem {
+	return collectionItem{id: r.ID, name: r.Name, uid: r.UID, exercises: r.Exercises, createdAt: r.CreatedAt, updatedAt: r.UpdatedAt}
+}
+
+// itemFrom converts documents to items with a model converter. A document
+// that doesn't convert keeps the fields read before the error.
+func itemFrom[T any](from func(firebase.Document) (T, error), item func(T) collectionItem) func(firebase.Document) (collectionItem, error) {
+	return func(d firebase.Document) (collectionItem, error) {
+		m, err := from(d)
+		return item(m), err
+	}
+}
+
+// routinesSpec shows routines with the exercises they contain
+var routinesSpec = &collectionSpec{
+	collection: "routines",
+	title:      "Routines",
+	item:       itemFrom(firebase.RoutineFrom, routineItem),
+	columns: []collectionColumn{
+		textColumn("Name", 25, collectionItem.title),
+		ownerColumn(30),
+		countColumn("Exercises", 10, func(it collectionItem) int { return len(it.exercises) }),
+		createdColumn(20),
+		updatedColumn(20),
+	},
+	lookup:        lookupExercises,
+	preview:       routinePreview,
+	previewHeight: 10,
+}
+
+// exercisesSpec shows the exercises users have defined
+var exercisesSpec = &collectionSpec{
+	collection: "exercises",
+	title:      "Exercises",
+	item:       itemFrom(firebase.ExerciseFrom, exerciseItem),
+	columns: []collectionColumn{
+		textColumn("Name", 25, collectionItem.title),
+		ownerColumn(30),
+		createdColumn(20),
+		updatedColumn(20),
+	},
+}
+
+// historiesSpec shows finished workouts, newest first
+var historiesSpec = &collectionSpec{
+	collection: "histories",
+	title:      "Histories",
+	item:       itemFrom(firebase.HistoryFrom, historyItem),
+	columns: []collectionColumn{
+		textColumn("Workout", 25, func(it collectionItem) string { return it.name }),
+		timeColumn("Date", 20, func(it collectionItem) time.Time { return it.date }),
+		ownerColumn(30),
+		createdColumn(20),
+	},
+	sort: tableSort{col: 1, desc: true},
+}
+
+// profilesSpec shows profiles with their personal records
+var profilesSpec = &collectionSpec{
+	collection: "profiles",
+	title:      "Profiles",
+	item:       itemFrom(firebase.ProfileFrom, profileItem),
+	columns: []collectionColumn{
+		textColumn("Name", 25, collectionItem.title),
+		ownerColumn(30),
+		createdColumn(20),
+		updatedColumn(20),
+	},
+	sections: []documentSection{{title: "records", load: profileRecordLines}},
+}
+
+// profileRecordLines lists the personal records of a profile
+func profileRecordLines(ctx context.Context, storeSvc firebase.DocumentStore, d firebase.Document) ([]string, error) {
+	records, err := firebase.RecordsOfProfile(ctx, storeSvc, d.ID)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, len(records))
+	for i, r := range records {
+		lines[i] = fmt.Sprintf("[%s] %s", r.ID, recordSummary(r))
+	}
+	return lines, nil
+}
+
+// recordSummary describes a personal record: the exercise, the weight lifted
+// for the reps and when
+func recordSummary(r firebase.Record) string {
+	name := r.Name
+	if name == "" {
+		name = "(unnamed)"
+	}
+	return name + labelStyle.UnsetWidth().Render(fmt.Sprintf("  %g × %d reps · %s", r.Weight, r.Reps, formatTime(r.Date)))
+}
+
+// lookupExercises resolves the names of the exercises routines reference.
+// Missing exercises have an empty name.
+func lookupExercises(ctx context.Context, storeSvc firebase.DocumentStore, docs []firebase.Document) (map[string]string, error) {
+	var ids []string
+	for _, d := range docs {
+		ids = append(ids, routineExerciseIDs(d)...)
+	}
+	exercises, err := firebase.ExercisesByID(ctx, storeSvc, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(ids))
+	for _, id := range ids {
+		names[id] = exercises[id].Name
+	}
+	return names, nil
+}
+
+// routinePreview renders the contents of the selected routine below the table
+func routinePreview(s collectionScreen, d firebase.Document) string {
+	var sb strings.Builder
+
+	field := func(label, value string) {
+		sb.WriteString(labelStyle.Render(label) + value + "\n")
+	}
+
+	routine, err := firebase.RoutineFrom(d)
+	sb.WriteString(sectionStyle.Render(routineItem(routine).title()) + "\n")
+	field("ID", routine.ID)
+	field("Owner", s.ownerOf(d))
+	field("Created", formatTime(routine.CreatedAt))
+	field("Updated", formatTime(routine.UpdatedAt))
+
+	switch {
+	case err != nil:
+		field("Exercises", errorStyle.UnsetMarginLeft().Render(err.Error()))
+		return strings.TrimSuffix(sb.String(), "\n")
+	case len(routine.Exercises) == 0:
+		field("Exercises", "-")
+		return strings.TrimSuffix(sb.String(), "\n")
+	}
+
+	// Leave room for the fields above
+	ids := routine.Exercises
+	shown := min(len(ids), s.spec.previewHeight-5)
+	for i, id := range ids[:shown] {
+		label := ""
+		if i == 0 {
+			label = "Exercises"
+		}
+		name := s.lookups[id]
+		if name == "" {
+			name = "(missing exercise)"
+		}
+		field(label, fmt.Sprintf("%d. %s [%s]", i+1, name, id))
+	}
+	if shown < len(ids) {
+		field("", fmt.Sprintf("… and %d more", len(ids)-shown))
+	}
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// routineExerciseIDs returns the IDs of the exercises in a routine, or none
+// when the routine can't be read
+func routineExerciseIDs(d firebase.Document) []string {
+	routine, err := firebase.RoutineFrom(d)
+	if err != nil {
+		return nil
+	}
+	return routine.Exercises
+}
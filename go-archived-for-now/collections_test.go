@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"arrogance/firebase"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// collectionTab returns the collection screen at the root of the active tab
+func collectionTab(t *testing.T, m Model) collectionScreen {
+	t.Helper()
+	s, ok := m.router.top().(collectionScreen)
+	if !ok {
+		t.Fatalf("Expected a collection screen, got %T", m.router.top())
+	}
+	return s
+}
+
+func keyRunes(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+// TestRoutinesTab tests loading, sorting and previewing routines
+func TestRoutinesTab(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, RoutinesTab)
+	v := collectionTab(t, m)
+	if v.err != "" || len(v.docs) != 6 {
+		t.Fatalf("Expected 6 routines, got %d (error %q)", len(v.docs), v.err)
+	}
+
+	// Sorted by name by default
+	rows := v.table.Rows()
+	for i := 1; i < len(rows); i++ {
+		if strings.ToLower(rows[i-1][0]) > strings.ToLower(rows[i][0]) {
+			t.Errorf("Rows not sorted by name: %q before %q", rows[i-1][0], rows[i][0])
+		}
+	}
+	if !strings.HasSuffix(v.table.Columns()[0].Title, "▲") {
+		t.Errorf("Expected a sort indicator on the name column, got %q", v.table.Columns()[0].Title)
+	}
+
+	// Owners are shown by email
+	view := m.View()
+	for _, want := range []string{"Push Day", "maya.santoso@example.com", "Total routines: 6"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Routines view does not contain %q", want)
+		}
+	}
+
+	// s moves the sort to the owner column, S reverses it
+	m = settle(t, m, keyRunes("s"))
+	m = settle(t, m, keyRunes("S"))
+	v = collectionTab(t, m)
+	if v.sort.col != 1 || !v.sort.desc {
+		t.Fatalf("Expected a descending owner sort, got column %d desc %v", v.sort.col, v.sort.desc)
+	}
+	rows = v.table.Rows()
+	if rows[0][1] < rows[len(rows)-1][1] {
+		t.Errorf("Rows not sorted by owner descending: first %q, last %q", rows[0][1], rows[len(rows)-1][1])
+	}
+
+	// The preview names the exercises of the selected routine
+	for i, d := range v.docs {
+		if d.ID == "rMayaPush" {
+			v.table.SetCursor(i)
+		}
+	}
+	d, _ := v.selected()
+	preview := routinePreview(v, d)
+	for _, want := range []string{"Push Day", "rMayaPush", "Bench Press"} {
+		if !strings.Contains(preview, want) {
+			t.Errorf("Routine preview does not contain %q", want)
+		}
+	}
+}
+
+// TestCollectionTabsEmptyAndError tests the empty and error states
+func TestCollectionTabsEmptyAndError(t *testing.T) {
+	authSvc := firebase.NewMemoryAuth()
+	storeSvc := firebase.NewMemoryFirestore()
+	m := newTestApp(t, authSvc, storeSvc, ExercisesTab)
+	if !strings.Contains(m.View(), "No exercises found") {
+		t.Error("Expected the empty state for an empty collection")
+	}
+
+	// A routine pointing at a missing exercise or owner still renders
+	_ = storeSvc.Set(context.Background(), "routines", "r1", map[string]interface{}{
+		"name": "Orphan", "uid": "gone", "exercises": []interface{}{"missing"},
+	})
+	m = settle(t, m, tabChangeMsg{index: RoutinesTab})
+	v := collectionTab(t, m)
+	if preview := routinePreview(v, v.docs[0]); !strings.Contains(preview, "gone") || !strings.Contains(preview, "missing exercise") {
+		t.Errorf("Unexpected preview for an orphaned routine:\n%s", preview)
+	}
+
+	// A routine with a field of the wrong type still loads
+	_ = storeSvc.Set(context.Background(), "routines", "r2", map[string]interface{}{"name": 42, "uid": "gone"})
+	m = settle(t, m, tabChangeMsg{index: RoutinesTab})
+	if v := collectionTab(t, m); v.err != "" || len(v.docs) != 2 || !strings.Contains(m.View(), "(unnamed)") {
+		t.Errorf("Expected both routines, got %d (error %q)\n%s", len(v.docs), v.err, m.View())
+	}
+
+	m = settle(t, m, collectionErrorMsg{collection: "routines", err: errors.New("boom")})
+	if !strings.Contains(m.View(), "Error loading routines: boom") {
+		t.Error("Expected the error state")
+	}
+}
+
+// TestCollectionUserFilter tests filtering a tab to one user by email
+func TestCollectionUserFilter(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, HistoriesTab)
+	v := collectionTab(t, m)
+	if len(v.docs) != 6 {
+		t.Fatalf("Expected 6 histories, got %d", len(v.docs))
+	}
+
+	// Histories are ordered by workout date, newest first
+	first := v.items[v.docs[0].Path].date
+	last := v.items[v.docs[len(v.docs)-1].Path].date
+	if first.Before(last) {
+		t.Errorf("Expected newest histories first, got %v before %v", first, last)
+	}
+
+	m = settle(t, m, keyRunes("u"))
+	if !collectionTab(t, m).filtering {
+		t.Fatal("Expected u to open the user filter")
+	}
+
+	// q is typed into the filter instead of quitting
+	m = settle(t, m, keyRunes("q"))
+	if v := collectionTab(t, m); v.filterInput.Value() != "q" {
+		t.Fatalf("Expected q to be typed into the filter, got %q", v.filterInput.Value())
+	}
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyBackspace})
+	m = settle(t, m, keyRunes("maya.santoso@example.com"))
+
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	v = collectionTab(t, m)
+	if v.filtering || v.err != "" || len(v.docs) != 2 {
+		t.Fatalf("Expected Maya's 2 histories, got %d (error %q)", len(v.docs), v.err)
+	}
+	if !strings.Contains(m.View(), "user maya.santoso@example.com") {
+		t.Error("Expected the active filter to be shown")
+	}
+
+	// An unknown user is an error rather than an empty list
+	m = settle(t, m, keyRunes("u"))
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyCtrlU})
+	m = settle(t, m, keyRunes("nobody@example.com"))
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if collectionTab(t, m).err == "" {
+		t.Error("Expected an error for an unknown email")
+	}
+}
+
+// TestCollectionDocumentDetail tests opening a profile with its records
+func TestCollectionDocumentDetail(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, ProfilesTab)
+
+	for i, d := range collectionTab(t, m).docs {
+		if d.ID == "pMaya01" {
+			for range i {
+				m = settle(t, m, tea.KeyMsg{Type: tea.KeyDown})
+			}
+		}
+	}
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	v, ok := m.router.top().(documentScreen)
+	if !ok || v.loading || v.err != "" {
+		t.Fatalf("Expected the profile to open with its records, got %T", m.router.top())
+	}
+
+	body := v.render()
+	for _, want := range []string{"profiles/pMaya01", "maya.santoso@example.com", "Records (3)", "Bench Press", "70 × 5 reps"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Profile detail does not contain %q", want)
+		}
+	}
+
+	// q closes the document instead of quitting
+	updated, cmd := m.Update(keyRunes("q"))
+	m = updated.(Model)
+	if _, ok := m.router.top().(collectionScreen); !ok || cmd != nil || m.router.depth() != 1 {
+		t.Error("Expected q to return to the profiles table")
+	}
+}
+
+// TestTabCycle tests that tab visits every collection tab
+func TestTabCycle(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, HomeTab)
+
+	var views []string
+	for range m.router.titles() {
+		m = settle(t, m, tea.KeyMsg{Type: tea.KeyTab})
+		views = append(views, m.router.titles()[m.router.active])
+	}
+	want := []string{"Users", "Routines", "Exercises", "Histories", "Profiles", "Browse", "Home"}
+	if strings.Join(views, ",") != strings.Join(want, ",") {
+		t.Errorf("Tab visited %v, want %v", views, want)
+	}
+}
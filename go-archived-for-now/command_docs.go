@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"arrogance/firebase"
+)
+
+// getDocument loads the document at path, such as routines/abc
+func getDocument(ctx context.Context, docs firebase.DocumentStore, path string) (firebase.Document, error) {
+	collection, id, err := firebase.SplitDocumentPath(path)
+	if err != nil {
+		return firebase.Document{}, err
+	}
+	var data map[string]interface{}
+	if err := docs.Get(ctx, collection, id, &data); err != nil {
+		return firebase.Document{}, fmt.Errorf("failed to get %s: %w", path, err)
+	}
+	return firebase.Document{ID: id, Path: strings.Trim(path, "/"), Data: data}, nil
+}
+
+// docsGet shows a document
+func (c cli) docsGet(args []string) int {
+	fs := c.newFlagSet("docs get", "PATH")
+	output := addOutputFlag(fs)
+	columns := fs.String("columns", "", "comma-separated fields to show, dotted for nested fields (default every field)")
+	positional, code, ok := parseArgs(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return c.usageError(err)
+	}
+	if _, _, err := firebase.SplitDocumentPath(positional[0]); err != nil {
+		return c.usageError(err)
+	}
+
+	_, docs, closeBackend, err := c.connect()
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to connect: %w", err))
+	}
+	defer closeBackend()
+
+	d, err := getDocument(context.Background(), docs, positional[0])
+	if err != nil {
+		return c.fail(err)
+	}
+	if err := printRecord(c.stdout, *output, parseColumns(*columns), firebase.ExportDocument(d)); err != nil {
+		return c.fail(err)
+	}
+	return exitOK
+}
+
+// docsList lists the documents matching a query
+func (c cli) docsList(args []string) int {
+	fs := c.newFlagSet("docs list", "QUERY")
+	output := addOutputFlag(fs)
+	columns := fs.String("columns", "", "comma-separated fields to show, dotted for nested fields (default every field)")
+	positional, code, ok := parseArgs(fs, args, 1, 1<<16)
+	if !ok {
+		return code
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return c.usageError(err)
+	}
+	q, err := firebase.ParseQuery(strings.Join(positional, " "))
+	if err != nil {
+		return c.usageError(fmt.Errorf("invalid query: %w", err))
+	}
+
+	_, docs, closeBackend, err := c.connect()
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to connect: %w", err))
+	}
+	defer closeBackend()
+
+	results, err := docs.Query(context.Background(), q)
+	if err != nil {
+		return c.fail(fmt.Errorf("query failed: %w", err))
+	}
+	if err := printRecords(c.stdout, *output, parseColumns(*columns), documentRecords(results)); err != nil {
+		return c.fail(err)
+	}
+	return exitOK
+}
+
+// docsSet writes a document from JSON given as an argument, a file or stdin
+func (c cli) docsSet(args []string) int {
+	fs := c.newFlagSet("docs set", "PATH [JSON]")
+	output := addOutputFlag(fs)
+	file := fs.String("file", "", "read the JSON from a file, - for stdin")
+	merge := fs.Bool("merge", false, "only change the given fields of an existing document")
+	positional, code, ok := parseArgs(fs, args, 1, 2)
+	if !ok {
+		return code
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return c.usageError(err)
+	}
+	collection, id, err := firebase.SplitDocumentPath(positional[0])
+	if err != nil {
+		return c.usageError(err)
+	}
+
+	var input []byte
+	switch {
+	case len(positional) == 2 && *file != "":
+		return c.usageError(errors.New("give the JSON as an argument or with --file, not both"))
+	case len(positional) == 2:
+		input = []byte(positional[1])
+	case *file == "" || *file == "-":
+		input, err = io.ReadAll(os.Stdin)
+	default:
+		input, err = os.ReadFile(*file)
+	}
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to read the document: %w", err))
+	}
+	data, err := firebase.DocumentFromJSON(input)
+	if err != nil {
+		return c.usageError(fmt.Errorf("invalid document JSON: %w", err))
+	}
+
+	_, docs, closeBackend, err := c.connect()
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to connect: %w", err))
+	}
+	defer closeBackend()
+
+	ctx := context.Background()
+	if *merge {
+		err = docs.Update(ctx, collection, id, data)
+	} else {
+		err = docs.Set(ctx, collection, id, data)
+	}
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to write %s: %w", positional[0], err))
+	}
+
+	d, err := getDocument(ctx, docs, positional[0])
+	if err != nil {
+		return c.fail(err)
+	}
+	if err := printRecord(c.stdout, *output, nil, firebase.ExportDocument(d)); err != nil {
+		return c.fail(err)
+	}
+	return exitOK
+}
+
+// docsDelete deletes a document, failing when it doesn't exist
+func (c cli) docsDelete(args []string) int {
+	fs := c.newFlagSet("docs delete", "PATH")
+	positional, code, ok := parseArgs(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+	collection, id, err := firebase.SplitDocumentPath(positional[0])
+	if err != nil {
+		return c.usageError(err)
+	}
+
+	_, docs, closeBackend, err := c.connect()
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to connect: %w", err))
+	}
+	defer closeBackend()
+
+	ctx := context.Background()
+	if _, err := getDocument(ctx, docs, positional[0]); err != nil {
+		return c.fail(err)
+	}
+	if err := docs.Delete(ctx, collection, id); err != nil {
+		return c.fail(fmt.Errorf("failed to delete %s: %w", positional[0], err))
+	}
+	fmt.Fprintln(c.stdout, "Deleted "+positional[0])
+	return exitOK
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"arrogance/firebase"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// userListColumns are the columns of users list tables
+var userListColumns = []string{"uid", "email", "displayName", "disabled", "createdAt"}
+
+// userDetailColumns are the fields shown for a single user
+func userDetailColumns() []string {
+	return firebase.ExportUserFields
+}
+
+// userRecord converts a user to a record for printing
+func userRecord(user *auth.UserRecord) map[string]interface{} {
+	return firebase.ExportUser(&auth.ExportedUserRecord{UserRecord: user}, false)
+}
+
+// lookupUser finds a user by UID, or by email when the argument has an @
+func lookupUser(ctx context.Context, users firebase.UserStore, uidOrEmail string) (*auth.UserRecord, error) {
+	if strings.Contains(uidOrEmail, "@") {
+		user, err := users.GetUserByEmail(ctx, uidOrEmail)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find user %s: %w", uidOrEmail, err)
+		}
+		return user, nil
+	}
+	user, err := users.GetUser(ctx, uidOrEmail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user %s: %w", uidOrEmail, err)
+	}
+	return user, nil
+}
+
+// printUser prints a user after loading it again, so it shows the saved record
+func (c cli) printUser(ctx context.Context, users firebase.UserStore, uid, output string) int {
+	user, err := users.GetUser(ctx, uid)
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to load user %s: %w", uid, err))
+	}
+	if err := printRecord(c.stdout, output, userDetailColumns(), userRecord(user)); err != nil {
+		return c.fail(err)
+	}
+	return exitOK
+}
+
+// usersList lists every user matching a filter
+func (c cli) usersList(args []string) int {
+	fs := c.newFlagSet("users list", "")
+	output := addOutputFlag(fs)
+	filterText := fs.String("filter", "", "users filter, such as disabled:true or created:<2024-01-01")
+	limit := fs.Int("limit", 0, "list at most this many users, 0 for all")
+	columns := fs.String("columns", "", "comma-separated fields to show (default uid,email,displayName,disabled,createdAt for tables, every field otherwise)")
+	if _, code, ok := parseArgs(fs, args, 0, 0); !ok {
+		return code
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return c.usageError(err)
+	}
+	filter, err := parseUserFilter(*filterText)
+	if err != nil {
+		return c.usageError(fmt.Errorf("invalid filter: %w", err))
+	}
+
+	users, _, closeBackend, err := c.connect()
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to connect: %w", err))
+	}
+	defer closeBackend()
+
+	// Stop listing once the limit is reached
+	errLimit := errors.New("limit reached")
+	var records []map[string]interface{}
+	err = firebase.ExportUsers(context.Background(), users, func(u *auth.ExportedUserRecord) error {
+		if !filter.matches(u.UserRecord) {
+			return nil
+		}
+		if *limit > 0 && len(records) == *limit {
+			return errLimit
+		}
+		records = append(records, userRecord(u.UserRecord))
+		return nil
+	})
+	if err != nil && !errors.Is(err, errLimit) {
+		return c.fail(fmt.Errorf("failed to list users: %w", err))
+	}
+
+	selected := parseColumns(*columns)
+	if len(selected) == 0 {
+		selected = userDetailColumns()
+		if *output == "table" {
+			selected = userListColumns
+		}
+	}
+	if err := printRecords(c.stdout, *output, selected, records); err != nil {
+		return c.fail(err)
+	}
+	return exitOK
+}
+
+// usersGet shows a user
+func (c cli) usersGet(args []string) int {
+	fs := c.newFlagSet("users get", "UID|EMAIL")
+	output := addOutputFlag(fs)
+	positional, code, ok := parseArgs(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return c.usageError(err)
+	}
+
+	users, _, closeBackend, err := c.connect()
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to connect: %w", err))
+	}
+	defer closeBackend()
+
+	user, err := lookupUser(context.Background(), users, positional[0])
+	if err != nil {
+		return c.fail(err)
+	}
+	if err := printRecord(c.stdout, *output, userDetailColumns(), userRecord(user)); err != nil {
+		return c.fail(err)
+	}
+	return exitOK
+}
+
+// userFlags are the user fields that can be set from flags
+type userFlags struct {
+	email         *string
+	password      *string
+	displayName   *string
+	phoneNumber   *string
+	photoURL      *string
+	emailVerified *bool
+}
+
+// addUserFlags defines the flags of the user fields
+func addUserFlags(fs *flag.FlagSet) userFlags {
+	return userFlags{
+		email:         fs.String("email", "", "email address"),
+		password:      fs.String("password", "", "password, at least 6 characters"),
+		displayName:   fs.String("name", "", "display name"),
+		phoneNumber:   fs.String("phone", "", "phone number in E.164 format, such as +14155550100"),
+		photoURL:      fs.String("photo-url", "", "http or https URL of a photo"),
+		emailVerified: fs.Bool("email-verified", false, "whether the email address is verified"),
+	}
+}
+
+// validate checks the fields the way the user form does
+func (f userFlags) validate() error {
+	checks := []error{
+		validateEmail(false)(*f.email),
+		validatePassword(false)(*f.password),
+		validatePhone(*f.phoneNumber),
+		validatePhotoURL(*f.photoURL),
+	}
+	for _, err := range checks {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usersCreate creates a user
+func (c cli) usersCreate(args []string) int {
+	fs := c.newFlagSet("users create", "")
+	output := addOutputFlag(fs)
+	uid := fs.String("uid", "", "UID of the new user (default generated)")
+	fields := addUserFlags(fs)
+	disabled := fs.Bool("disabled", false, "create the user disabled")
+	if _, code, ok := parseArgs(fs, args, 0, 0); !ok {
+		return code
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return c.usageError(err)
+	}
+	if err := fields.validate(); err != nil {
+		return c.usageError(err)
+	}
+
+	users, _, closeBackend, err := c.connect()
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to connect: %w", err))
+	}
+	defer closeBackend()
+
+	ctx := context.Background()
+	created, err := users.CreateUser(ctx, &firebase.UserToCreate{
+		UID:           *uid,
+		Email:         *fields.email,
+		Password:      *fields.password,
+		DisplayName:   *fields.displayName,
+		PhoneNumber:   *fields.phoneNumber,
+		PhotoURL:      *fields.photoURL,
+		EmailVerified: *fields.emailVerified,
+		Disabled:      *disabled,
+	})
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to create user: %w", err))
+	}
+	return c.printUser(ctx, users, created, *output)
+}
+
+// usersUpdate changes the fields of a user given as flags
+func (c cli) usersUpdate(args []string) int {
+	fs := c.newFlagSet("users update", "UID|EMAIL")
+	output := addOutputFlag(fs)
+	fields := addUserFlags(fs)
+	disabled := fs.Bool("disabled", false, "whether the user is disabled")
+	positional, code, ok := parseArgs(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return c.usageError(err)
+	}
+	if err := fields.validate(); err != nil {
+		return c.usageError(err)
+	}
+
+	// Only the flags given are changed
+	var params firebase.UserToUpdate
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "email":
+			params.Email = fields.email
+		case "password":
+			params.Password = fields.password
+		case "name":
+			params.DisplayName = fields.displayName
+		case "phone":
+			params.PhoneNumber = fields.phoneNumber
+		case "photo-url":
+			params.PhotoURL = fields.photoURL
+		case "email-verified":
+			params.EmailVerified = fields.emailVerified
+		case "disabled":
+			params.Disabled = disabled
+		}
+	})
+	if params == (firebase.UserToUpdate{}) {
+		return c.usageError(errors.New("nothing to update, pass the fields to change such as --name"))
+	}
+
+	users, _, closeBackend, err := c.connect()
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to connect: %w", err))
+	}
+	defer closeBackend()
+
+	ctx := context.Background()
+	user, err := lookupUser(ctx, users, positional[0])
+	if err != nil {
+		return c.fail(err)
+	}
+	if err := users.UpdateUser(ctx, user.UID, &params); err != nil {
+		return c.fail(fmt.Errorf("failed to update user %s: %w", user.UID, err))
+	}
+	return c.printUser(ctx, users, user.UID, *output)
+}
+
+// usersSetDisabled disables or enables a user
+func (c cli) usersSetDisabled(args []string, disabled bool) int {
+	name := "users enable"
+	if disabled {
+		name = "users disable"
+	}
+	fs := c.newFlagSet(name, "UID|EMAIL")
+	output := addOutputFlag(fs)
+	positional, code, ok := parseArgs(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+	if err := checkOutputFormat(*output); err != nil {
+		return c.usageError(err)
+	}
+
+	users, _, closeBackend, err := c.connect()
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to connect: %w", err))
+	}
+	defer closeBackend()
+
+	ctx := context.Background()
+	user, err := lookupUser(ctx, users, positional[0])
+	if err != nil {
+		return c.fail(err)
+	}
+	if err := users.UpdateUser(ctx, user.UID, &firebase.UserToUpdate{Disabled: firebase.Bool(disabled)}); err != nil {
+		return c.fail(fmt.Errorf("failed to update user %s: %w", user.UID, err))
+	}
+	return c.printUser(ctx, users, user.UID, *output)
+}
+
+// usersDelete deletes a user, and with --cascade every document they own
+func (c cli) usersDelete(args []string) int {
+	fs := c.newFlagSet("users delete", "UID|EMAIL")
+	cascade := fs.Bool("cascade", false, "also delete the user's profiles, records, exercises, histories and routines")
+	positional, code, ok := parseArgs(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+
+	users, docs, closeBackend, err := c.connect()
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to connect: %w", err))
+	}
+	defer closeBackend()
+
+	ctx := context.Background()
+	user, err := lookupUser(ctx, users, positional[0])
+	if err != nil {
+		return c.fail(err)
+	}
+
+	if !*cascade {
+		if err := users.DeleteUser(ctx, user.UID); err != nil {
+			return c.fail(fmt.Errorf("failed to delete user %s: %w", user.UID, err))
+		}
+		fmt.Fprintln(c.stdout, "Deleted user "+user.UID)
+		return exitOK
+	}
+
+	summary, err := firebase.DeleteUserAndData(ctx, users, docs, user.UID, nil)
+	if err != nil {
+		return c.fail(err)
+	}
+	fmt.Fprintln(c.stdout, deleteSummaryText(user.UID, summary))
+	return exitOK
+}
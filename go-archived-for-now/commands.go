@@ -0,0 +1,493 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"arrogance/firebase"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// Exit codes of commands
+const (
+	exitOK       = 0
+	exitFailure  = 1
+	exitUsage    = 2
+	exitNotFound = 3
+)
+
+// backendOptions are the global flags choosing the backend commands run against
+type backendOptions struct {
+	demo     bool
+	emulator bool
+	project  string
+}
+
+// cli runs commands, printing results to stdout and progress and errors to stderr
+type cli struct {
+	stdout io.Writer
+	stderr io.Writer
+	// connect opens the backend, returning a function that closes it
+	connect func() (firebase.UserStore, firebase.DocumentStore, func(), error)
+}
+
+// runCommand runs the command in args and returns its exit code
+func runCommand(args []string, opts backendOptions) int {
+	c := cli{stdout: os.Stdout, stderr: os.Stderr, connect: opts.connect}
+	return c.run(args)
+}
+
+// connect opens the in-memory demo backend or Firebase
+func (o backendOptions) connect() (firebase.UserStore, firebase.DocumentStore, func(), error) {
+	if o.demo {
+		authSvc, storeSvc, err := firebase.LoadDemoFixture()
+		return authSvc, storeSvc, func() {}, err
+	}
+
+	if err := prepareFirebase(o.emulator, o.project, os.Stderr); err != nil {
+		return nil, nil, nil, err
+	}
+	client, err := firebase.InitFirebase()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	closeFirebase := func() { _ = firebase.CloseFirebase() }
+	return firebase.NewAuthService(client.Auth), firebase.NewFirestoreService(client.Firestore), closeFirebase, nil
+}
+
+// usage describes the global flags and the commands
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] [command]\n\n", os.Args[0])
+	fmt.Fprintln(out, "Commands:")
+	fmt.Fprintln(out, "  tui                         start the TUI, the default")
+	fmt.Fprintln(out, "  users list                  list users")
+	fmt.Fprintln(out, "  users get UID|EMAIL         show a user")
+	fmt.Fprintln(out, "  users create                create a user")
+	fmt.Fprintln(out, "  users update UID            change fields of a user")
+	fmt.Fprintln(out, "  users delete UID            delete a user, and their data with --cascade")
+	fmt.Fprintln(out, "  users disable|enable UID    disable or enable a user")
+	fmt.Fprintln(out, "  docs get PATH               show a document, such as routines/abc")
+	fmt.Fprintln(out, "  docs list QUERY             list the documents matching a query, such as routines where uid == abc")
+	fmt.Fprintln(out, "  docs set PATH [JSON]        write a document from JSON, --file or stdin")
+	fmt.Fprintln(out, "  docs delete PATH            delete a document")
+	fmt.Fprintln(out, "  import users FILE           import users from a CSV or JSON file")
+	fmt.Fprintln(out, "  export users                export users to CSV, JSON or NDJSON")
+	fmt.Fprintln(out, "  export docs QUERY           export the documents matching a query")
+	fmt.Fprintln(out, "\nRun a command with -h for its flags, which go after the command; -- ends them.")
+	fmt.Fprintln(out, "Exit codes: 0 success, 1 failure, 2 usage error, 3 not found.")
+	fmt.Fprintln(out, "\nFlags, given before the command:")
+	flag.PrintDefaults()
+}
+
+// run dispatches args to a command
+func (c cli) run(args []string) int {
+	switch {
+	case isCommand(args, "users", "list"):
+		return c.usersList(args[2:])
+	case isCommand(args, "users", "get"):
+		return c.usersGet(args[2:])
+	case isCommand(args, "users", "create"):
+		return c.usersCreate(args[2:])
+	case isCommand(args, "users", "update"):
+		return c.usersUpdate(args[2:])
+	case isCommand(args, "users", "delete"):
+		return c.usersDelete(args[2:])
+	case isCommand(args, "users", "disable"):
+		return c.usersSetDisabled(args[2:], true)
+	case isCommand(args, "users", "enable"):
+		return c.usersSetDisabled(args[2:], false)
+	case isCommand(args, "docs", "get"):
+		return c.docsGet(args[2:])
+	case isCommand(args, "docs", "list"):
+		return c.docsList(args[2:])
+	case isCommand(args, "docs", "set"):
+		return c.docsSet(args[2:])
+	case isCommand(args, "docs", "delete"):
+		return c.docsDelete(args[2:])
+	case isCommand(args, "import", "users"):
+		return c.importUsers(args[2:])
+	case isCommand(args, "export", "users"):
+		return c.exportUsers(args[2:])
+	case isCommand(args, "export", "docs"):
+		return c.exportDocs(args[2:])
+	}
+	fmt.Fprintf(c.stderr, "unknown command %q, run with -h for the commands\n", strings.Join(args, " "))
+	return exitUsage
+}
+
+// newFlagSet creates the flags of a command, whose usage shows the
+// positional arguments it takes, followed by any lines explaining them
+func (c cli) newFlagSet(name, positional string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: %s [flags] %s\n\nFlags:\n", name, positional)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses the flags of a command, which takes between min and max
+// positional arguments. When it fails, the command exits with code.
+func parseArgs(fs *flag.FlagSet, args []string, min, max int) (positional []string, code int, ok bool) {
+	positional, err := parseFlags(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil, exitOK, false
+	}
+	if err != nil {
+		return nil, exitUsage, false
+	}
+	if len(positional) < min || len(positional) > max {
+		fs.Usage()
+		return nil, exitUsage, false
+	}
+	return positional, exitOK, true
+}
+
+// addOutputFlag defines the --output flag, with -o as its short form
+func addOutputFlag(fs *flag.FlagSet) *string {
+	output := fs.String("output", "table", "output format: table, json or yaml")
+	fs.StringVar(output, "o", "table", "shorthand for --output")
+	return output
+}
+
+// fail reports an error, returning the exit code for it
+func (c cli) fail(err error) int {
+	fmt.Fprintln(c.stderr, err)
+	if errors.Is(err, firebase.ErrUserNotFound) || errors.Is(err, firebase.ErrDocumentNotFound) {
+		return exitNotFound
+	}
+	return exitFailure
+}
+
+// usageError reports a problem with the arguments of a command
+func (c cli) usageError(err error) int {
+	fmt.Fprintln(c.stderr, err)
+	return exitUsage
+}
+
+// isCommand reports whether args start with the words of a command
+func isCommand(args []string, words ...string) bool {
+	if len(args) < len(words) {
+		return false
+	}
+	for i, word := range words {
+		if args[i] != word {
+			return false
+		}
+	}
+	return true
+}
+
+// flagSet reports whether a flag was given on the command line
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// parseFlags parses flags that may come before, between or after the
+// positional arguments, and returns the positional ones. Arguments after --
+// and negative numbers such as -5 are positional.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for len(args) > 0 {
+		if _, err := strconv.ParseFloat(args[0], 64); err == nil {
+			positional = append(positional, args[0])
+			args = args[1:]
+			continue
+		}
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if parsed := len(args) - len(rest); parsed > 0 && args[parsed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+	return positional, nil
+}
+
+// importUsers imports users from a file, printing what changes first
+func (c cli) importUsers(args []string) int {
+	fs := c.newFlagSet("import users", "FILE\n\nFILE is a CSV file with a header row or a JSON array of users, - for stdin.")
+	format := fs.String("format", "", "file format, csv or json (default from the file extension)")
+	dryRun := fs.Bool("dry-run", false, "only show what would change")
+	skipInvalid := fs.Bool("skip-invalid", false, "import the valid rows when some are invalid")
+	hash := importHash
+	fs.StringVar(&hash.Algorithm, "hash-algorithm", hash.Algorithm, "algorithm of the password hashes, such as SCRYPT or BCRYPT")
+	fs.StringVar(&hash.Key, "hash-key", hash.Key, "base64 signer key of SCRYPT or key of the HMAC algorithms")
+	fs.StringVar(&hash.SaltSeparator, "salt-separator", hash.SaltSeparator, "base64 salt separator of SCRYPT")
+	fs.IntVar(&hash.Rounds, "rounds", hash.Rounds, "rounds of the hash algorithm")
+	fs.IntVar(&hash.MemoryCost, "memory-cost", hash.MemoryCost, "memory cost of SCRYPT and STANDARD_SCRYPT")
+	fs.IntVar(&hash.BlockSize, "block-size", hash.BlockSize, "block size of STANDARD_SCRYPT")
+	fs.IntVar(&hash.Parallelization, "parallelization", hash.Parallelization, "parallelization of STANDARD_SCRYPT")
+	fs.IntVar(&hash.DerivedKeyLength, "derived-key-length", hash.DerivedKeyLength, "derived key length of STANDARD_SCRYPT")
+
+	positional, code, ok := parseArgs(fs, args, 1, 1)
+	if !ok {
+		return code
+	}
+	path := positional[0]
+
+	hashConfig, err := hash.config()
+	if err != nil {
+		fmt.Fprintf(c.stderr, "invalid hash settings: %v\n", err)
+		return exitUsage
+	}
+	fileFormat, err := importFormat(path, *format)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitUsage
+	}
+
+	rows, err := readImportPath(path, fileFormat)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "failed to read %s: %v\n", path, err)
+		return exitFailure
+	}
+
+	users, _, closeBackend, err := c.connect()
+	if err != nil {
+		fmt.Fprintf(c.stderr, "failed to connect: %v\n", err)
+		return exitFailure
+	}
+	defer closeBackend()
+
+	ctx := context.Background()
+	rows, err = planImport(ctx, users, rows)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitFailure
+	}
+	for _, line := range importDiff(rows) {
+		fmt.Fprintln(c.stdout, line)
+	}
+	fmt.Fprintln(c.stdout, importCounts(rows))
+
+	if err := checkImportHash(rows, hashConfig); err != nil {
+		fmt.Fprintf(c.stderr, "%v, or pass --hash-algorithm\n", err)
+		return exitFailure
+	}
+	invalid := len(rows) - countValid(rows)
+	if invalid > 0 && !*skipInvalid {
+		fmt.Fprintf(c.stderr, "%d invalid %s, fix them or pass --skip-invalid\n", invalid, plural(invalid, "row", "rows"))
+		return exitFailure
+	}
+	if *dryRun {
+		return exitOK
+	}
+
+	todo := importable(rows)
+	result := runImport(ctx, users, todo, hashConfig, func(p firebase.ImportProgress) {
+		if p.Total > 0 {
+			fmt.Fprintf(c.stderr, "Imported %d/%d\n", p.Done, p.Total)
+		}
+	})
+	for _, line := range importFailureLines(todo, result) {
+		fmt.Fprintln(c.stderr, line)
+	}
+	fmt.Fprintln(c.stdout, importResultText(result))
+	if len(result.Failures) > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// readImportPath reads an import file, or stdin for -
+func readImportPath(path, format string) ([]importRow, error) {
+	if path == "-" {
+		return parseImportFile(os.Stdin, format)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseImportFile(f, format)
+}
+
+// exportFlags are the flags shared by the export commands
+type exportFlags struct {
+	format  *string
+	columns *string
+	file    *string
+}
+
+// addExportFlags defines the flags shared by the export commands
+func addExportFlags(fs *flag.FlagSet, defaultColumns string) exportFlags {
+	return exportFlags{
+		format:  fs.String("format", "", "csv, json or ndjson (default from the file extension, or json)"),
+		columns: fs.String("columns", defaultColumns, "comma-separated fields to export, dotted for nested fields"),
+		file:    fs.String("file", "-", "file to write, - for stdout"),
+	}
+}
+
+// openExport creates the export output and its record writer in a format
+// returned by exportFormat. finish closes both.
+func (c cli) openExport(flags exportFlags, format string, columns []string) (recordWriter, func() error, error) {
+	out, closeOut := c.stdout, func() error { return nil }
+	if *flags.file != "-" {
+		f, err := os.Create(*flags.file)
+		if err != nil {
+			return nil, nil, err
+		}
+		out, closeOut = f, f.Close
+	}
+
+	rw, err := newRecordWriter(out, format, columns)
+	if err != nil {
+		closeOut()
+		return nil, nil, err
+	}
+	finish := func() error {
+		if err := rw.close(); err != nil {
+			closeOut()
+			return err
+		}
+		return closeOut()
+	}
+	return rw, finish, nil
+}
+
+// exportUsers writes every user matching a filter, a page at a time
+func (c cli) exportUsers(args []string) int {
+	fs := c.newFlagSet("export users", "")
+	flags := addExportFlags(fs, strings.Join(firebase.ExportUserFields, ","))
+	filterText := fs.String("filter", "", "users filter, such as disabled:true or created:<2024-01-01")
+	withPasswords := fs.Bool("with-password-hashes", false, "also export the password hashes and salts of the users")
+
+	if _, code, ok := parseArgs(fs, args, 0, 0); !ok {
+		return code
+	}
+	filter, err := parseUserFilter(*filterText)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "invalid filter: %v\n", err)
+		return exitUsage
+	}
+	columns := parseColumns(*flags.columns)
+	if len(columns) == 0 {
+		fmt.Fprintln(c.stderr, "no columns to export")
+		return exitUsage
+	}
+	if *withPasswords && !flagSet(fs, "columns") {
+		columns = append(columns, firebase.ExportPasswordFields...)
+	}
+	for _, column := range columns {
+		if slices.Contains(firebase.ExportPasswordFields, column) && !*withPasswords {
+			fmt.Fprintf(c.stderr, "exporting %s needs --with-password-hashes\n", column)
+			return exitUsage
+		}
+	}
+	format, err := exportFormat(*flags.file, *flags.format)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitUsage
+	}
+
+	users, _, closeBackend, err := c.connect()
+	if err != nil {
+		fmt.Fprintf(c.stderr, "failed to connect: %v\n", err)
+		return exitFailure
+	}
+	defer closeBackend()
+
+	rw, finish, err := c.openExport(flags, format, columns)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitFailure
+	}
+	n := 0
+	err = firebase.ExportUsers(context.Background(), users, func(u *auth.ExportedUserRecord) error {
+		if !filter.matches(u.UserRecord) {
+			return nil
+		}
+		n++
+		return rw.write(firebase.ExportUser(u, *withPasswords))
+	})
+	if closeErr := finish(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintf(c.stderr, "export failed after %d users: %v\n", n, err)
+		return exitFailure
+	}
+	fmt.Fprintf(c.stderr, "Exported %d %s\n", n, plural(n, "user", "users"))
+	return exitOK
+}
+
+// exportDocs writes the documents matching a query
+func (c cli) exportDocs(args []string) int {
+	fs := c.newFlagSet("export docs", "QUERY\n\nQUERY is a collection with optional clauses, such as\n  routines where uid == abc order by createdAt desc limit 100\n\nWithout --columns, a CSV export has the fields of the first document.")
+	flags := addExportFlags(fs, "")
+
+	positional, code, ok := parseArgs(fs, args, 1, 1<<16)
+	if !ok {
+		return code
+	}
+	q, err := firebase.ParseQuery(strings.Join(positional, " "))
+	if err != nil {
+		fmt.Fprintf(c.stderr, "invalid query: %v\n", err)
+		return exitUsage
+	}
+	format, err := exportFormat(*flags.file, *flags.format)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitUsage
+	}
+
+	_, docs, closeBackend, err := c.connect()
+	if err != nil {
+		fmt.Fprintf(c.stderr, "failed to connect: %v\n", err)
+		return exitFailure
+	}
+	defer closeBackend()
+
+	// Documents are written as they arrive, so a CSV header without
+	// --columns comes from the fields of the first one
+	ctx := context.Background()
+	columns := parseColumns(*flags.columns)
+	if format == "csv" && len(columns) == 0 {
+		first, err := docs.Query(ctx, q.Limit(1))
+		if err != nil {
+			fmt.Fprintf(c.stderr, "query failed: %v\n", err)
+			return exitFailure
+		}
+		columns = documentColumns(documentRecords(first))
+	}
+	rw, finish, err := c.openExport(flags, format, columns)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitFailure
+	}
+	n := 0
+	err = docs.QueryEach(ctx, q, func(d firebase.Document) error {
+		n++
+		return rw.write(firebase.ExportDocument(d))
+	})
+	if closeErr := finish(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintf(c.stderr, "export failed after %d documents: %v\n", n, err)
+		return exitFailure
+	}
+	fmt.Fprintf(c.stderr, "Exported %d %s\n", n, plural(n, "document", "documents"))
+	return exitOK
+}
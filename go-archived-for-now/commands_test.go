@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestUsersCommands tests listing, showing and changing users from the command line
+func TestUsersCommands(t *testing.T) {
+	c, stdout, stderr := demoCLI(t)
+
+	if code := c.run([]string{"users", "list", "--filter", "disabled:true"}); code != exitOK {
+		t.Fatalf("List failed with %d: %s", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "UID") || !strings.Contains(lines[1], "oscar.wijaya@example.com") {
+		t.Errorf("Expected a header and oscar, got\n%s", stdout)
+	}
+
+	stdout.Reset()
+	if code := c.run([]string{"users", "list", "-o", "json", "--limit", "2"}); code != exitOK {
+		t.Fatalf("List failed with %d: %s", code, stderr)
+	}
+	var listed []map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &listed); err != nil || len(listed) != 2 {
+		t.Fatalf("Expected a JSON array of 2 users, got %d (%v)", len(listed), err)
+	}
+	if _, ok := listed[0]["passwordHash"]; ok {
+		t.Error("Expected password hashes to be left out")
+	}
+
+	stdout.Reset()
+	if code := c.run([]string{"users", "get", "maya.santoso@example.com", "--output", "yaml"}); code != exitOK {
+		t.Fatalf("Get failed with %d: %s", code, stderr)
+	}
+	var user map[string]interface{}
+	if err := yaml.Unmarshal(stdout.Bytes(), &user); err != nil {
+		t.Fatalf("Expected YAML, got %v:\n%s", err, stdout)
+	}
+	if user["uid"] != "3xKq9PzR1vT7bN2mYc5LwA8dFe4H" || user["phoneNumber"] != "+6281234567890" {
+		t.Errorf("Got the wrong user: %v", user)
+	}
+
+	if code := c.run([]string{"users", "get", "missing"}); code != exitNotFound {
+		t.Errorf("Expected exit code %d for a missing user, got %d", exitNotFound, code)
+	}
+
+	stdout.Reset()
+	args := []string{"users", "create", "--uid", "new-user", "--email", "new@example.com", "--name", "New User", "-o", "json"}
+	if code := c.run(args); code != exitOK {
+		t.Fatalf("Create failed with %d: %s", code, stderr)
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &user); err != nil || user["displayName"] != "New User" {
+		t.Errorf("Expected the created user, got %v (%v)", user, err)
+	}
+
+	stdout.Reset()
+	if code := c.run([]string{"users", "update", "new-user", "--name", "Renamed", "-o", "json"}); code != exitOK {
+		t.Fatalf("Update failed with %d: %s", code, stderr)
+	}
+	user = nil
+	if err := json.Unmarshal(stdout.Bytes(), &user); err != nil || user["displayName"] != "Renamed" || user["email"] != "new@example.com" {
+		t.Errorf("Expected only the name to change, got %v (%v)", user, err)
+	}
+
+	stdout.Reset()
+	if code := c.run([]string{"users", "disable", "new@example.com", "-o", "json"}); code != exitOK {
+		t.Fatalf("Disable failed with %d: %s", code, stderr)
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &user); err != nil || user["disabled"] != true {
+		t.Errorf("Expected the user to be disabled, got %v (%v)", user, err)
+	}
+
+	stdout.Reset()
+	if code := c.run([]string{"users", "delete", "--cascade", "3xKq9PzR1vT7bN2mYc5LwA8dFe4H"}); code != exitOK {
+		t.Fatalf("Delete failed with %d: %s", code, stderr)
+	}
+	if code := c.run([]string{"docs", "get", "routines/rMayaPush"}); code != exitNotFound {
+		t.Errorf("Expected maya's routines to be deleted, got %d", code)
+	}
+	if code := c.run([]string{"users", "get", "3xKq9PzR1vT7bN2mYc5LwA8dFe4H"}); code != exitNotFound {
+		t.Errorf("Expected maya to be deleted, got %d", code)
+	}
+}
+
+// TestDocsCommands tests reading and writing documents from the command line
+func TestDocsCommands(t *testing.T) {
+	c, stdout, stderr := demoCLI(t)
+
+	if code := c.run([]string{"docs", "get", "routines/rMayaPush", "--columns", "id,name,createdAt"}); code != exitOK {
+		t.Fatalf("Get failed with %d: %s", code, stderr)
+	}
+	for _, want := range []string{"rMayaPush", "Push Day", "2024-04-11T07:05:00Z"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("Expected %q in\n%s", want, stdout)
+		}
+	}
+
+	stdout.Reset()
+	if code := c.run([]string{"docs", "list", "-o", "json", "--columns", "id", `routines where name == "Push Day"`}); code != exitOK {
+		t.Fatalf("List failed with %d: %s", code, stderr)
+	}
+	var listed []map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &listed); err != nil || len(listed) != 1 || listed[0]["id"] != "rMayaPush" {
+		t.Errorf("Expected rMayaPush, got %s (%v)", stdout, err)
+	}
+
+	stdout.Reset()
+	if code := c.run([]string{"docs", "set", "notes/n1", `{"text":"hello","count":2}`, "-o", "json"}); code != exitOK {
+		t.Fatalf("Set failed with %d: %s", code, stderr)
+	}
+	if code := c.run([]string{"docs", "set", "--merge", "notes/n1", `{"count":3}`}); code != exitOK {
+		t.Fatalf("Merge failed with %d: %s", code, stderr)
+	}
+	stdout.Reset()
+	if code := c.run([]string{"docs", "get", "notes/n1", "-o", "json"}); code != exitOK {
+		t.Fatalf("Get failed with %d: %s", code, stderr)
+	}
+	var note map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &note); err != nil || note["text"] != "hello" || note["count"] != float64(3) {
+		t.Errorf("Expected the merged note, got %v (%v)", note, err)
+	}
+
+	stdout.Reset()
+	if code := c.run([]string{"docs", "delete", "notes/n1"}); code != exitOK {
+		t.Fatalf("Delete failed with %d: %s", code, stderr)
+	}
+	if code := c.run([]string{"docs", "delete", "notes/n1"}); code != exitNotFound {
+		t.Errorf("Expected exit code %d deleting a missing document, got %d", exitNotFound, code)
+	}
+
+	// Paths starting with - come after --
+	for _, args := range [][]string{
+		{"docs", "set", "-o", "json", "--", "-drafts/n1", `{"text":"draft"}`},
+		{"docs", "get", "--", "-drafts/n1"},
+		{"docs", "delete", "--", "-drafts/n1"},
+	} {
+		if code := c.run(args); code != exitOK {
+			t.Errorf("%s: failed with %d: %s", strings.Join(args, " "), code, stderr)
+		}
+	}
+}
+
+// TestParseFlags tests flags between positional arguments, -- and negative numbers
+func TestParseFlags(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		want string // the positional arguments and the flag, or empty for an error
+	}{
+		{[]string{"a", "--name", "x", "b"}, "[a b] x"},
+		{[]string{"--name", "x", "--", "-a", "--name", "y"}, "[-a --name y] x"},
+		{[]string{"a", "-5", "-2.5", "--name", "x"}, "[a -5 -2.5] x"},
+		{[]string{"--", "--"}, "[--] "},
+		{[]string{"-a"}, ""},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		name := fs.String("name", "", "")
+		positional, err := parseFlags(fs, tc.args)
+		got := ""
+		if err == nil {
+			got = fmt.Sprintf("%v %s", positional, *name)
+		}
+		if got != tc.want {
+			t.Errorf("parseFlags(%q) = %q (%v), want %q", tc.args, got, err, tc.want)
+		}
+	}
+}
+
+// TestCommandUsageErrors tests that bad arguments exit with the usage code
+func TestCommandUsageErrors(t *testing.T) {
+	c, _, _ := demoCLI(t)
+
+	for _, args := range [][]string{
+		{"users"},
+		{"users", "list", "-o", "xml"},
+		{"users", "list", "--filter", "nonsense:1"},
+		{"users", "get"},
+		{"users", "update", "new-user"},
+		{"users", "create", "--email", "not-an-email"},
+		{"docs", "get", "routines"},
+		{"docs", "set", "notes/n1", "{"},
+		{"docs", "list", "routines where"},
+	} {
+		if code := c.run(args); code != exitUsage {
+			t.Errorf("%s: expected exit code %d, got %d", strings.Join(args, " "), exitUsage, code)
+		}
+	}
+}
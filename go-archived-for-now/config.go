@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"arrogance/firebase"
+)
+
+// Config holds the settings read from the config file
+type Config struct {
+	// Keys remaps key bindings by name, for example
+	// {"users.next_page": ["n", "ctrl+n"]}. See keyBindings for the names.
+	Keys map[string][]string `json:"keys"`
+
+	// Links configures the email action links generated for users
+	Links LinksConfig `json:"links"`
+
+	// Import configures importing users from files
+	Import ImportConfig `json:"import"`
+}
+
+// LinksConfig configures the email action links generated for users
+type LinksConfig struct {
+	// ContinueURL is where users end up after using a link. Email sign-in
+	// links need one, since the app at this URL completes the sign-in.
+	ContinueURL string `json:"continue_url"`
+}
+
+// ImportConfig configures importing users from files
+type ImportConfig struct {
+	// Hash describes the password hashes in imported files
+	Hash HashSettings `json:"hash"`
+}
+
+// HashSettings describe how the password hashes of imported users were made.
+// Keys are base64, as shown in the Firebase console's password hash parameters.
+type HashSettings struct {
+	Algorithm        string `json:"algorithm"`
+	Key              string `json:"key"`
+	SaltSeparator    string `json:"salt_separator"`
+	Rounds           int    `json:"rounds"`
+	MemoryCost       int    `json:"memory_cost"`
+	BlockSize        int    `json:"block_size"`
+	Parallelization  int    `json:"parallelization"`
+	DerivedKeyLength int    `json:"derived_key_length"`
+}
+
+// config decodes and validates the settings. It returns nil when no
+// algorithm is set, for files without password hashes.
+func (h HashSettings) config() (*firebase.HashConfig, error) {
+	if h.Algorithm == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(h.Key)
+	if err != nil {
+		return nil, errors.New("the hash key must be base64")
+	}
+	separator, err := base64.StdEncoding.DecodeString(h.SaltSeparator)
+	if err != nil {
+		return nil, errors.New("the salt separator must be base64")
+	}
+
+	hash := &firebase.HashConfig{
+		Algorithm:        h.Algorithm,
+		Key:              key,
+		SaltSeparator:    separator,
+		Rounds:           h.Rounds,
+		MemoryCost:       h.MemoryCost,
+		BlockSize:        h.BlockSize,
+		Parallelization:  h.Parallelization,
+		DerivedKeyLength: h.DerivedKeyLength,
+	}
+	if err := hash.Validate(); err != nil {
+		return nil, err
+	}
+	return hash, nil
+}
+
+// defaultConfigPath returns the config file used when none is given on the command line
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "arrogance", "config.json")
+}
+
+// loadConfig reads the config file at path. A missing file gives the
+// default config unless the file is required.
+func loadConfig(path string, required bool) (Config, error) {
+	var cfg Config
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// apply applies the config to the app. It must run before the app starts.
+func (c Config) apply() error {
+	if err := remapKeys(c.Keys); err != nil {
+		return fmt.Errorf("invalid keys in config file: %w", err)
+	}
+	if c.Links.ContinueURL != "" {
+		u, err := url.Parse(c.Links.ContinueURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid links.continue_url in config file: %q is not an http or https URL", c.Links.ContinueURL)
+		}
+	}
+	linkSettings = firebase.ActionCodeSettings{ContinueURL: c.Links.ContinueURL}
+
+	if _, err := c.Import.Hash.config(); err != nil {
+		return fmt.Errorf("invalid import.hash in config file: %w", err)
+	}
+	importHash = c.Import.Hash
+	return nil
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"arrogance/firebase"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// DocumentChildrenMsg is sent when the sections of a document are loaded
+type documentChildrenMsg struct {
+	path     string
+	children map[string][]string // section title -> lines
+	err      error
+}
+
+// documentSection is a list shown under the fields of a document, such as
+// the records of a profile. load returns one line per entry.
+type documentSection struct {
+	title string
+	load  func(ctx context.Context, storeSvc firebase.DocumentStore, d firebase.Document) ([]string, error)
+}
+
+// documentScreen shows every field of a document along with its sections
+type documentScreen struct {
+	frame
+	storeSvc firebase.DocumentStore
+	keys     documentKeyMap
+
+	doc      firebase.Document
+	owner    string
+	lookups  map[string]string
+	sections []documentSection
+	children map[string][]string
+	loading  bool
+	err      string
+	viewport viewport.Model
+}
+
+// documentKeyMap holds the keys of the document screen
+type documentKeyMap struct {
+	Up   key.Binding
+	Down key.Binding
+}
+
+var documentKeys = documentKeyMap{
+	Up:   key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "scroll up")),
+	Down: key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "scroll down")),
+}
+
+// ShortHelp returns the keys shown in the footer
+func (k documentKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Up, k.Down}
+}
+
+// FullHelp returns every key of the screen
+func (k documentKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{{k.Up, k.Down}}
+}
+
+// newDocumentScreen creates the screen for a document. owner is shown as the
+// document's owner and lookups name the documents it references.
+func newDocumentScreen(storeSvc firebase.DocumentStore, doc firebase.Document, owner string, lookups map[string]string, sections []documentSection) documentScreen {
+	return documentScreen{
+		storeSvc: storeSvc,
+		keys:     documentKeys,
+		doc:      doc,
+		owner:    owner,
+		lookups:  lookups,
+		sections: sections,
+		loading:  len(sections) > 0,
+		viewport: withScrollKeys(viewport.New(0, 0), documentKeys.Up, documentKeys.Down),
+	}
+}
+
+// Init loads the sections of the document
+func (s documentScreen) Init() tea.Cmd {
+	if len(s.sections) == 0 {
+		return nil
+	}
+	return fetchDocumentChildren(s.storeSvc, s.doc, s.sections)
+}
+
+// KeyMap returns the keys of the document screen
+func (s documentScreen) KeyMap() help.KeyMap {
+	return s.keys
+}
+
+// Loading reports whether sections are loading
+func (s documentScreen) Loading() bool {
+	return s.loading
+}
+
+// Update handles loading sections and scrolling
+func (s documentScreen) Update(msg tea.Msg) (Screen, tea.Cmd) {
+	s.track(msg)
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.viewport.Width = s.width - 8
+		s.viewport.Height = s.height - 12
+		s.viewport.SetContent(s.render())
+
+	case documentChildrenMsg:
+		// Ignore other documents
+		if msg.path != s.doc.Path {
+			return s, nil
+		}
+		s.loading = false
+		if msg.err != nil {
+			s.err = msg.err.Error()
+			return s, nil
+		}
+		s.children = msg.children
+		s.viewport.SetContent(s.render())
+
+	case tea.KeyMsg:
+		var cmd tea.Cmd
+		s.viewport, cmd = s.viewport.Update(msg)
+		return s, cmd
+	}
+
+	return s, nil
+}
+
+// View shows the document
+func (s documentScreen) View() string {
+	body := s.viewport.View()
+	if s.loading {
+		titles := make([]string, len(s.sections))
+		for i, section := range s.sections {
+			titles[i] = section.title
+		}
+		body = loadingStyle.Render(s.spinner()+" Loading "+strings.Join(titles, ", ")+"...") + "\n\n" + body
+	} else if s.err != "" {
+		body = errorStyle.Render("Error loading document: "+s.err) + "\n\n" + body
+	}
+
+	return lipgloss.NewStyle().
+		Height(s.height-12).
+		Padding(0, 2).
+		Render(body)
+}
+
+// render renders the scrollable body of the document screen
+func (s documentScreen) render() string {
+	var sb strings.Builder
+
+	field := func(label, value string) {
+		if value == "" {
+			value = "-"
+		}
+		sb.WriteString(labelStyle.Render(label) + value + "\n")
+	}
+
+	d := s.doc
+	sb.WriteString(sectionStyle.Render(documentName(d)) + "\n")
+	field("ID", d.ID)
+	field("Path", d.Path)
+	field("Owner", s.owner)
+	field("Created", formatTime(d.CreateTime))
+	field("Last updated", formatTime(d.UpdateTime))
+
+	sb.WriteString("\n" + sectionStyle.Render("Fields") + "\n")
+	for _, f := range flattenFields(d.Data) {
+		field(f.path, s.formatValue(f.value))
+	}
+
+	for _, section := range s.sections {
+		lines, ok := s.children[section.title]
+		if !ok {
+			continue
+		}
+		title := strings.ToUpper(section.title[:1]) + section.title[1:]
+		sb.WriteString(fmt.Sprintf("\n%s\n", sectionStyle.Render(fmt.Sprintf("%s (%d)", title, len(lines)))))
+		if len(lines) == 0 {
+			sb.WriteString("-\n")
+		}
+		for i, line := range lines {
+			sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, line))
+		}
+	}
+
+	return sb.String()
+}
+
+// formatValue formats a document field for display, naming referenced documents
+func (s documentScreen) formatValue(value interface{}) string {
+	switch val := value.(type) {
+	case nil:
+		return "null"
+	case time.Time:
+		return formatTime(val)
+	case string:
+		if name := s.lookups[val]; name != "" {
+			return fmt.Sprintf("%s [%s]", name, val)
+		}
+		return val
+	case []interface{}:
+		items := make([]string, len(val))
+		for i, item := range val {
+			items[i] = s.formatValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// fetchDocumentChildren loads the sections of a document
+func fetchDocumentChildren(storeSvc firebase.DocumentStore, d firebase.Document, sections []documentSection) tea.Cmd {
+	return func() tea.Msg {
+		msg := documentChildrenMsg{path: d.Path}
+		if storeSvc == nil {
+			msg.err = errors.New("firestore service not initialized")
+			return msg
+		}
+
+		msg.children = make(map[string][]string, len(sections))
+		for _, section := range sections {
+			lines, err := section.load(context.Background(), storeSvc, d)
+			if err != nil {
+				msg.err = err
+				return msg
+			}
+			msg.children[section.title] = lines
+		}
+		return msg
+	}
+}
+
+// documentName returns a human readable name for a document of any
+// collection, from its name or the name of its workout
+func documentName(d firebase.Document) string {
+	if name, ok := d.Data["name"].(string); ok && name != "" {
+		return name
+	}
+	if workout, ok := d.Data["workout"].(map[string]interface{}); ok {
+		if name, ok := workout["name"].(string); ok && name != "" {
+			return name
+		}
+	}
+	return "(unnamed)"
+}
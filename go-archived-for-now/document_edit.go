@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"arrogance/firebase"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// fieldKinds are the types a field can be given in the document editor
+var fieldKinds = []string{"string", "number", "boolean", "timestamp", "null", "reference", "geopoint", "bytes", "map", "array"}
+
+// fieldKind returns the editor type of a field value
+func fieldKind(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case int64, int, float64:
+		return "number"
+	case time.Time:
+		return "timestamp"
+	case *firestore.DocumentRef:
+		return "reference"
+	case *latlng.LatLng:
+		return "geopoint"
+	case []byte:
+		return "bytes"
+	case map[string]interface{}:
+		return "map"
+	case []interface{}:
+		return "array"
+	}
+	return "string"
+}
+
+// fieldInput formats a field value as the text typed into the editor for its type
+func fieldInput(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case *latlng.LatLng:
+		return fmt.Sprintf("%g, %g", v.Latitude, v.Longitude)
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(firebase.ExportValue(v))
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	case *firestore.DocumentRef, []byte:
+		text, _ := firebase.ExportValue(v).(string)
+		return text
+	}
+	text, _ := treeValue(value)
+	return text
+}
+
+// parseFieldInput converts the text typed into the editor to a value of kind.
+// References are made by the document store, as they belong to its database.
+func parseFieldInput(kind, text string, storeSvc firebase.DocumentStore) (interface{}, error) {
+	text = strings.TrimSpace(text)
+	switch kind {
+	case "string":
+		return text, nil
+	case "null":
+		return nil, nil
+	case "number":
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n, nil
+		}
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, errors.New("enter a number such as 42 or 72.5")
+		}
+		return f, nil
+	case "boolean":
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, errors.New("enter true or false")
+		}
+		return b, nil
+	case "timestamp":
+		t, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return nil, errors.New("enter a time such as 2024-04-11T07:05:00Z")
+		}
+		return t, nil
+	case "reference":
+		if storeSvc == nil {
+			return nil, errors.New("firestore service not initialized")
+		}
+		ref, err := storeSvc.Reference(text)
+		if err != nil {
+			return nil, errors.New("enter a document path such as exercises/abc")
+		}
+		return ref, nil
+	case "geopoint":
+		lat, lng, ok := strings.Cut(text, ",")
+		latitude, latErr := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+		longitude, lngErr := strconv.ParseFloat(strings.TrimSpace(lng), 64)
+		if !ok || latErr != nil || lngErr != nil || latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+			return nil, errors.New("enter a latitude and longitude such as -6.2, 106.8")
+		}
+		return &latlng.LatLng{Latitude: latitude, Longitude: longitude}, nil
+	case "bytes":
+		b, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return nil, errors.New("enter base64 data")
+		}
+		return b, nil
+	case "map", "array":
+		if text == "" {
+			text = map[string]string{"map": "{}", "array": "[]"}[kind]
+		}
+		v, err := firebase.ValueFromJSON([]byte(text))
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+		if fieldKind(v) != kind {
+			return nil, fmt.Errorf("enter a JSON %s", map[string]string{"map": "object", "array": "array"}[kind])
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", kind)
+}
+
+// sameValue reports whether two field values are equal, including their types
+func sameValue(a, b interface{}) bool {
+	if fieldKind(a) != fieldKind(b) {
+		return false
+	}
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b := b.(map[string]interface{})
+		if len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if other, ok := b[k]; !ok || !sameValue(v, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b := b.([]interface{})
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !sameValue(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return bytes.Equal(exportJSON(a), exportJSON(b))
+}
+
+// exportJSON encodes a value the way exports write it
+func exportJSON(v interface{}) []byte {
+	data, _ := json.Marshal(firebase.ExportValue(v))
+	return data
+}
+
+// documentChanges compares edited document data with the data that was
+// read. It returns the updates to apply, keyed by top-level field, with
+// removed fields deleted, and a line describing each change.
+func documentChanges(before, after map[string]interface{}) (map[string]interface{}, []string) {
+	updates := map[string]interface{}{}
+	var diff []string
+
+	names := make(map[string]interface{}, len(before)+len(after))
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+	for _, name := range sortedKeys(names) {
+		old, hadOld := before[name]
+		value, hasNew := after[name]
+		switch {
+		case !hasNew:
+			updates[name] = firestore.Delete
+			diff = append(diff, fmt.Sprintf("- %s: %s", name, fieldPreview(old)))
+		case !hadOld:
+			updates[name] = value
+			diff = append(diff, fmt.Sprintf("+ %s: %s", name, fieldPreview(value)))
+		case !sameValue(old, value):
+			updates[name] = value
+			diff = append(diff, fmt.Sprintf("~ %s: %s → %s", name, fieldPreview(old), fieldPreview(value)))
+		}
+	}
+	return updates, diff
+}
+
+// applyChanges returns a copy of data with the updates made by
+// documentChanges applied
+func applyChanges(data, updates map[string]interface{}) map[string]interface{} {
+	out := copyDraft(data)
+	for name, value := range updates {
+		if value == firestore.Delete {
+			delete(out, name)
+			continue
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// quoteFields keys updates made by documentChanges by field path, so a field
+// named with a dot isn't taken for a nested one
+func quoteFields(updates map[string]interface{}) map[string]interface{} {
+	quoted := make(map[string]interface{}, len(updates))
+	for name, value := range updates {
+		quoted[firebase.QuoteField(name)] = value
+	}
+	return quoted
+}
+
+// fieldPreview shows a field value and its type on one line
+func fieldPreview(value interface{}) string {
+	kind := fieldKind(value)
+	text := fieldInput(value)
+	switch kind {
+	case "string":
+		text = strconv.Quote(text)
+	case "null":
+		return "null"
+	}
+	return truncate(text, maxCellWidth) + " (" + kind + ")"
+}
+
+// restoreTypes keeps the original value of every field of edited JSON whose
+// JSON is unchanged, so references, geopoints and bytes survive a round trip
+// through the editor. Changed maps are compared field by field.
+func restoreTypes(original, edited map[string]interface{}) map[string]interface{} {
+	for name, value := range edited {
+		old, ok := original[name]
+		if !ok {
+			continue
+		}
+		if bytes.Equal(exportJSON(old), exportJSON(value)) {
+			edited[name] = old
+			continue
+		}
+		oldMap, oldIsMap := old.(map[string]interface{})
+		newMap, newIsMap := value.(map[string]interface{})
+		if oldIsMap && newIsMap {
+			edited[name] = restoreTypes(oldMap, newMap)
+		}
+	}
+	return edited
+}
+
+// writeEditorFile writes document data as indented JSON to a temporary file
+// for editing
+func writeEditorFile(data map[string]interface{}) (string, error) {
+	content, err := json.MarshalIndent(firebase.ExportValue(data), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp("", "arrogance-document-*.json")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(append(content, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// readEditorFile reads document data back from an edited file, restoring
+// the types of unchanged fields from original
+func readEditorFile(path string, original map[string]interface{}) (map[string]interface{}, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	edited, err := firebase.DocumentFromJSON(content)
+	if err != nil {
+		return nil, err
+	}
+	return restoreTypes(original, edited), nil
+}
+
+// editorCommand returns the command that edits file in the user's editor,
+// from $VISUAL or $EDITOR, defaulting to vi
+func editorCommand(file string) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if strings.TrimSpace(editor) == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	// The editor may come with arguments, such as "code --wait"
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		args = []string{"vi"}
+	}
+	return exec.Command(args[0], append(args[1:], file)...)
+}
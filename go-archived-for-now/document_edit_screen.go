@@ -0,0 +1,559 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"arrogance/firebase"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// editStage is what the document editor is showing
+type editStage int
+
+const (
+	editFields editStage = iota // the list of fields
+	editName                    // the name of a new field
+	editValue                   // the type and value of a field
+	editReview                  // the changes before saving
+)
+
+// EditorClosedMsg is sent when the external editor editing a document exits
+type editorClosedMsg struct {
+	path string
+	file string
+	err  error
+}
+
+// DocumentEditSavedMsg is sent when an edited document has been saved, or
+// saving it failed
+type documentEditSavedMsg struct {
+	path string
+	err  error
+}
+
+// DocumentEditReloadedMsg is sent when the latest version of a document that
+// couldn't be saved has been read again
+type documentEditReloadedMsg struct {
+	path string
+	doc  firebase.Document
+	err  error
+}
+
+// documentEditKeyMap holds the keys of the document editor
+type documentEditKeyMap struct {
+	Up     key.Binding
+	Down   key.Binding
+	Edit   key.Binding
+	Add    key.Binding
+	Delete key.Binding
+	Editor key.Binding
+	Type   key.Binding
+	Review key.Binding
+	Save   key.Binding
+	Reload key.Binding
+	Cancel key.Binding
+}
+
+var documentEditKeys = documentEditKeyMap{
+	Up:     key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "up")),
+	Down:   key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "down")),
+	Edit:   key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "edit field")),
+	Add:    key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "add field")),
+	Delete: key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "delete field")),
+	Editor: key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit as JSON")),
+	Type:   key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "next type")),
+	Review: key.NewBinding(key.WithKeys("ctrl+s"), key.WithHelp("ctrl+s", "review changes")),
+	Save:   key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "save")),
+	Reload: key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "reload and re-apply")),
+	Cancel: key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+}
+
+// ShortHelp returns the keys shown in the footer
+func (k documentEditKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Edit, k.Add, k.Delete, k.Editor, k.Type, k.Review, k.Save, k.Reload, k.Cancel}
+}
+
+// FullHelp returns every key of the editor
+func (k documentEditKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{{k.Up, k.Down, k.Edit, k.Add, k.Delete}, {k.Editor, k.Type, k.Review, k.Save, k.Reload, k.Cancel}}
+}
+
+// documentEditScreen edits a document field by field, or as JSON in the
+// user's editor, and saves the changes after showing them. The update only
+// applies if the document hasn't been written since it was read; if it has,
+// the changes can be re-applied to the latest version and reviewed again.
+type documentEditScreen struct {
+	frame
+	storeSvc firebase.DocumentStore
+	keys     documentEditKeyMap
+
+	doc    firebase.Document
+	draft  map[string]interface{}
+	cursor int
+	stage  editStage
+
+	// The field being edited, its type and the text of its value or new name
+	field string
+	kind  int
+	input textinput.Model
+
+	updates   map[string]interface{}
+	diff      []string
+	saving    bool
+	conflict  bool // the last save found the document changed by someone else
+	reloading bool
+	notice    string
+	err       string
+}
+
+// newDocumentEditScreen creates the editor for a document read with its update time
+func newDocumentEditScreen(storeSvc firebase.DocumentStore, doc firebase.Document) documentEditScreen {
+	input := textinput.New()
+	input.CharLimit = 4096
+	input.Width = 60
+	return documentEditScreen{
+		storeSvc: storeSvc,
+		keys:     documentEditKeys,
+		doc:      doc,
+		draft:    copyDraft(doc.Data),
+		input:    input,
+	}
+}
+
+// copyDraft returns a shallow copy of document data, so edits never change
+// the data of earlier screen values
+func copyDraft(data map[string]interface{}) map[string]interface{} {
+	draft := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		draft[k] = v
+	}
+	return draft
+}
+
+// Init has nothing to load
+func (s documentEditScreen) Init() tea.Cmd {
+	return nil
+}
+
+// KeyMap returns the keys of the current stage of the editor
+func (s documentEditScreen) KeyMap() help.KeyMap {
+	k := s.keys
+	hasFields := len(s.draft) > 0
+	list := s.stage == editFields
+	k.Up.SetEnabled(list && hasFields)
+	k.Down.SetEnabled(list && hasFields)
+	k.Edit.SetEnabled(s.stage != editReview && (hasFields || !list))
+	k.Add.SetEnabled(list)
+	k.Delete.SetEnabled(list && hasFields)
+	k.Editor.SetEnabled(list)
+	k.Type.SetEnabled(s.stage == editValue)
+	k.Review.SetEnabled(list)
+	k.Save.SetEnabled(s.stage == editReview && !s.Loading())
+	k.Reload.SetEnabled(s.stage == editReview && s.conflict && !s.Loading())
+
+	switch s.stage {
+	case editName:
+		k.Edit.SetHelp(k.Edit.Help().Key, "next")
+		k.Cancel.SetHelp(k.Cancel.Help().Key, "back")
+	case editValue:
+		k.Edit.SetHelp(k.Edit.Help().Key, "apply")
+		k.Cancel.SetHelp(k.Cancel.Help().Key, "back")
+	case editReview:
+		k.Cancel.SetHelp(k.Cancel.Help().Key, "back")
+	}
+	return k
+}
+
+// Loading reports whether the document is being saved or reloaded
+func (s documentEditScreen) Loading() bool {
+	return s.saving || s.reloading
+}
+
+// CapturesInput keeps every key on the editor, which is closed with its cancel key
+func (s documentEditScreen) CapturesInput() bool {
+	return true
+}
+
+// names returns the names of the fields being edited, in order
+func (s documentEditScreen) names() []string {
+	return sortedKeys(s.draft)
+}
+
+// Update handles editing fields, the external editor and saving
+func (s documentEditScreen) Update(msg tea.Msg) (Screen, tea.Cmd) {
+	s.track(msg)
+
+	switch msg := msg.(type) {
+	case editorClosedMsg:
+		if msg.path != s.doc.Path {
+			return s, nil
+		}
+		return s.readEditor(msg), nil
+
+	case documentEditSavedMsg:
+		if !s.saving || msg.path != s.doc.Path {
+			return s, nil
+		}
+		s.saving = false
+		if msg.err != nil {
+			var conflict *firebase.ConflictError
+			s.conflict = errors.As(msg.err, &conflict)
+			s.err = msg.err.Error()
+			return s, nil
+		}
+		return s, popScreen
+
+	case documentEditReloadedMsg:
+		if !s.reloading || msg.path != s.doc.Path {
+			return s, nil
+		}
+		return s.reapply(msg), nil
+
+	case tea.KeyMsg:
+		if s.Loading() {
+			return s, nil
+		}
+		switch s.stage {
+		case editFields:
+			return s.handleFieldsKey(msg)
+		case editReview:
+			switch {
+			case key.Matches(msg, s.keys.Save):
+				s.saving = true
+				s.conflict = false
+				s.err = ""
+				return s, saveDocumentEdit(s.storeSvc, s.doc, s.updates)
+			case s.conflict && key.Matches(msg, s.keys.Reload):
+				s.reloading = true
+				return s, reloadEditedDocument(s.storeSvc, s.doc.Path)
+			case key.Matches(msg, s.keys.Cancel):
+				s.stage = editFields
+				s.conflict = false
+				s.err = ""
+			}
+			return s, nil
+		default:
+			return s.handleInputKey(msg)
+		}
+	}
+
+	return s, nil
+}
+
+// handleFieldsKey handles the keys of the field list
+func (s documentEditScreen) handleFieldsKey(msg tea.KeyMsg) (Screen, tea.Cmd) {
+	names := s.names()
+	s.notice = ""
+
+	switch {
+	case key.Matches(msg, s.keys.Cancel):
+		return s, popScreen
+	case key.Matches(msg, s.keys.Up):
+		s.cursor = max(s.cursor-1, 0)
+	case key.Matches(msg, s.keys.Down):
+		s.cursor = min(s.cursor+1, max(len(names)-1, 0))
+	case key.Matches(msg, s.keys.Edit):
+		if len(names) == 0 {
+			return s, nil
+		}
+		value := s.draft[names[s.cursor]]
+		s.field = names[s.cursor]
+		s.kind = slices.Index(fieldKinds, fieldKind(value))
+		return s.startInput(editValue, fieldInput(value), "")
+	case key.Matches(msg, s.keys.Add):
+		return s.startInput(editName, "", "field name")
+	case key.Matches(msg, s.keys.Delete):
+		if len(names) == 0 {
+			return s, nil
+		}
+		s.draft = copyDraft(s.draft)
+		delete(s.draft, names[s.cursor])
+		s.cursor = min(s.cursor, max(len(names)-2, 0))
+	case key.Matches(msg, s.keys.Editor):
+		file, err := writeEditorFile(s.draft)
+		if err != nil {
+			s.err = "Could not write the document for the editor: " + err.Error()
+			return s, nil
+		}
+		path := s.doc.Path
+		return s, tea.ExecProcess(editorCommand(file), func(err error) tea.Msg {
+			return editorClosedMsg{path: path, file: file, err: err}
+		})
+	case key.Matches(msg, s.keys.Review):
+		s.updates, s.diff = documentChanges(s.doc.Data, s.draft)
+		if len(s.diff) == 0 {
+			s.notice = "No changes to save"
+			return s, nil
+		}
+		s.stage = editReview
+		s.err = ""
+	}
+	return s, nil
+}
+
+// startInput moves to a stage that types into the input
+func (s documentEditScreen) startInput(stage editStage, value, placeholder string) (Screen, tea.Cmd) {
+	s.stage = stage
+	s.err = ""
+	s.input.SetValue(value)
+	s.input.Placeholder = placeholder
+	s.input.CursorEnd()
+	s.input.Focus()
+	return s, textinput.Blink
+}
+
+// handleInputKey handles the keys of the new field name and field value stages
+func (s documentEditScreen) handleInputKey(msg tea.KeyMsg) (Screen, tea.Cmd) {
+	switch {
+	case key.Matches(msg, s.keys.Cancel):
+		s.stage = editFields
+		s.err = ""
+		s.input.Blur()
+		return s, nil
+
+	case s.stage == editValue && key.Matches(msg, s.keys.Type):
+		s.kind = (s.kind + 1) % len(fieldKinds)
+		s.err = ""
+		return s, nil
+
+	case s.stage == editName && key.Matches(msg, s.keys.Edit):
+		name := strings.TrimSpace(s.input.Value())
+		if err := s.checkNewField(name); err != nil {
+			s.err = err.Error()
+			return s, nil
+		}
+		s.field = name
+		s.kind = 0
+		return s.startInput(editValue, "", "")
+
+	case s.stage == editValue && key.Matches(msg, s.keys.Edit):
+		value, err := parseFieldInput(fieldKinds[s.kind], s.input.Value(), s.storeSvc)
+		if err != nil {
+			s.err = err.Error()
+			return s, nil
+		}
+		s.draft = copyDraft(s.draft)
+		s.draft[s.field] = value
+		s.cursor = slices.Index(s.names(), s.field)
+		s.stage = editFields
+		s.err = ""
+		s.input.Blur()
+		return s, nil
+	}
+
+	var cmd tea.Cmd
+	s.input, cmd = s.input.Update(msg)
+	return s, cmd
+}
+
+// checkNewField reports why a field can't be added under name. Updates are
+// keyed by field path, so names can't contain dots; nested fields are edited
+// through their map.
+func (s documentEditScreen) checkNewField(name string) error {
+	switch {
+	case name == "":
+		return errors.New("enter a field name")
+	case strings.ContainsAny(name, ".`"):
+		return errors.New("field names can't contain dots or backticks, edit nested fields through their map")
+	}
+	if _, ok := s.draft[name]; ok {
+		return fmt.Errorf("%s already exists", name)
+	}
+	return nil
+}
+
+// readEditor replaces the draft with the document saved in the external
+// editor. An invalid file is kept so the edit isn't lost.
+func (s documentEditScreen) readEditor(msg editorClosedMsg) documentEditScreen {
+	if msg.err != nil {
+		os.Remove(msg.file)
+		s.err = "The editor failed: " + msg.err.Error()
+		return s
+	}
+	draft, err := readEditorFile(msg.file, s.draft)
+	if err != nil {
+		s.err = fmt.Sprintf("The edited JSON is invalid, it's kept in %s: %v", msg.file, err)
+		return s
+	}
+	os.Remove(msg.file)
+	s.draft = draft
+	s.cursor = min(s.cursor, max(len(draft)-1, 0))
+	s.err = ""
+	return s
+}
+
+// reapply makes the reloaded document the one being edited and applies the
+// same changes to it, returning to the review so they can be checked again
+func (s documentEditScreen) reapply(msg documentEditReloadedMsg) documentEditScreen {
+	s.reloading = false
+	if msg.err != nil {
+		s.conflict = false
+		s.err = msg.err.Error()
+		return s
+	}
+
+	s.doc = msg.doc
+	s.draft = applyChanges(msg.doc.Data, s.updates)
+	s.cursor = min(s.cursor, max(len(s.draft)-1, 0))
+	s.updates, s.diff = documentChanges(s.doc.Data, s.draft)
+	s.conflict = false
+	s.err = ""
+	if len(s.diff) == 0 {
+		s.stage = editFields
+		s.notice = "The latest version already has your changes"
+		return s
+	}
+	s.notice = "Your changes were re-applied to the latest version, review them again"
+	return s
+}
+
+// reloadEditedDocument reads the latest version of a document that couldn't be saved
+func reloadEditedDocument(storeSvc firebase.DocumentStore, path string) tea.Cmd {
+	return func() tea.Msg {
+		collection, id, err := firebase.SplitDocumentPath(path)
+		var doc firebase.Document
+		if err == nil {
+			doc, err = storeSvc.GetDocument(context.Background(), collection, id)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to reload %s: %w", path, err)
+		}
+		return documentEditReloadedMsg{path: path, doc: doc, err: err}
+	}
+}
+
+// saveDocumentEdit applies the changes to a document, provided it hasn't been
+// written since it was read
+func saveDocumentEdit(storeSvc firebase.DocumentStore, doc firebase.Document, updates map[string]interface{}) tea.Cmd {
+	return func() tea.Msg {
+		collection, id, err := firebase.SplitDocumentPath(doc.Path)
+		if err == nil {
+			err = storeSvc.Update(context.Background(), collection, id, quoteFields(updates), firebase.LastUpdateTime(doc.UpdateTime))
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to save %s: %w", doc.Path, err)
+		}
+		return documentEditSavedMsg{path: doc.Path, err: err}
+	}
+}
+
+// View shows the stage of the editor in a modal
+func (s documentEditScreen) View() string {
+	var body string
+	switch s.stage {
+	case editFields:
+		body = s.fieldsView()
+	case editName:
+		body = "New field name\n\n" + s.input.View()
+	case editValue:
+		body = s.valueView()
+	case editReview:
+		body = s.reviewView()
+	}
+
+	switch {
+	case s.saving:
+		body += "\n\n" + loadingStyle.UnsetMarginLeft().Render(s.spinner()+" Saving...")
+	case s.reloading:
+		body += "\n\n" + loadingStyle.UnsetMarginLeft().Render(s.spinner()+" Reloading...")
+	case s.conflict:
+		body += "\n\n" + errorStyle.UnsetMarginLeft().Width(70).Render(s.err+". Press r to reload it and re-apply your changes.")
+	case s.err != "":
+		body += "\n\n" + errorStyle.UnsetMarginLeft().Width(70).Render(s.err)
+	case s.notice != "":
+		body += "\n\n" + successStyle.UnsetMarginLeft().Render(s.notice)
+	}
+
+	return renderModal(s.width-8, s.height-12, "Edit "+s.doc.Path, body, newHelp().View(s.KeyMap()))
+}
+
+// fieldsView lists the fields, marking the ones that were changed
+func (s documentEditScreen) fieldsView() string {
+	names := s.names()
+	if len(names) == 0 {
+		return "No fields. Press a to add one."
+	}
+
+	// Keep the cursor in view
+	visible := max(s.height-24, 3)
+	start := max(0, min(s.cursor-visible/2, len(names)-visible))
+
+	var sb strings.Builder
+	for i := start; i < min(start+visible, len(names)); i++ {
+		name := names[i]
+		marker := "  "
+		if old, ok := s.doc.Data[name]; !ok || !sameValue(old, s.draft[name]) {
+			marker = "* "
+		}
+		line := marker + labelStyle.UnsetForeground().Render(name) + fieldPreview(s.draft[name])
+		if i == s.cursor {
+			line = highlightStyle.Render(line)
+		}
+		sb.WriteString(line + "\n")
+	}
+	if removed := len(s.doc.Data) - countKept(s.doc.Data, s.draft); removed > 0 {
+		sb.WriteString(fmt.Sprintf("\n%d %s deleted", removed, plural(removed, "field", "fields")))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// countKept counts the fields of data that are still in draft
+func countKept(data, draft map[string]interface{}) int {
+	n := 0
+	for name := range data {
+		if _, ok := draft[name]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+// valueView shows the type and value of the field being edited
+func (s documentEditScreen) valueView() string {
+	kind := fieldKinds[s.kind]
+	body := labelStyle.Render("Field") + s.field + "\n"
+	body += labelStyle.Render("Type") + highlightStyle.Render(kind) + "\n"
+	if kind == "null" {
+		return body + labelStyle.Render("Value") + "null"
+	}
+	hints := map[string]string{
+		"timestamp": "RFC 3339, such as 2024-04-11T07:05:00Z",
+		"reference": "document path, such as exercises/abc",
+		"geopoint":  "latitude, longitude",
+		"bytes":     "base64",
+		"map":       "JSON object",
+		"array":     "JSON array",
+	}
+	body += labelStyle.Render("Value") + s.input.View()
+	if hint := hints[kind]; hint != "" {
+		body += "\n" + labelStyle.Render("") + hint
+	}
+	return body
+}
+
+// reviewView shows the changes that will be saved
+func (s documentEditScreen) reviewView() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d %s to save:\n\n", len(s.diff), plural(len(s.diff), "change", "changes")))
+	for _, line := range s.diff {
+		style := loadingStyle
+		switch line[0] {
+		case '+':
+			style = successStyle
+		case '-':
+			style = errorStyle
+		}
+		sb.WriteString(style.UnsetMarginLeft().Render(line) + "\n")
+	}
+	sb.WriteString("\nSaved only if the document is unchanged since " + formatTime(s.doc.UpdateTime) + ".")
+	return sb.String()
+}
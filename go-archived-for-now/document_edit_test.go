@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	tea "github.com/charmbracelet/bubbletea"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// TestParseFieldInput tests converting typed text to each field type
+func TestParseFieldInput(t *testing.T) {
+	_, storeSvc := CreateDemoBackend(t)
+
+	for _, tc := range []struct {
+		kind, text string
+		want       string // fieldPreview of the value, or empty for an error
+	}{
+		{"string", "Push Day", `"Push Day" (string)`},
+		{"number", "42", "42 (number)"},
+		{"number", "72.5", "72.5 (number)"},
+		{"number", "heavy", ""},
+		{"boolean", "true", "true (boolean)"},
+		{"boolean", "yes", ""},
+		{"timestamp", "2024-04-11T07:05:00Z", "2024-04-11T07:05:00Z (timestamp)"},
+		{"timestamp", "11 Apr 2024", ""},
+		{"null", "ignored", "null"},
+		{"reference", "exercises/eBench01", "exercises/eBench01 (reference)"},
+		{"reference", "exercises", ""},
+		{"geopoint", "-6.2, 106.8", "-6.2, 106.8 (geopoint)"},
+		{"geopoint", "91, 0", ""},
+		{"bytes", "aGk=", "aGk= (bytes)"},
+		{"map", `{"sets": 3}`, `{"sets":3} (map)`},
+		{"map", "[1]", ""},
+		{"array", `["a", 1]`, `["a",1] (array)`},
+		{"array", "", "[] (array)"},
+	} {
+		value, err := parseFieldInput(tc.kind, tc.text, storeSvc)
+		switch {
+		case tc.want == "" && err == nil:
+			t.Errorf("%s %q: expected an error, got %v", tc.kind, tc.text, value)
+		case tc.want != "" && err != nil:
+			t.Errorf("%s %q: unexpected error %v", tc.kind, tc.text, err)
+		case tc.want != "" && fieldPreview(value) != tc.want:
+			t.Errorf("%s %q: got %s, want %s", tc.kind, tc.text, fieldPreview(value), tc.want)
+		}
+	}
+}
+
+// TestDocumentChanges tests the updates and diff of an edited document
+func TestDocumentChanges(t *testing.T) {
+	before := map[string]interface{}{
+		"name":  "Push Day",
+		"reps":  int64(5),
+		"notes": "old",
+		"meta":  map[string]interface{}{"v": int64(1)},
+	}
+	after := map[string]interface{}{
+		"name":  "Push Day",
+		"reps":  "5",
+		"meta":  map[string]interface{}{"v": int64(2)},
+		"level": int64(3),
+	}
+
+	updates, diff := documentChanges(before, after)
+	if len(updates) != 4 || updates["notes"] != firestore.Delete || updates["level"] != int64(3) {
+		t.Errorf("Unexpected updates %v", updates)
+	}
+	want := []string{
+		"+ level: 3 (number)",
+		`~ meta: {"v":1} (map) → {"v":2} (map)`,
+		`- notes: "old" (string)`,
+		`~ reps: 5 (number) → "5" (string)`,
+	}
+	if strings.Join(diff, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Diff\n%s\nwant\n%s", strings.Join(diff, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// TestSaveDocumentEditDottedField tests that a field named with a dot is
+// saved as a top-level field
+func TestSaveDocumentEditDottedField(t *testing.T) {
+	_, storeSvc := CreateDemoBackend(t)
+	ctx := context.Background()
+	doc, _ := storeSvc.GetDocument(ctx, "routines", "rMayaPush")
+
+	draft := copyDraft(doc.Data)
+	draft["notes.v1"] = "warm up"
+	updates, _ := documentChanges(doc.Data, draft)
+	msg := saveDocumentEdit(storeSvc, doc, updates)().(documentEditSavedMsg)
+	if msg.err != nil {
+		t.Fatalf("Expected the document to save, got %v", msg.err)
+	}
+
+	saved, _ := storeSvc.GetDocument(ctx, "routines", "rMayaPush")
+	if _, nested := saved.Data["notes"]; nested || saved.Data["notes.v1"] != "warm up" {
+		t.Errorf("Expected a top-level notes.v1 field, got %v", saved.Data)
+	}
+}
+
+// TestEditorFileRoundTrip tests that unchanged references and geopoints keep
+// their types through the JSON editor
+func TestEditorFileRoundTrip(t *testing.T) {
+	original := map[string]interface{}{
+		"ref":   &firestore.DocumentRef{ID: "e1", Path: "projects/p/databases/(default)/documents/exercises/e1"},
+		"where": &latlng.LatLng{Latitude: -6.2, Longitude: 106.8},
+		"when":  time.Date(2024, 4, 11, 7, 5, 0, 0, time.UTC),
+		"name":  "Push",
+	}
+	file, err := writeEditorFile(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file)
+
+	content, _ := os.ReadFile(file)
+	edited := strings.Replace(string(content), `"Push"`, `"Pull"`, 1)
+	if err := os.WriteFile(file, []byte(edited), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	draft, err := readEditorFile(file, original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updates, diff := documentChanges(original, draft)
+	if len(updates) != 1 || updates["name"] != "Pull" {
+		t.Errorf("Expected only the name to change, got %v", diff)
+	}
+}
+
+// openDocumentEditor opens the tree of a document and its editor
+func openDocumentEditor(t *testing.T, m Model, path string) Model {
+	t.Helper()
+	m = settle(t, m, pushScreenMsg{screen: newDocumentTreeScreen(m.storeSvc, path)})
+	m = settle(t, m, keyRunes("e"))
+	if _, ok := m.router.top().(documentEditScreen); !ok {
+		t.Fatalf("Expected the document editor, got %T", m.router.top())
+	}
+	return m
+}
+
+// typeText sends text to the top screen one key at a time
+func typeText(t *testing.T, m Model, text string) Model {
+	t.Helper()
+	for _, r := range text {
+		m = settle(t, m, keyRunes(string(r)))
+	}
+	return m
+}
+
+// TestDocumentEditScreen tests editing, adding and deleting fields and saving them
+func TestDocumentEditScreen(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, BrowseTab)
+	m = openDocumentEditor(t, m, "routines/rMayaPush")
+
+	// Fields are listed by name: createdAt, exercises, name, uid, updatedAt
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyCtrlU})
+	m = typeText(t, m, "Push Day A")
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	// A new field typed as a number
+	m = settle(t, m, keyRunes("a"))
+	m = typeText(t, m, "level")
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyTab})
+	m = typeText(t, m, "x")
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if s := m.router.top().(documentEditScreen); s.stage != editValue || !strings.Contains(s.err, "enter a number") {
+		t.Fatalf("Expected an invalid number to be refused, got %q", s.err)
+	}
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyBackspace})
+	m = typeText(t, m, "3")
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	// The cursor is on level; delete uid, two fields below it
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	m = settle(t, m, keyRunes("d"))
+
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyCtrlS})
+	view := m.View()
+	for _, want := range []string{
+		"3 changes to save",
+		"+ level: 3 (number)",
+		`~ name: "Push Day" (string) → "Push Day A" (string)`,
+		"- uid: ",
+	} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Review does not contain %q:\n%s", want, view)
+		}
+	}
+
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	tree, ok := m.router.top().(documentTreeScreen)
+	if !ok || tree.loading {
+		t.Fatalf("Expected to return to the reloaded document, got %T", m.router.top())
+	}
+	if _, ok := tree.doc.Data["uid"]; ok || tree.doc.Data["name"] != "Push Day A" || tree.doc.Data["level"] != int64(3) {
+		t.Errorf("Expected the saved changes, got %v", tree.doc.Data)
+	}
+}
+
+// TestDocumentEditConflict tests that a document written by someone else
+// since it was read isn't overwritten, and that the changes can be re-applied
+// to the latest version
+func TestDocumentEditConflict(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, BrowseTab)
+	m = openDocumentEditor(t, m, "routines/rMayaPush")
+
+	if err := storeSvc.Update(context.Background(), "routines", "rMayaPush", map[string]interface{}{"name": "Theirs"}); err != nil {
+		t.Fatal(err)
+	}
+
+	m = settle(t, m, keyRunes("a"))
+	m = typeText(t, m, "note")
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	m = typeText(t, m, "mine")
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyCtrlS})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	s, ok := m.router.top().(documentEditScreen)
+	if !ok || !s.conflict || !strings.Contains(s.err, "routines/rMayaPush was updated at") {
+		t.Fatalf("Expected the save to be refused, got %T", m.router.top())
+	}
+	if !strings.Contains(m.View(), "Press r to reload") {
+		t.Error("Expected the reload option to be offered")
+	}
+	var data map[string]interface{}
+	_ = storeSvc.Get(context.Background(), "routines", "rMayaPush", &data)
+	if data["name"] != "Theirs" || data["note"] != nil {
+		t.Errorf("Expected the other change to be kept, got %v", data)
+	}
+
+	// r reloads the document and shows the same change against it
+	m = settle(t, m, keyRunes("r"))
+	s = m.router.top().(documentEditScreen)
+	if s.conflict || s.stage != editReview || s.doc.Data["name"] != "Theirs" || strings.Join(s.diff, "") != `+ note: "mine" (string)` {
+		t.Fatalf("Expected the change re-applied to the latest version, got %v (error %q)", s.diff, s.err)
+	}
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if _, ok := m.router.top().(documentTreeScreen); !ok {
+		t.Fatalf("Expected the re-applied change to save, got %T", m.router.top())
+	}
+	_ = storeSvc.Get(context.Background(), "routines", "rMayaPush", &data)
+	if data["name"] != "Theirs" || data["note"] != "mine" {
+		t.Errorf("Expected both changes, got %v", data)
+	}
+}
+
+// TestDocumentEditExternalEditor tests reading back the JSON saved in the editor
+func TestDocumentEditExternalEditor(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, BrowseTab)
+	m = openDocumentEditor(t, m, "profiles/pMaya01")
+
+	file := filepath.Join(t.TempDir(), "doc.json")
+	_ = os.WriteFile(file, []byte(`{"name": "Maya S", "uid": "3xKq9PzR1vT7bN2mYc5LwA8dFe4H"}`), 0o600)
+	m = settle(t, m, editorClosedMsg{path: "profiles/pMaya01", file: file})
+
+	s := m.router.top().(documentEditScreen)
+	updates, diff := documentChanges(s.doc.Data, s.draft)
+	if len(updates) != 3 || updates["name"] != "Maya S" || updates["createdAt"] != firestore.Delete {
+		t.Errorf("Unexpected changes from the editor: %v", diff)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Error("Expected the edited file to be removed")
+	}
+
+	// Invalid JSON is reported and kept
+	_ = os.WriteFile(file, []byte(`{"name": `), 0o600)
+	m = settle(t, m, editorClosedMsg{path: "profiles/pMaya01", file: file})
+	if s := m.router.top().(documentEditScreen); !strings.Contains(s.err, "kept in "+file) {
+		t.Errorf("Expected the invalid file to be kept, got %q", s.err)
+	}
+}
+
+// TestEditorCommand tests choosing the editor from the environment
+func TestEditorCommand(t *testing.T) {
+	for _, tc := range []struct {
+		visual, editor string
+		want           string
+	}{
+		{"code --wait", "nano", "code --wait doc.json"},
+		{"", "nano", "nano doc.json"},
+		{"  ", "emacs -nw", "emacs -nw doc.json"},
+		{"", " ", "vi doc.json"},
+		{"", "", "vi doc.json"},
+	} {
+		t.Setenv("VISUAL", tc.visual)
+		t.Setenv("EDITOR", tc.editor)
+		cmd := editorCommand("doc.json")
+		if got := strings.Join(cmd.Args, " "); got != tc.want {
+			t.Errorf("VISUAL=%q EDITOR=%q: got %q, want %q", tc.visual, tc.editor, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"arrogance/firebase"
+)
+
+// exportFormats are the formats data can be exported to
+var exportFormats = []string{"csv", "json", "ndjson"}
+
+// exportFormat returns the format of an export file from its extension
+// unless one is given. Stdout, "-", defaults to JSON.
+func exportFormat(path, format string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if path == "" || path == "-" {
+			format = "json"
+		}
+	}
+	for _, f := range exportFormats {
+		if f == format {
+			return format, nil
+		}
+	}
+	if format == "" {
+		return "", fmt.Errorf("can't tell the format of %s, use %s", path, strings.Join(exportFormats, ", "))
+	}
+	return "", fmt.Errorf("unknown export format %q, use %s", format, strings.Join(exportFormats, ", "))
+}
+
+// parseColumns splits a comma-separated list of columns
+func parseColumns(value string) []string {
+	var columns []string
+	for _, c := range strings.Split(value, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+// documentColumns returns the dotted paths of every field of the records,
+// after their id and path, for formats that need columns up front
+func documentColumns(records []map[string]interface{}) []string {
+	seen := map[string]bool{"id": true, "path": true}
+	var paths []string
+	for _, record := range records {
+		for _, f := range flattenFields(record) {
+			if !seen[f.path] {
+				seen[f.path] = true
+				paths = append(paths, f.path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return append([]string{"id", "path"}, paths...)
+}
+
+// recordValue returns a field of an exported record by its dotted path
+func recordValue(record map[string]interface{}, path string) interface{} {
+	if v, ok := record[path]; ok {
+		return v
+	}
+	var value interface{} = record
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+// exportCell formats a value for a CSV cell. Strings are written as they are,
+// lists and maps as JSON.
+func exportCell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// recordWriter writes exported records one at a time
+type recordWriter interface {
+	write(record map[string]interface{}) error
+	// close finishes the output, without closing the underlying writer
+	close() error
+}
+
+// newRecordWriter creates a writer for format. CSV needs the columns; JSON
+// and NDJSON write every field unless columns are given.
+func newRecordWriter(w io.Writer, format string, columns []string) (recordWriter, error) {
+	switch format {
+	case "csv":
+		if len(columns) == 0 {
+			return nil, fmt.Errorf("CSV exports need columns")
+		}
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return nil, err
+		}
+		return &csvRecordWriter{w: cw, columns: columns}, nil
+	case "json":
+		return &jsonRecordWriter{w: w, columns: columns}, nil
+	case "ndjson":
+		return &ndjsonRecordWriter{w: w, columns: columns}, nil
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// csvRecordWriter writes a row per record under a header of the columns
+type csvRecordWriter struct {
+	w       *csv.Writer
+	columns []string
+}
+
+func (c *csvRecordWriter) write(record map[string]interface{}) error {
+	row := make([]string, len(c.columns))
+	for i, column := range c.columns {
+		row[i] = exportCell(recordValue(record, column))
+	}
+	return c.w.Write(row)
+}
+
+func (c *csvRecordWriter) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonRecordWriter writes the records as a JSON array, a record per line
+type jsonRecordWriter struct {
+	w       io.Writer
+	columns []string
+	written bool
+}
+
+func (j *jsonRecordWriter) write(record map[string]interface{}) error {
+	data, err := encodeRecord(record, j.columns)
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if !j.written {
+		sep = "[\n"
+		j.written = true
+	}
+	_, err = io.WriteString(j.w, sep+string(data))
+	return err
+}
+
+func (j *jsonRecordWriter) close() error {
+	end := "\n]\n"
+	if !j.written {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
+
+// ndjsonRecordWriter writes a JSON object per line
+type ndjsonRecordWriter struct {
+	w       io.Writer
+	columns []string
+}
+
+func (n *ndjsonRecordWriter) write(record map[string]interface{}) error {
+	data, err := encodeRecord(record, n.columns)
+	if err != nil {
+		return err
+	}
+	_, err = n.w.Write(append(data, '\n'))
+	return err
+}
+
+func (n *ndjsonRecordWriter) close() error {
+	return nil
+}
+
+// encodeRecord encodes a record as a JSON object. With columns, only those
+// are written, in their order and keyed by their path.
+func encodeRecord(record map[string]interface{}, columns []string) ([]byte, error) {
+	if len(columns) == 0 {
+		return json.Marshal(record)
+	}
+
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, column := range columns {
+		key, err := json.Marshal(column)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(recordValue(record, column))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", column, err)
+		}
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.Write(key)
+		sb.WriteByte(':')
+		sb.Write(value)
+	}
+	sb.WriteByte('}')
+	return []byte(sb.String()), nil
+}
+
+// exportRecords writes records in format, returning how many were written
+func exportRecords(w io.Writer, format string, columns []string, records []map[string]interface{}) (int, error) {
+	if format == "csv" && len(columns) == 0 {
+		columns = documentColumns(records)
+	}
+	rw, err := newRecordWriter(w, format, columns)
+	if err != nil {
+		return 0, err
+	}
+	for i, record := range records {
+		if err := rw.write(record); err != nil {
+			return i, err
+		}
+	}
+	return len(records), rw.close()
+}
+
+// documentRecords converts documents to exported records
+func documentRecords(docs []firebase.Document) []map[string]interface{} {
+	records := make([]map[string]interface{}, len(docs))
+	for i, d := range docs {
+		records[i] = firebase.ExportDocument(d)
+	}
+	return records
+}
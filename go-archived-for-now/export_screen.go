@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Fields of the export dialog
+const (
+	fieldExportFile    = "File"
+	fieldExportColumns = "Columns"
+)
+
+// ExportDoneMsg is sent when rows have been written to a file
+type exportDoneMsg struct {
+	path string
+	n    int
+	err  error
+}
+
+// writeExportFile writes records to a file in the format of its extension
+func writeExportFile(path string, columns []string, records []map[string]interface{}) tea.Cmd {
+	return func() tea.Msg {
+		format, err := exportFormat(path, "")
+		if err != nil {
+			return exportDoneMsg{path: path, err: err}
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return exportDoneMsg{path: path, err: err}
+		}
+		n, err := exportRecords(f, format, columns, records)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return exportDoneMsg{path: path, n: n, err: err}
+	}
+}
+
+// exportScreen writes the rows of a table to a CSV, JSON or NDJSON file
+type exportScreen struct {
+	frame
+	noun    string
+	records []map[string]interface{}
+
+	form      form
+	exporting bool
+	done      string
+	err       string
+}
+
+// newExportScreen creates the export dialog for records, the rows of a table
+// named by noun. columns are the default columns, or nil for every field.
+func newExportScreen(noun string, records []map[string]interface{}, columns []string) exportScreen {
+	file := fmt.Sprintf("%s-%s.csv", noun, time.Now().Format("2006-01-02"))
+	return exportScreen{
+		noun:    noun,
+		records: records,
+		form: newForm(
+			textField(fieldExportFile, file, "file.csv, file.json or file.ndjson", func(value string) error {
+				if value == "" || value == "-" {
+					return errors.New("enter a file name")
+				}
+				_, err := exportFormat(value, "")
+				return err
+			}),
+			textField(fieldExportColumns, strings.Join(columns, ","), "every field", nil),
+		),
+	}
+}
+
+// Init starts the cursor blinking
+func (s exportScreen) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// KeyMap returns the keys of the form
+func (s exportScreen) KeyMap() help.KeyMap {
+	k := s.form.KeyMap()
+	k.Submit.SetHelp(k.Submit.Help().Key, "export")
+	if s.done != "" {
+		k.Next.SetEnabled(false)
+		k.Prev.SetEnabled(false)
+		k.Submit.SetEnabled(false)
+		k.Cancel.SetHelp(k.Cancel.Help().Key, "close")
+	}
+	return k
+}
+
+// Loading reports whether the file is being written
+func (s exportScreen) Loading() bool {
+	return s.exporting
+}
+
+// CapturesInput keeps every key on the dialog, which is closed with its cancel key
+func (s exportScreen) CapturesInput() bool {
+	return true
+}
+
+// Update edits the file and columns and writes the file
+func (s exportScreen) Update(msg tea.Msg) (Screen, tea.Cmd) {
+	s.track(msg)
+
+	switch msg := msg.(type) {
+	case exportDoneMsg:
+		if !s.exporting || msg.path != s.form.value(fieldExportFile) {
+			return s, nil
+		}
+		s.exporting = false
+		if msg.err != nil {
+			s.err = msg.err.Error()
+			return s, nil
+		}
+		s.done = fmt.Sprintf("Exported %d %s to %s", msg.n, s.noun, msg.path)
+		return s, nil
+
+	case tea.KeyMsg:
+		if s.exporting {
+			return s, nil
+		}
+		switch {
+		case key.Matches(msg, s.form.keys.Cancel):
+			return s, popScreen
+		case s.done != "":
+			return s, nil
+		case key.Matches(msg, s.form.keys.Submit):
+			var ok bool
+			s.err = ""
+			if s.form, ok = s.form.validate(); !ok {
+				return s, nil
+			}
+			s.exporting = true
+			return s, writeExportFile(s.form.value(fieldExportFile), parseColumns(s.form.value(fieldExportColumns)), s.records)
+		}
+	}
+
+	var cmd tea.Cmd
+	s.form, cmd = s.form.Update(msg)
+	return s, cmd
+}
+
+// View shows the form in a modal
+func (s exportScreen) View() string {
+	body := fmt.Sprintf("Export the %d %s shown. The extension picks CSV, JSON or NDJSON;\nnested fields are columns such as workout.name.\n\n", len(s.records), s.noun)
+	body += s.form.View()
+
+	switch {
+	case s.exporting:
+		body += "\n\n" + loadingStyle.UnsetMarginLeft().Render(s.spinner()+" Exporting...")
+	case s.err != "":
+		body += "\n\n" + errorStyle.UnsetMarginLeft().Width(60).Render(s.err)
+	case s.done != "":
+		body += "\n\n" + successStyle.UnsetMarginLeft().Render(s.done)
+	}
+
+	return renderModal(s.width-8, s.height-12, "Export "+s.noun, body, newHelp().View(s.KeyMap()))
+}
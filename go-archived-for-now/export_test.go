@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"arrogance/firebase"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// demoCLI returns a cli running against the demo backend, with its output buffers
+func demoCLI(t *testing.T) (cli, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	authSvc, storeSvc := CreateDemoBackend(t)
+	var stdout, stderr bytes.Buffer
+	c := cli{stdout: &stdout, stderr: &stderr, connect: func() (firebase.UserStore, firebase.DocumentStore, func(), error) {
+		return authSvc, storeSvc, func() {}, nil
+	}}
+	return c, &stdout, &stderr
+}
+
+// TestExportCommand tests exporting users and documents from the command line
+func TestExportCommand(t *testing.T) {
+	c, stdout, stderr := demoCLI(t)
+
+	if code := c.run([]string{"export", "users", "--format", "csv", "--columns", "uid,email,customClaims.role", "--filter", "disabled:true"}); code != exitOK {
+		t.Fatalf("Export failed with %d: %s", code, stderr)
+	}
+	want := "uid,email,customClaims.role\nBq8Zr4Lm2Nx6Ty1Vw9Ks3Jh7Gf5D,oscar.wijaya@example.com,coach\n"
+	if stdout.String() != want {
+		t.Errorf("Exported\n%s\nwant\n%s", stdout, want)
+	}
+
+	// Password hashes are only exported when asked for
+	for _, tc := range []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"export", "users", "--format", "csv"}, false},
+		{[]string{"export", "users", "--format", "csv", "--with-password-hashes"}, true},
+	} {
+		stdout.Reset()
+		if code := c.run(tc.args); code != exitOK {
+			t.Fatalf("%v failed with %d: %s", tc.args, code, stderr)
+		}
+		header, _, _ := strings.Cut(stdout.String(), "\n")
+		if got := strings.HasSuffix(header, ",passwordHash,passwordSalt"); got != tc.want {
+			t.Errorf("%v: unexpected header %q", tc.args, header)
+		}
+	}
+	if code := c.run([]string{"export", "users", "--columns", "uid,passwordHash"}); code != exitUsage {
+		t.Errorf("Expected a usage error for a password column without --with-password-hashes, got %d", code)
+	}
+
+	// Timestamps become RFC 3339 strings
+	stdout.Reset()
+	if code := c.run([]string{"export", "docs", "--format", "ndjson", "--columns", "id,name,createdAt", `routines where name == "Push Day"`}); code != exitOK {
+		t.Fatalf("Export failed with %d: %s", code, stderr)
+	}
+	if want := `{"id":"rMayaPush","name":"Push Day","createdAt":"2024-04-11T07:05:00Z"}` + "\n"; stdout.String() != want {
+		t.Errorf("Exported %q, want %q", stdout, want)
+	}
+
+	stdout.Reset()
+	if code := c.run([]string{"export", "docs", "routines"}); code != exitOK {
+		t.Fatalf("Export failed with %d: %s", code, stderr)
+	}
+	var routines []map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &routines); err != nil || len(routines) != 6 {
+		t.Errorf("Expected a JSON array of 6 routines, got %d (%v)", len(routines), err)
+	}
+
+	if code := c.run([]string{"export", "docs", "routines where"}); code != exitUsage {
+		t.Errorf("Expected a usage error for an invalid query, got %d", code)
+	}
+	if code := c.run([]string{"export", "users", "--file", "users.xlsx"}); code != exitUsage {
+		t.Errorf("Expected a usage error for an unknown format, got %d", code)
+	}
+
+	// A CSV export without columns has the fields of the first document
+	stdout.Reset()
+	if code := c.run([]string{"export", "docs", "--format", "csv", "routines order by name limit 2"}); code != exitOK {
+		t.Fatalf("Export failed with %d: %s", code, stderr)
+	}
+	if lines := strings.Split(stdout.String(), "\n"); len(lines) != 4 || lines[0] != "id,path,createdAt,exercises,name,uid,updatedAt" {
+		t.Errorf("Unexpected CSV export\n%s", stdout)
+	}
+
+	// The format is checked before connecting, and a file that can't be
+	// created is a failure rather than a usage error
+	connected := false
+	connect := c.connect
+	c.connect = func() (firebase.UserStore, firebase.DocumentStore, func(), error) {
+		connected = true
+		return connect()
+	}
+	if code := c.run([]string{"export", "docs", "--format", "xml", "routines"}); code != exitUsage || connected {
+		t.Errorf("Expected a usage error before connecting, got %d (connected %v)", code, connected)
+	}
+	for _, args := range [][]string{{"export", "users", "extra"}, {"export", "docs"}, {"import", "users"}, {"import", "users", "a.csv", "b.csv"}} {
+		if code := c.run(args); code != exitUsage {
+			t.Errorf("%v: expected a usage error, got %d", args, code)
+		}
+	}
+	if code := c.run([]string{"export", "docs", "-h"}); code != exitOK || !strings.Contains(stderr.String(), "Usage: export docs [flags] QUERY") {
+		t.Errorf("Expected the usage of export docs, got %d", code)
+	}
+	unwritable := filepath.Join(t.TempDir(), "missing", "routines.json")
+	for _, args := range [][]string{{"export", "users", "--file", unwritable}, {"export", "docs", "--file", unwritable, "routines"}} {
+		if code := c.run(args); code != exitFailure {
+			t.Errorf("%v: expected a failure for an unwritable file, got %d", args, code)
+		}
+	}
+}
+
+// TestExportImportRoundTrip tests that a CSV export of users imports without changes
+func TestExportImportRoundTrip(t *testing.T) {
+	c, stdout, stderr := demoCLI(t)
+	path := filepath.Join(t.TempDir(), "users.csv")
+
+	if code := c.run([]string{"export", "users", "--file", path}); code != exitOK {
+		t.Fatalf("Export failed with %d: %s", code, stderr)
+	}
+	if code := c.run([]string{"import", "users", "--dry-run", path}); code != exitOK {
+		t.Fatalf("Import failed with %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout.String(), "0 to create, 0 to update, 6 unchanged, 0 invalid") {
+		t.Errorf("Expected every user to be unchanged:\n%s", stdout)
+	}
+}
+
+// TestExportScreen tests exporting the rows of a collection tab
+func TestExportScreen(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	path := filepath.Join(t.TempDir(), "routines.csv")
+
+	m := newTestApp(t, authSvc, storeSvc, RoutinesTab)
+	m = settle(t, m, keyRunes("E"))
+	if _, ok := m.router.top().(exportScreen); !ok {
+		t.Fatalf("Expected the export dialog, got %T", m.router.top())
+	}
+
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyCtrlU})
+	m = settle(t, m, keyRunes(path))
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyTab})
+	m = settle(t, m, keyRunes("name,uid"))
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if view := m.View(); !strings.Contains(view, "Exported 6 routines") {
+		t.Fatalf("Expected the export to finish:\n%s", view)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 7 || lines[0] != "name,uid" || !strings.Contains(string(data), "Push Day,3xKq9PzR1vT7bN2mYc5LwA8dFe4H") {
+		t.Errorf("Unexpected export:\n%s", data)
+	}
+
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	collectionTab(t, m)
+}
@@ -3,17 +3,80 @@ package firebase
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
-	"os"
 
 	"firebase.google.com/go/v4/auth"
+	"google.golang.org/api/iterator"
 )
 
+// ErrEmailExists is returned by CreateUser and UpdateUser when another user has the email
+var ErrEmailExists = errors.New("email already in use")
+
+// ErrPhoneNumberExists is returned by CreateUser and UpdateUser when another user has the phone number
+var ErrPhoneNumberExists = errors.New("phone number already in use")
+
+// ErrUserNotFound is returned by GetUser and GetUserByEmail when there's no such user
+var ErrUserNotFound = errors.New("user not found")
+
+// MaxGetUsersBatchSize is the maximum number of users Firebase looks up in one GetUsers call
+const MaxGetUsersBatchSize = 100
+
+// ErrContinueURLRequired is returned by EmailSignInLink when no continue URL is set
+var ErrContinueURLRequired = errors.New("email sign-in links need a continue URL")
+
 // AuthService provides authentication-related functionality
 type AuthService struct {
 	client *auth.Client
 }
 
+// UserPage is a single page of users returned by ListUsers
+type UserPage struct {
+	Users         []*auth.ExportedUserRecord
+	NextPageToken string
+}
+
+// UserToCreate holds the properties of a user to create.
+// Empty fields are left unset.
+type UserToCreate struct {
+	UID           string
+	Email         string
+	Password      string
+	DisplayName   string
+	PhoneNumber   string
+	PhotoURL      string
+	EmailVerified bool
+	Disabled      bool
+}
+
+// UserToUpdate holds the properties of a user to update.
+// Nil fields are left unchanged.
+type UserToUpdate struct {
+	Email         *string
+	Password      *string
+	DisplayName   *string
+	PhoneNumber   *string
+	PhotoURL      *string
+	EmailVerified *bool
+	Disabled      *bool
+}
+
+// ActionCodeSettings configures where the links sent in emails lead once used.
+// Without a continue URL, links end on the default Firebase page.
+type ActionCodeSettings struct {
+	ContinueURL string
+}
+
+// String returns a pointer to s, for use in UserToUpdate
+func String(s string) *string {
+	return &s
+}
+
+// Bool returns a pointer to b, for use in UserToUpdate
+func Bool(b bool) *bool {
+	return &b
+}
+
 // NewAuthService creates a new AuthService
 func NewAuthService(client *auth.Client) *AuthService {
 	return &AuthService{
@@ -34,48 +97,89 @@ func (s *AuthService) GetUser(ctx context.Context, uid string) (*auth.UserRecord
 	if s.client == nil {
 		return nil, errors.New("auth client not initialized")
 	}
-	return s.client.GetUser(ctx, uid)
+	user, err := s.client.GetUser(ctx, uid)
+	return user, userReadError(err)
 }
 
-// ListUsers lists users with pagination
-func (s *AuthService) ListUsers(ctx context.Context, maxResults uint32, pageToken string) (*auth.UserIterator, error) {
+// GetUserByEmail gets a user by their email address
+func (s *AuthService) GetUserByEmail(ctx context.Context, email string) (*auth.UserRecord, error) {
 	if s.client == nil {
-		log.Println("Auth client is nil")
 		return nil, errors.New("auth client not initialized")
 	}
+	user, err := s.client.GetUserByEmail(ctx, email)
+	return user, userReadError(err)
+}
 
-	log.Println("Auth client is available, creating user iterator")
+// GetUsers gets the users matching any of up to MaxGetUsersBatchSize
+// identifiers, listing the identifiers that matched nobody as NotFound
+func (s *AuthService) GetUsers(ctx context.Context, identifiers []auth.UserIdentifier) (*auth.GetUsersResult, error) {
+	if s.client == nil {
+		return nil, errors.New("auth client not initialized")
+	}
+	return s.client.GetUsers(ctx, identifiers)
+}
 
-	// Print the GOOGLE_APPLICATION_CREDENTIALS environment variable
-	if creds := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); creds != "" {
-		log.Printf("Using GOOGLE_APPLICATION_CREDENTIALS: %s", creds)
+// userReadError wraps Admin SDK errors about missing users so they can be
+// matched with errors.Is
+func userReadError(err error) error {
+	if auth.IsUserNotFound(err) {
+		return fmt.Errorf("%w: %v", ErrUserNotFound, err)
 	}
+	return err
+}
 
-	// Using the Users method to get an iterator
-	iter := s.client.Users(ctx, pageToken)
-	log.Println("User iterator created successfully")
+// ListUsers lists a single page of users starting at pageToken
+func (s *AuthService) ListUsers(ctx context.Context, maxResults int, pageToken string) (*UserPage, error) {
+	if s.client == nil {
+		log.Println("Auth client is nil")
+		return nil, errors.New("auth client not initialized")
+	}
+
+	// Using the Users iterator through a pager so only one page is fetched
+	pager := iterator.NewPager(s.client.Users(ctx, ""), maxResults, pageToken)
+
+	page := &UserPage{}
+	nextPageToken, err := pager.NextPage(&page.Users)
+	if err != nil {
+		return nil, err
+	}
+	page.NextPageToken = nextPageToken
 
-	return iter, nil
+	return page, nil
 }
 
 // CreateUser creates a new user
-func (s *AuthService) CreateUser(ctx context.Context, params *auth.UserToCreate) (string, error) {
+func (s *AuthService) CreateUser(ctx context.Context, params *UserToCreate) (string, error) {
 	if s.client == nil {
 		return "", errors.New("auth client not initialized")
 	}
-	user, err := s.client.CreateUser(ctx, params)
+	user, err := s.client.CreateUser(ctx, params.toAuth())
 	if err != nil {
-		return "", err
+		return "", userWriteError(err)
 	}
 	return user.UID, nil
 }
 
 // UpdateUser updates a user
-func (s *AuthService) UpdateUser(ctx context.Context, uid string, params *auth.UserToUpdate) error {
+func (s *AuthService) UpdateUser(ctx context.Context, uid string, params *UserToUpdate) error {
 	if s.client == nil {
 		return errors.New("auth client not initialized")
 	}
-	_, err := s.client.UpdateUser(ctx, uid, params)
+	_, err := s.client.UpdateUser(ctx, uid, params.toAuth())
+	return userWriteError(err)
+}
+
+// userWriteError wraps Admin SDK errors about missing users and duplicate
+// emails and phone numbers so they can be matched with errors.Is
+func userWriteError(err error) error {
+	switch {
+	case auth.IsUserNotFound(err):
+		return fmt.Errorf("%w: %v", ErrUserNotFound, err)
+	case auth.IsEmailAlreadyExists(err):
+		return fmt.Errorf("%w: %v", ErrEmailExists, err)
+	case auth.IsPhoneNumberAlreadyExists(err):
+		return fmt.Errorf("%w: %v", ErrPhoneNumberExists, err)
+	}
 	return err
 }
 
@@ -84,5 +188,154 @@ func (s *AuthService) DeleteUser(ctx context.Context, uid string) error {
 	if s.client == nil {
 		return errors.New("auth client not initialized")
 	}
-	return s.client.DeleteUser(ctx, uid)
+	return userWriteError(s.client.DeleteUser(ctx, uid))
+}
+
+// RevokeRefreshTokens signs a user out of every session by revoking their
+// refresh tokens. ID tokens already issued stay valid until they expire.
+func (s *AuthService) RevokeRefreshTokens(ctx context.Context, uid string) error {
+	if s.client == nil {
+		return errors.New("auth client not initialized")
+	}
+	return userWriteError(s.client.RevokeRefreshTokens(ctx, uid))
+}
+
+// SetCustomUserClaims replaces the custom claims of a user. Empty claims remove
+// them all. Users see the new claims once their ID token is refreshed.
+func (s *AuthService) SetCustomUserClaims(ctx context.Context, uid string, claims map[string]interface{}) error {
+	if s.client == nil {
+		return errors.New("auth client not initialized")
+	}
+	if err := ValidateCustomClaims(claims); err != nil {
+		return err
+	}
+	return userWriteError(s.client.SetCustomUserClaims(ctx, uid, claims))
+}
+
+// PasswordResetLink generates a link that lets the user with the email set a new password
+func (s *AuthService) PasswordResetLink(ctx context.Context, email string, settings *ActionCodeSettings) (string, error) {
+	if s.client == nil {
+		return "", errors.New("auth client not initialized")
+	}
+	return s.client.PasswordResetLinkWithSettings(ctx, email, settings.toAuth(false))
+}
+
+// EmailVerificationLink generates a link that verifies the email of the user who has it
+func (s *AuthService) EmailVerificationLink(ctx context.Context, email string, settings *ActionCodeSettings) (string, error) {
+	if s.client == nil {
+		return "", errors.New("auth client not initialized")
+	}
+	return s.client.EmailVerificationLinkWithSettings(ctx, email, settings.toAuth(false))
+}
+
+// EmailSignInLink generates a link that signs in the user with the email.
+// The link is handled by the app at the continue URL, so one must be set.
+func (s *AuthService) EmailSignInLink(ctx context.Context, email string, settings *ActionCodeSettings) (string, error) {
+	if s.client == nil {
+		return "", errors.New("auth client not initialized")
+	}
+	if settings == nil || settings.ContinueURL == "" {
+		return "", ErrContinueURLRequired
+	}
+	return s.client.EmailSignInLink(ctx, email, settings.toAuth(true))
+}
+
+// ImportUsers imports up to MaxImportBatchSize users, replacing existing
+// accounts with the same UID. hash is required when any user has a password
+// hash. Users Firebase rejects are reported in the result.
+func (s *AuthService) ImportUsers(ctx context.Context, users []*ImportUser, hash *HashConfig) (*ImportResult, error) {
+	if s.client == nil {
+		return nil, errors.New("auth client not initialized")
+	}
+	if len(users) > MaxImportBatchSize {
+		return nil, fmt.Errorf("can't import more than %d users at once, got %d", MaxImportBatchSize, len(users))
+	}
+
+	var opts []auth.UserImportOption
+	if hash != nil && hash.Algorithm != "" {
+		algorithm, err := hash.toAuth()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, auth.WithHash(algorithm))
+	} else if hasPasswords(users) {
+		return nil, errors.New("users with password hashes need a hash algorithm")
+	}
+
+	records := make([]*auth.UserToImport, len(users))
+	for i, u := range users {
+		records[i] = u.toAuth()
+	}
+	res, err := s.client.ImportUsers(ctx, records, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{Imported: res.SuccessCount}
+	for _, e := range res.Errors {
+		result.Failures = append(result.Failures, ImportFailure{Index: e.Index, UID: users[e.Index].UID, Reason: e.Reason})
+	}
+	return result, nil
+}
+
+// toAuth converts the settings to the Admin SDK representation, which is nil
+// when there's no continue URL
+func (s *ActionCodeSettings) toAuth(handleCodeInApp bool) *auth.ActionCodeSettings {
+	if s == nil || s.ContinueURL == "" {
+		return nil
+	}
+	return &auth.ActionCodeSettings{URL: s.ContinueURL, HandleCodeInApp: handleCodeInApp}
+}
+
+// toAuth converts the parameters to the Admin SDK representation
+func (p *UserToCreate) toAuth() *auth.UserToCreate {
+	params := &auth.UserToCreate{}
+	if p.UID != "" {
+		params.UID(p.UID)
+	}
+	if p.Email != "" {
+		params.Email(p.Email)
+	}
+	if p.Password != "" {
+		params.Password(p.Password)
+	}
+	if p.DisplayName != "" {
+		params.DisplayName(p.DisplayName)
+	}
+	if p.PhoneNumber != "" {
+		params.PhoneNumber(p.PhoneNumber)
+	}
+	if p.PhotoURL != "" {
+		params.PhotoURL(p.PhotoURL)
+	}
+	params.EmailVerified(p.EmailVerified)
+	params.Disabled(p.Disabled)
+	return params
+}
+
+// toAuth converts the parameters to the Admin SDK representation
+func (p *UserToUpdate) toAuth() *auth.UserToUpdate {
+	params := &auth.UserToUpdate{}
+	if p.Email != nil {
+		params.Email(*p.Email)
+	}
+	if p.Password != nil {
+		params.Password(*p.Password)
+	}
+	if p.DisplayName != nil {
+		params.DisplayName(*p.DisplayName)
+	}
+	if p.PhoneNumber != nil {
+		params.PhoneNumber(*p.PhoneNumber)
+	}
+	if p.PhotoURL != nil {
+		params.PhotoURL(*p.PhotoURL)
+	}
+	if p.EmailVerified != nil {
+		params.EmailVerified(*p.EmailVerified)
+	}
+	if p.Disabled != nil {
+		params.Disabled(*p.Disabled)
+	}
+	return params
 }
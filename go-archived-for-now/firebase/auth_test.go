@@ -0,0 +1,47 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	firebase "firebase.google.com/go/v4"
+	"google.golang.org/api/option"
+)
+
+// TestAuthServiceUserNotFound tests that every write to a missing user
+// returns ErrUserNotFound, against a server answering like the Auth emulator
+func TestAuthServiceUserNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error": {"code": 400, "message": "USER_NOT_FOUND"}}`))
+	}))
+	defer server.Close()
+	t.Setenv(AuthEmulatorHostEnv, strings.TrimPrefix(server.URL, "http://"))
+
+	ctx := context.Background()
+	app, err := firebase.NewApp(ctx, &firebase.Config{ProjectID: "demo-test"}, option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("NewApp() error = %v", err)
+	}
+	client, err := app.Auth(ctx)
+	if err != nil {
+		t.Fatalf("Auth() error = %v", err)
+	}
+	svc := NewAuthService(client)
+
+	for name, call := range map[string]func() error{
+		"UpdateUser":          func() error { return svc.UpdateUser(ctx, "missing", &UserToUpdate{DisplayName: String("x")}) },
+		"DeleteUser":          func() error { return svc.DeleteUser(ctx, "missing") },
+		"RevokeRefreshTokens": func() error { return svc.RevokeRefreshTokens(ctx, "missing") },
+		"SetCustomUserClaims": func() error { return svc.SetCustomUserClaims(ctx, "missing", map[string]interface{}{"role": "coach"}) },
+	} {
+		if err := call(); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("%s() error = %v, want ErrUserNotFound", name, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+)
+
+// WriteKind is the operation of a Write
+type WriteKind int
+
+const (
+	SetWrite    WriteKind = iota // create or overwrite the document with Data
+	UpdateWrite                  // update the fields of an existing document with Updates
+	DeleteWrite                  // delete the document
+)
+
+// Write is one operation of a batched write
+type Write struct {
+	Kind    WriteKind
+	Path    string                 // document path, such as "profiles/abc/records/xyz"
+	Data    interface{}            // the document of a SetWrite
+	Updates map[string]interface{} // the dotted field paths and values of an UpdateWrite, as in DocumentStore.Update
+}
+
+// Batch collects writes to any number of documents and commits them in
+// batched writes of at most MaxBatchSize operations. Each batched write is
+// atomic; a batch larger than that is committed in order, and a failed
+// commit leaves the earlier ones applied. Use RunTransaction when all of the
+// writes must apply together.
+type Batch struct {
+	docs   DocumentStore
+	writes []Write
+}
+
+// NewBatch creates an empty batch committed to docs
+func NewBatch(docs DocumentStore) *Batch {
+	return &Batch{docs: docs}
+}
+
+// Set adds the creation or overwrite of a document
+func (b *Batch) Set(collectionPath, documentID string, data interface{}) *Batch {
+	b.writes = append(b.writes, Write{Kind: SetWrite, Path: collectionPath + "/" + documentID, Data: data})
+	return b
+}
+
+// Update adds an update of specific fields of an existing document
+func (b *Batch) Update(collectionPath, documentID string, updates map[string]interface{}) *Batch {
+	b.writes = append(b.writes, Write{Kind: UpdateWrite, Path: collectionPath + "/" + documentID, Updates: updates})
+	return b
+}
+
+// Delete adds the deletion of a document
+func (b *Batch) Delete(collectionPath, documentID string) *Batch {
+	b.writes = append(b.writes, Write{Kind: DeleteWrite, Path: collectionPath + "/" + documentID})
+	return b
+}
+
+// Len returns the number of writes in the batch
+func (b *Batch) Len() int {
+	return len(b.writes)
+}
+
+// Commit commits the writes in order, MaxBatchSize at a time, calling
+// progress with the number committed after each batched write. progress may be nil.
+func (b *Batch) Commit(ctx context.Context, progress func(committed, total int)) error {
+	if progress == nil {
+		progress = func(int, int) {}
+	}
+
+	for start := 0; start < len(b.writes); start += MaxBatchSize {
+		end := min(start+MaxBatchSize, len(b.writes))
+		if err := b.docs.CommitBatch(ctx, b.writes[start:end]); err != nil {
+			return fmt.Errorf("failed to commit writes %d-%d of %d: %w", start+1, end, len(b.writes), err)
+		}
+		progress(end, len(b.writes))
+	}
+	return nil
+}
+
+// batchDelete deletes documents by path with a Batch
+func batchDelete(ctx context.Context, docs DocumentStore, documentPaths []string) error {
+	b := NewBatch(docs)
+	for _, path := range documentPaths {
+		collection, id, err := SplitDocumentPath(path)
+		if err != nil {
+			return err
+		}
+		b.Delete(collection, id)
+	}
+	return b.Commit(ctx, nil)
+}
+
+// checkBatch returns an error for writes that can't be committed as one
+// batched write
+func checkBatch(writes []Write) error {
+	if len(writes) > MaxBatchSize {
+		return fmt.Errorf("can't commit more than %d writes in one batch, got %d", MaxBatchSize, len(writes))
+	}
+	for _, w := range writes {
+		if _, _, err := SplitDocumentPath(w.Path); err != nil {
+			return err
+		}
+		if w.Kind < SetWrite || w.Kind > DeleteWrite {
+			return fmt.Errorf("unknown write kind %d for %s", w.Kind, w.Path)
+		}
+	}
+	return nil
+}
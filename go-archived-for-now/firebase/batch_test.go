@@ -0,0 +1,89 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBatchCommit(t *testing.T) {
+	docStore := NewMemoryFirestore()
+	ctx := context.Background()
+
+	batch := NewBatch(docStore)
+	for i := 0; i < MaxBatchSize*2+1; i++ {
+		batch.Set("histories", fmt.Sprintf("h%04d", i), map[string]interface{}{"n": i})
+	}
+	var commits []int
+	if err := batch.Commit(ctx, func(committed, total int) {
+		if total != batch.Len() {
+			t.Errorf("Expected a total of %d, got %d", batch.Len(), total)
+		}
+		commits = append(commits, committed)
+	}); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if fmt.Sprint(commits) != "[500 1000 1001]" {
+		t.Errorf("Expected 3 commits, got %v", commits)
+	}
+	if docs, _ := docStore.List(ctx, "histories"); len(docs) != batch.Len() {
+		t.Errorf("Expected %d histories, got %d", batch.Len(), len(docs))
+	}
+
+	// A failed commit keeps the earlier ones and none of its own writes
+	batch = NewBatch(docStore)
+	for i := 0; i < MaxBatchSize; i++ {
+		batch.Delete("histories", fmt.Sprintf("h%04d", i))
+	}
+	batch.Delete("histories", "h0500").Update("histories", "missing", map[string]interface{}{"n": 0})
+	err := batch.Commit(ctx, nil)
+	if !errors.Is(err, ErrDocumentNotFound) || !strings.Contains(err.Error(), "writes 501-502 of 502") {
+		t.Errorf("Expected the second commit to fail, got %v", err)
+	}
+	if docs, _ := docStore.List(ctx, "histories"); len(docs) != MaxBatchSize+1 {
+		t.Errorf("Expected only the first commit to delete histories, %d left", len(docs))
+	}
+}
+
+func TestMemoryFirestoreCommitBatch(t *testing.T) {
+	_, docStore := loadTestFixture(t)
+	ctx := context.Background()
+
+	// Later writes see earlier ones to the same document
+	err := docStore.CommitBatch(ctx, []Write{
+		{Kind: SetWrite, Path: "routines/r9", Data: map[string]interface{}{"name": "New", "sets": 3}},
+		{Kind: UpdateWrite, Path: "routines/r9", Updates: map[string]interface{}{"sets": 4}},
+		{Kind: DeleteWrite, Path: "profiles/p1/records/rec1"},
+	})
+	if err != nil {
+		t.Fatalf("CommitBatch() error = %v", err)
+	}
+	var r9 map[string]interface{}
+	if err := docStore.Get(ctx, "routines", "r9", &r9); err != nil || r9["name"] != "New" || r9["sets"] != int64(4) {
+		t.Errorf("Unexpected routine r9 %v, %v", r9, err)
+	}
+	if _, err := docStore.GetDocument(ctx, "profiles/p1/records", "rec1"); err != ErrDocumentNotFound {
+		t.Errorf("Expected the record to be deleted, got %v", err)
+	}
+
+	// A failing write leaves every document unchanged
+	err = docStore.CommitBatch(ctx, []Write{
+		{Kind: DeleteWrite, Path: "routines/r1"},
+		{Kind: UpdateWrite, Path: "routines/missing", Updates: map[string]interface{}{"name": "x"}},
+	})
+	if !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("Expected ErrDocumentNotFound, got %v", err)
+	}
+	if _, err := docStore.GetDocument(ctx, "routines", "r1"); err != nil {
+		t.Errorf("Expected r1 to remain, got %v", err)
+	}
+
+	if err := docStore.CommitBatch(ctx, make([]Write, MaxBatchSize+1)); err == nil {
+		t.Error("Expected an error for more than MaxBatchSize writes")
+	}
+	if err := docStore.CommitBatch(ctx, []Write{{Kind: DeleteWrite, Path: "routines"}}); err == nil {
+		t.Error("Expected an error for a collection path")
+	}
+}
@@ -0,0 +1,106 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MaxBatchSize is the maximum number of writes Firestore accepts in one batch
+const MaxBatchSize = 500
+
+// UserCollections are the root collections holding a user's documents,
+// linked to the Auth account by a uid field
+var UserCollections = []string{"profiles", "exercises", "histories", "routines"}
+
+// UserSubcollections lists the subcollections of each user collection whose
+// documents belong to the same user, such as the records of a profile
+var UserSubcollections = map[string][]string{
+	"profiles": {"records"},
+}
+
+// DeleteProgress reports how far a cascading delete has got
+type DeleteProgress struct {
+	Stage   string
+	Deleted int
+	Total   int
+}
+
+// DeleteSummary describes what a cascading delete removed
+type DeleteSummary struct {
+	Documents map[string]int
+	Total     int
+}
+
+// DeleteUserAndData deletes every document belonging to a user, including
+// subcollections, in batched writes and then deletes the Auth account.
+// The account is only deleted once all documents are gone, so a failed run
+// can safely be retried. progress may be nil.
+func DeleteUserAndData(ctx context.Context, users UserStore, docs DocumentStore, uid string, progress func(DeleteProgress)) (*DeleteSummary, error) {
+	if progress == nil {
+		progress = func(DeleteProgress) {}
+	}
+
+	// Find everything to delete before deleting anything
+	summary := &DeleteSummary{Documents: make(map[string]int)}
+	var paths []string
+	for _, collection := range UserCollections {
+		progress(DeleteProgress{Stage: "Finding " + collection})
+
+		results, err := docs.Query(ctx, From(collection).Where("uid", "==", uid))
+		if err != nil {
+			return nil, fmt.Errorf("failed to find %s: %w", collection, err)
+		}
+
+		for _, doc := range results {
+			// Children go first so a parent is never removed while its children remain
+			for _, sub := range UserSubcollections[collection] {
+				children, err := docs.Query(ctx, From(doc.Path+"/"+sub))
+				if err != nil {
+					return nil, fmt.Errorf("failed to find %s of %s: %w", sub, doc.Path, err)
+				}
+				for _, child := range children {
+					paths = append(paths, child.Path)
+				}
+				summary.Documents[sub] += len(children)
+			}
+			paths = append(paths, doc.Path)
+		}
+		summary.Documents[collection] += len(results)
+	}
+	summary.Total = len(paths)
+
+	// Delete the documents in batches, in the order found
+	batch := NewBatch(docs)
+	for _, path := range paths {
+		collection, id, err := SplitDocumentPath(path)
+		if err != nil {
+			return nil, err
+		}
+		batch.Delete(collection, id)
+	}
+	progress(DeleteProgress{Stage: "Deleting documents", Total: len(paths)})
+	err := batch.Commit(ctx, func(committed, total int) {
+		progress(DeleteProgress{Stage: "Deleting documents", Deleted: committed, Total: total})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete documents: %w", err)
+	}
+
+	// Finally, delete the user from Firebase Auth
+	progress(DeleteProgress{Stage: "Deleting account", Deleted: len(paths), Total: len(paths)})
+	if err := users.DeleteUser(ctx, uid); err != nil {
+		return nil, fmt.Errorf("deleted %d documents but failed to delete the account: %w", len(paths), err)
+	}
+
+	return summary, nil
+}
+
+// SplitDocumentPath splits a document path into its collection path and ID
+func SplitDocumentPath(path string) (collectionPath, documentID string, err error) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) < 2 || len(segments)%2 != 0 {
+		return "", "", fmt.Errorf("%q is not a document path", path)
+	}
+	return strings.Join(segments[:len(segments)-1], "/"), segments[len(segments)-1], nil
+}
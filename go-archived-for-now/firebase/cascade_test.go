@@ -0,0 +1,96 @@
+package firebase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestDeleteUserAndData(t *testing.T) {
+	authStore, docStore, err := LoadDemoFixture()
+	if err != nil {
+		t.Fatalf("LoadDemoFixture() error = %v", err)
+	}
+	ctx := context.Background()
+	const uid = "3xKq9PzR1vT7bN2mYc5LwA8dFe4H"
+
+	var stages []string
+	summary, err := DeleteUserAndData(ctx, authStore, docStore, uid, func(p DeleteProgress) {
+		stages = append(stages, p.Stage)
+	})
+	if err != nil {
+		t.Fatalf("DeleteUserAndData() error = %v", err)
+	}
+
+	want := map[string]int{"profiles": 2, "records": 3, "exercises": 4, "histories": 2, "routines": 2}
+	for collection, count := range want {
+		if summary.Documents[collection] != count {
+			t.Errorf("Deleted %d %s, want %d", summary.Documents[collection], collection, count)
+		}
+	}
+	if summary.Total != 13 {
+		t.Errorf("Deleted %d documents in total, want 13", summary.Total)
+	}
+	if len(stages) == 0 || stages[len(stages)-1] != "Deleting account" {
+		t.Errorf("Unexpected progress stages %v", stages)
+	}
+
+	if _, err := authStore.GetUser(ctx, uid); err == nil {
+		t.Error("Expected the Auth account to be deleted")
+	}
+	for _, collection := range append(UserCollections, "profiles/pMaya01/records") {
+		docs, err := docStore.Query(ctx, From(collection).Where("uid", "==", uid))
+		if err != nil {
+			t.Fatalf("Query() error = %v", err)
+		}
+		if len(docs) != 0 {
+			t.Errorf("%d documents left in %s", len(docs), collection)
+		}
+	}
+	records, _ := docStore.List(ctx, "profiles/pMaya01/records")
+	if len(records) != 0 {
+		t.Errorf("%d profile records left", len(records))
+	}
+
+	// Other users keep their data
+	others, _ := docStore.List(ctx, "routines")
+	if len(others) != 4 {
+		t.Errorf("Expected 4 routines of other users to remain, got %d", len(others))
+	}
+}
+
+func TestDeleteUserAndDataBatches(t *testing.T) {
+	authStore := NewMemoryAuth()
+	docStore := NewMemoryFirestore()
+	ctx := context.Background()
+
+	uid, _ := authStore.CreateUser(ctx, &UserToCreate{UID: "bulk"})
+	for i := 0; i < MaxBatchSize*2+10; i++ {
+		if err := docStore.Set(ctx, "histories", fmt.Sprintf("h%04d", i), map[string]interface{}{"uid": uid}); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+
+	batches := 0
+	_, err := DeleteUserAndData(ctx, authStore, docStore, uid, func(p DeleteProgress) {
+		if p.Stage == "Deleting documents" && p.Deleted < p.Total {
+			batches++
+		}
+	})
+	if err != nil {
+		t.Fatalf("DeleteUserAndData() error = %v", err)
+	}
+	if batches != 3 {
+		t.Errorf("Expected 3 batches, got %d", batches)
+	}
+}
+
+func TestSplitDocumentPath(t *testing.T) {
+	collection, id, err := SplitDocumentPath("profiles/p1/records/r1")
+	if err != nil || collection != "profiles/p1/records" || id != "r1" {
+		t.Errorf("SplitDocumentPath() = %q, %q, %v", collection, id, err)
+	}
+	if _, _, err := SplitDocumentPath("profiles"); err == nil {
+		t.Error("Expected a collection path to be rejected")
+	}
+}
@@ -0,0 +1,108 @@
+package firebase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MaxCustomClaimsSize is the largest JSON encoding of a user's custom claims
+// Firebase Authentication accepts, in bytes
+const MaxCustomClaimsSize = 1000
+
+// reservedClaims are set by Firebase on every ID token and can't be custom claims
+var reservedClaims = map[string]bool{
+	"acr": true, "amr": true, "at_hash": true, "aud": true, "auth_time": true,
+	"azp": true, "cnf": true, "c_hash": true, "exp": true, "firebase": true,
+	"iat": true, "iss": true, "jti": true, "nbf": true, "nonce": true, "sub": true,
+}
+
+// CustomClaimsSize returns the size of the JSON encoding of claims in bytes,
+// which is what the size limit applies to
+func CustomClaimsSize(claims map[string]interface{}) (int, error) {
+	if len(claims) == 0 {
+		return 0, nil
+	}
+	encoded, err := json.Marshal(claims)
+	if err != nil {
+		return 0, err
+	}
+	return len(encoded), nil
+}
+
+// ValidateCustomClaims checks claims against the rules Firebase Authentication
+// applies: no reserved claim names and at most MaxCustomClaimsSize bytes of JSON
+func ValidateCustomClaims(claims map[string]interface{}) error {
+	var reserved []string
+	for name := range claims {
+		if reservedClaims[name] {
+			reserved = append(reserved, name)
+		}
+	}
+	if len(reserved) > 0 {
+		sort.Strings(reserved)
+		return fmt.Errorf("reserved claims can't be set: %s", strings.Join(reserved, ", "))
+	}
+
+	size, err := CustomClaimsSize(claims)
+	if err != nil {
+		return fmt.Errorf("claims can't be encoded as JSON: %w", err)
+	}
+	if size > MaxCustomClaimsSize {
+		return fmt.Errorf("claims are %d bytes, the limit is %d", size, MaxCustomClaimsSize)
+	}
+	return nil
+}
+
+// MergeCustomClaims returns the claims of a user with changes applied on top.
+// A nil value in changes removes that claim.
+func MergeCustomClaims(claims, changes map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(claims)+len(changes))
+	for name, value := range claims {
+		merged[name] = value
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(merged, name)
+			continue
+		}
+		merged[name] = value
+	}
+	return merged
+}
+
+// GrantSummary describes the outcome of granting claims to several users
+type GrantSummary struct {
+	Updated []string
+	Failed  map[string]error
+}
+
+// GrantClaims merges changes into the custom claims of every user in uids.
+// A user whose claims can't be updated doesn't stop the others, and is
+// reported in the summary instead. progress may be nil.
+func GrantClaims(ctx context.Context, users UserStore, uids []string, changes map[string]interface{}, progress func(done, total int)) *GrantSummary {
+	if progress == nil {
+		progress = func(int, int) {}
+	}
+
+	summary := &GrantSummary{Failed: make(map[string]error)}
+	for i, uid := range uids {
+		progress(i, len(uids))
+
+		user, err := users.GetUser(ctx, uid)
+		if err != nil {
+			summary.Failed[uid] = err
+			continue
+		}
+		if err := users.SetCustomUserClaims(ctx, uid, MergeCustomClaims(user.CustomClaims, changes)); err != nil {
+			summary.Failed[uid] = err
+			continue
+		}
+		summary.Updated = append(summary.Updated, uid)
+	}
+	progress(len(uids), len(uids))
+
+	return summary
+}
@@ -0,0 +1,66 @@
+package firebase
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValidateCustomClaims(t *testing.T) {
+	tests := []struct {
+		name    string
+		claims  map[string]interface{}
+		wantErr string
+	}{
+		{"empty", nil, ""},
+		{"role", map[string]interface{}{"admin": true, "role": "coach"}, ""},
+		{"reserved", map[string]interface{}{"sub": "x", "aud": "y", "role": "coach"}, "reserved claims can't be set: aud, sub"},
+		{"at the limit", map[string]interface{}{"n": strings.Repeat("x", MaxCustomClaimsSize-8)}, ""},
+		{"too large", map[string]interface{}{"n": strings.Repeat("x", MaxCustomClaimsSize-7)}, "claims are 1001 bytes, the limit is 1000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCustomClaims(tt.claims)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateCustomClaims() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ValidateCustomClaims() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGrantClaims(t *testing.T) {
+	authStore, _, err := LoadDemoFixture()
+	if err != nil {
+		t.Fatalf("LoadDemoFixture() error = %v", err)
+	}
+	ctx := context.Background()
+	const maya = "3xKq9PzR1vT7bN2mYc5LwA8dFe4H"
+
+	summary := GrantClaims(ctx, authStore, []string{maya, "missing"}, map[string]interface{}{"role": "coach"}, nil)
+	if len(summary.Updated) != 1 || summary.Updated[0] != maya {
+		t.Errorf("Updated = %v, want only %s", summary.Updated, maya)
+	}
+	if _, ok := summary.Failed["missing"]; !ok || len(summary.Failed) != 1 {
+		t.Errorf("Failed = %v, want only the missing user", summary.Failed)
+	}
+
+	// Claims the grant doesn't mention are kept
+	user, _ := authStore.GetUser(ctx, maya)
+	if user.CustomClaims["admin"] != true || user.CustomClaims["role"] != "coach" {
+		t.Errorf("CustomClaims = %v, want admin and coach", user.CustomClaims)
+	}
+
+	// A nil value removes a claim
+	GrantClaims(ctx, authStore, []string{maya}, map[string]interface{}{"admin": nil}, nil)
+	user, _ = authStore.GetUser(ctx, maya)
+	if _, ok := user.CustomClaims["admin"]; ok {
+		t.Errorf("CustomClaims = %v, want admin removed", user.CustomClaims)
+	}
+}
@@ -0,0 +1,302 @@
+package firebase
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	docRefType    = reflect.TypeOf(&firestore.DocumentRef{})
+	latLngType    = reflect.TypeOf(&latlng.LatLng{})
+	byteSliceType = reflect.TypeOf([]byte(nil))
+)
+
+// encodeValue converts a Go value into the representation Firestore returns
+// from DocumentSnapshot.Data: int64, float64, string, bool, time.Time, []byte,
+// []interface{}, map[string]interface{}, references and geopoints.
+// Structs honour `firestore` field tags. The result never shares memory with v.
+func encodeValue(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if v == firestore.ServerTimestamp {
+		return time.Now(), nil
+	}
+	return encodeReflect(reflect.ValueOf(v))
+}
+
+func encodeReflect(v reflect.Value) (interface{}, error) {
+	switch v.Type() {
+	case timeType:
+		return v.Interface(), nil
+	case docRefType, latLngType:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return v.Interface(), nil
+	case byteSliceType:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return append([]byte(nil), v.Bytes()...), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return encodeReflect(v.Elem())
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return int64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			elem, err := encodeReflect(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = elem
+		}
+		return out, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map key type %s is not supported", v.Type().Key())
+		}
+		if v.IsNil() {
+			return nil, nil
+		}
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			elem, err := encodeReflect(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			out[iter.Key().String()] = elem
+		}
+		return out, nil
+	case reflect.Struct:
+		out := make(map[string]interface{})
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, omitEmpty, skip := fieldTag(field)
+			if skip || (omitEmpty && v.Field(i).IsZero()) {
+				continue
+			}
+			elem, err := encodeReflect(v.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			out[name] = elem
+		}
+		return out, nil
+	}
+
+	return nil, fmt.Errorf("type %s is not supported", v.Type())
+}
+
+// encodeData converts document data passed to Create or Set into a map
+func encodeData(data interface{}) (map[string]interface{}, error) {
+	encoded, err := encodeValue(data)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := encoded.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("document data must be a map or struct, got %T", data)
+	}
+	return m, nil
+}
+
+// decodeData copies document data into dest, which must be a non-nil pointer.
+// It mirrors DocumentSnapshot.DataTo for the in-memory backend.
+func decodeData(data map[string]interface{}, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("destination must be a non-nil pointer, got %T", dest)
+	}
+	return decodeReflect(data, v.Elem())
+}
+
+func decodeReflect(src interface{}, dst reflect.Value) error {
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	if dst.Kind() == reflect.Interface {
+		copied, err := encodeValue(src)
+		if err != nil {
+			return err
+		}
+		if copied == nil {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		cv := reflect.ValueOf(copied)
+		if !cv.Type().AssignableTo(dst.Type()) {
+			return fmt.Errorf("cannot assign %T to %s", src, dst.Type())
+		}
+		dst.Set(cv)
+		return nil
+	}
+
+	sv := reflect.ValueOf(src)
+	switch dst.Type() {
+	case timeType, docRefType, latLngType:
+		if sv.Type() != dst.Type() {
+			return fmt.Errorf("cannot assign %T to %s", src, dst.Type())
+		}
+		dst.Set(sv)
+		return nil
+	case byteSliceType:
+		b, ok := src.([]byte)
+		if !ok {
+			return fmt.Errorf("cannot assign %T to []byte", src)
+		}
+		dst.SetBytes(append([]byte(nil), b...))
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(dst.Type().Elem())
+		if err := decodeReflect(src, elem.Elem()); err != nil {
+			return err
+		}
+		dst.Set(elem)
+	case reflect.String:
+		s, ok := src.(string)
+		if !ok {
+			return fmt.Errorf("cannot assign %T to string", src)
+		}
+		dst.SetString(s)
+	case reflect.Bool:
+		b, ok := src.(bool)
+		if !ok {
+			return fmt.Errorf("cannot assign %T to bool", src)
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch n := src.(type) {
+		case int64:
+			dst.SetInt(n)
+		case float64:
+			if n != float64(int64(n)) {
+				return fmt.Errorf("cannot assign non-integer %v to %s", n, dst.Type())
+			}
+			dst.SetInt(int64(n))
+		default:
+			return fmt.Errorf("cannot assign %T to %s", src, dst.Type())
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := src.(int64)
+		if !ok || n < 0 {
+			return fmt.Errorf("cannot assign %v to %s", src, dst.Type())
+		}
+		dst.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		switch n := src.(type) {
+		case int64:
+			dst.SetFloat(float64(n))
+		case float64:
+			dst.SetFloat(n)
+		default:
+			return fmt.Errorf("cannot assign %T to %s", src, dst.Type())
+		}
+	case reflect.Slice:
+		items, ok := src.([]interface{})
+		if !ok {
+			return fmt.Errorf("cannot assign %T to %s", src, dst.Type())
+		}
+		out := reflect.MakeSlice(dst.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeReflect(item, out.Index(i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(out)
+	case reflect.Map:
+		m, ok := src.(map[string]interface{})
+		if !ok || dst.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("cannot assign %T to %s", src, dst.Type())
+		}
+		out := reflect.MakeMapWithSize(dst.Type(), len(m))
+		for k, item := range m {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := decodeReflect(item, elem); err != nil {
+				return err
+			}
+			out.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), elem)
+		}
+		dst.Set(out)
+	case reflect.Struct:
+		m, ok := src.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot assign %T to %s", src, dst.Type())
+		}
+		t := dst.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, skip := fieldTag(field)
+			if skip {
+				continue
+			}
+			item, ok := m[name]
+			if !ok {
+				continue
+			}
+			if err := decodeReflect(item, dst.Field(i)); err != nil {
+				return fmt.Errorf("field %s: %w", name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("type %s is not supported", dst.Type())
+	}
+
+	return nil
+}
+
+// fieldTag parses the `firestore` tag of a struct field
+func fieldTag(field reflect.StructField) (name string, omitEmpty, skip bool) {
+	tag := field.Tag.Get("firestore")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
@@ -0,0 +1,202 @@
+package firebase
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"firebase.google.com/go/v4/auth"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// exportPageSize is the largest page of users Firebase lists at once
+const exportPageSize = 1000
+
+// ExportUserFields are the fields of an exported user, in the order they're
+// written. They match the columns read by user imports, except for the
+// metadata fields.
+var ExportUserFields = []string{
+	"uid", "email", "emailVerified", "displayName", "phoneNumber", "photoURL", "disabled",
+	"customClaims", "providers", "createdAt", "lastSignInAt", "lastRefreshAt",
+}
+
+// ExportPasswordFields are the credential fields of an exported user, only
+// written when asked for
+var ExportPasswordFields = []string{"passwordHash", "passwordSalt"}
+
+// ExportValue converts a Firestore value to one that encodes as plain JSON:
+// timestamps become RFC 3339 strings, references their document path,
+// geopoints a latitude/longitude object and bytes base64
+func ExportValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case *firestore.DocumentRef:
+		if v == nil {
+			return nil
+		}
+		return documentPath(v)
+	case *latlng.LatLng:
+		if v == nil {
+			return nil
+		}
+		return map[string]interface{}{"latitude": v.Latitude, "longitude": v.Longitude}
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, elem := range v {
+			out[i] = ExportValue(elem)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, elem := range v {
+			out[k] = ExportValue(elem)
+		}
+		return out
+	}
+	return v
+}
+
+// ExportDocument converts a document to an exported record holding its id,
+// path and converted fields. A field named id or path wins over the metadata.
+func ExportDocument(d Document) map[string]interface{} {
+	record := ExportValue(d.Data).(map[string]interface{})
+	if _, ok := record["id"]; !ok {
+		record["id"] = d.ID
+	}
+	if _, ok := record["path"]; !ok {
+		record["path"] = d.Path
+	}
+	return record
+}
+
+// ExportUser converts a user to an exported record with ExportUserFields, and
+// ExportPasswordFields when withPasswords is set. Timestamps that were never
+// set are left empty.
+func ExportUser(u *auth.ExportedUserRecord, withPasswords bool) map[string]interface{} {
+	millis := func(ms int64) interface{} {
+		if ms <= 0 {
+			return nil
+		}
+		return time.UnixMilli(ms).UTC().Format(time.RFC3339)
+	}
+
+	providers := make([]interface{}, 0, len(u.ProviderUserInfo))
+	for _, p := range u.ProviderUserInfo {
+		providers = append(providers, p.ProviderID)
+	}
+
+	record := map[string]interface{}{
+		"uid":           u.UID,
+		"email":         u.Email,
+		"emailVerified": u.EmailVerified,
+		"displayName":   u.DisplayName,
+		"phoneNumber":   u.PhoneNumber,
+		"photoURL":      u.PhotoURL,
+		"disabled":      u.Disabled,
+		"customClaims":  nil,
+		"providers":     providers,
+		"createdAt":     nil,
+		"lastSignInAt":  nil,
+		"lastRefreshAt": nil,
+	}
+	if withPasswords {
+		record["passwordHash"] = u.PasswordHash
+		record["passwordSalt"] = u.PasswordSalt
+	}
+	if len(u.CustomClaims) > 0 {
+		record["customClaims"] = ExportValue(u.CustomClaims)
+	}
+	if u.UserMetadata != nil {
+		record["createdAt"] = millis(u.UserMetadata.CreationTimestamp)
+		record["lastSignInAt"] = millis(u.UserMetadata.LastLogInTimestamp)
+		record["lastRefreshAt"] = millis(u.UserMetadata.LastRefreshTimestamp)
+	}
+	return record
+}
+
+// ExportUsers lists every user a page at a time, calling write for each one
+// so large projects are never held in memory. It stops at the first error.
+func ExportUsers(ctx context.Context, users UserStore, write func(*auth.ExportedUserRecord) error) error {
+	pageToken := ""
+	for {
+		page, err := users.ListUsers(ctx, exportPageSize, pageToken)
+		if err != nil {
+			return err
+		}
+		for _, u := range page.Users {
+			if err := write(u); err != nil {
+				return err
+			}
+		}
+		if page.NextPageToken == "" {
+			return nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// DocumentFromJSON decodes a JSON object into document data, reversing
+// ExportValue where it can: whole numbers become integers and RFC 3339
+// strings timestamps. References and geopoints stay strings and maps.
+func DocumentFromJSON(data []byte) (map[string]interface{}, error) {
+	v, err := ValueFromJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("a document must be a JSON object")
+	}
+	return doc, nil
+}
+
+// ValueFromJSON decodes any JSON value into a field value, converting it as
+// DocumentFromJSON does
+func ValueFromJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return fromJSON(v), nil
+}
+
+// fromJSON converts a decoded JSON value to a Firestore value
+func fromJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t
+		}
+		return v
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = fromJSON(elem)
+		}
+		return v
+	case map[string]interface{}:
+		for k, elem := range v {
+			v[k] = fromJSON(elem)
+		}
+		return v
+	}
+	return v
+}
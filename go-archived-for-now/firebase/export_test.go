@@ -0,0 +1,65 @@
+package firebase
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"firebase.google.com/go/v4/auth"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+func TestExportValue(t *testing.T) {
+	value := ExportValue(map[string]interface{}{
+		"at":    time.Date(2024, 4, 11, 7, 5, 0, 0, time.FixedZone("WIB", 7*3600)),
+		"ref":   &firestore.DocumentRef{Path: "projects/p/databases/(default)/documents/exercises/eBench01"},
+		"where": &latlng.LatLng{Latitude: -6.2, Longitude: 106.8},
+		"raw":   []byte("hi"),
+		"sets":  []interface{}{map[string]interface{}{"done": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}},
+	})
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	want := `{"at":"2024-04-11T00:05:00Z","raw":"aGk=","ref":"exercises/eBench01","sets":[{"done":"2024-01-01T00:00:00Z"}],"where":{"latitude":-6.2,"longitude":106.8}}`
+	if string(data) != want {
+		t.Errorf("Exported\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestExportUsers(t *testing.T) {
+	authStore, _ := loadTestFixture(t)
+	ctx := context.Background()
+
+	var uids []string
+	var first map[string]interface{}
+	err := ExportUsers(ctx, authStore, func(u *auth.ExportedUserRecord) error {
+		if first == nil {
+			first = ExportUser(u, false)
+		}
+		uids = append(uids, u.UID)
+		return nil
+	})
+	page, _ := authStore.ListUsers(ctx, 1000, "")
+	if err != nil || len(uids) != len(page.Users) {
+		t.Fatalf("Exported %d users (error %v), want %d", len(uids), err, len(page.Users))
+	}
+	for _, field := range ExportUserFields {
+		if _, ok := first[field]; !ok {
+			t.Errorf("Exported user has no %s", field)
+		}
+	}
+	if _, ok := first["passwordHash"]; ok {
+		t.Error("Expected the password hash to be left out by default")
+	}
+	hashed := ExportUser(&auth.ExportedUserRecord{UserRecord: &auth.UserRecord{UserInfo: &auth.UserInfo{UID: "u1"}}, PasswordHash: "aGFzaA"}, true)
+	if hashed["passwordHash"] != "aGFzaA" {
+		t.Errorf("Expected the password hash when asked for, got %v", hashed)
+	}
+	if first["uid"] != uids[0] {
+		t.Errorf("Exported uid %v, want %s", first["uid"], uids[0])
+	}
+}
@@ -0,0 +1,68 @@
+package firebase
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// simpleFieldName matches the field names that need no quoting in a field path
+var simpleFieldName = regexp.MustCompile(`^[_a-zA-Z][_a-zA-Z0-9]*$`)
+
+// QuoteField returns a field name for use as one segment of a dotted field
+// path, in backticks unless it's a simple name, so that an update keyed by
+// QuoteField("a.b") sets the top-level field "a.b" rather than the field b of a
+func QuoteField(name string) string {
+	if simpleFieldName.MatchString(name) {
+		return name
+	}
+	name = strings.ReplaceAll(name, `\`, `\\`)
+	return "`" + strings.ReplaceAll(name, "`", "\\`") + "`"
+}
+
+// queryFieldPath splits a field path of a query checked by Query.Validate
+func queryFieldPath(path string) []string {
+	fields, err := splitFieldPath(path)
+	if err != nil {
+		return []string{path}
+	}
+	return fields
+}
+
+// splitFieldPath splits a dotted field path into its field names, where
+// segments quoted with QuoteField may contain dots
+func splitFieldPath(path string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	quoted, escaped, wasQuoted := false, false, false
+	for _, r := range path {
+		switch {
+		case escaped:
+			field.WriteRune(r)
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '`' && (quoted || field.Len() == 0 && !wasQuoted):
+			quoted = !quoted
+			wasQuoted = true
+		case !quoted && r == '.':
+			if field.Len() == 0 && !wasQuoted {
+				return nil, fmt.Errorf("invalid field path %q: empty field name", path)
+			}
+			fields = append(fields, field.String())
+			field.Reset()
+			wasQuoted = false
+		case wasQuoted && !quoted:
+			return nil, fmt.Errorf("invalid field path %q: text after a quoted field name", path)
+		default:
+			field.WriteRune(r)
+		}
+	}
+	if quoted || escaped {
+		return nil, fmt.Errorf("invalid field path %q: unterminated quoted field name", path)
+	}
+	if field.Len() == 0 && !wasQuoted {
+		return nil, fmt.Errorf("invalid field path %q: empty field name", path)
+	}
+	return append(fields, field.String()), nil
+}
@@ -0,0 +1,37 @@
+package firebase
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFieldPaths(t *testing.T) {
+	for _, tc := range []struct {
+		path string
+		want []string // nil for an error
+	}{
+		{"name", []string{"name"}},
+		{"workout.date", []string{"workout", "date"}},
+		{QuoteField("a.b"), []string{"a.b"}},
+		{"meta." + QuoteField("v`1\\2") + ".x", []string{"meta", "v`1\\2", "x"}},
+		{QuoteField(""), []string{""}},
+		{"a..b", nil},
+		{"`a.b", nil},
+		{"`a`b", nil},
+	} {
+		got, err := splitFieldPath(tc.path)
+		if tc.want == nil {
+			if err == nil {
+				t.Errorf("splitFieldPath(%q) = %q, want an error", tc.path, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("splitFieldPath(%q) = %q, %v, want %q", tc.path, got, err, tc.want)
+		}
+	}
+
+	if QuoteField("createdAt") != "createdAt" || QuoteField("a.b") != "`a.b`" || QuoteField("1st") != "`1st`" {
+		t.Errorf("Expected only names that aren't simple to be quoted")
+	}
+}
@@ -13,11 +13,28 @@ import (
 	"google.golang.org/api/option"
 )
 
+// Environment variables read by the Admin SDK to target the Emulator Suite
+const (
+	AuthEmulatorHostEnv      = "FIREBASE_AUTH_EMULATOR_HOST"
+	FirestoreEmulatorHostEnv = "FIRESTORE_EMULATOR_HOST"
+)
+
+// Emulator defaults, matching the ports of `firebase emulators:start`
+const (
+	defaultAuthEmulatorHost      = "127.0.0.1:9099"
+	defaultFirestoreEmulatorHost = "127.0.0.1:8080"
+	defaultEmulatorProjectID     = "demo-arrogance"
+)
+
 // AppClient holds Firebase client instances
 type AppClient struct {
 	App       *firebase.App
 	Auth      *auth.Client
 	Firestore *firestore.Client
+
+	// ProjectID is only set when running against the emulators
+	ProjectID string
+	Emulator  bool
 }
 
 var (
@@ -25,10 +42,42 @@ var (
 	Client *AppClient
 )
 
+// UseEmulators points the SDK at a local Emulator Suite, keeping any
+// emulator hosts that are already set in the environment
+func UseEmulators() {
+	if os.Getenv(AuthEmulatorHostEnv) == "" {
+		os.Setenv(AuthEmulatorHostEnv, defaultAuthEmulatorHost)
+	}
+	if os.Getenv(FirestoreEmulatorHostEnv) == "" {
+		os.Setenv(FirestoreEmulatorHostEnv, defaultFirestoreEmulatorHost)
+	}
+}
+
+// EmulatorEnabled reports whether either emulator host is configured
+func EmulatorEnabled() bool {
+	return os.Getenv(AuthEmulatorHostEnv) != "" || os.Getenv(FirestoreEmulatorHostEnv) != ""
+}
+
+// EmulatorProjectID returns the project ID used against the emulators.
+// It must match the project the emulators were started with.
+func EmulatorProjectID() string {
+	for _, env := range []string{"FIREBASE_PROJECT_ID", "GCLOUD_PROJECT", "GOOGLE_CLOUD_PROJECT"} {
+		if projectID := os.Getenv(env); projectID != "" {
+			return projectID
+		}
+	}
+	return defaultEmulatorProjectID
+}
+
 // InitFirebase initializes Firebase services
 func InitFirebase() (*AppClient, error) {
 	ctx := context.Background()
 
+	// The emulators need no credentials, only a project ID
+	if EmulatorEnabled() {
+		return initEmulator(ctx)
+	}
+
 	// Get service account path from environment
 	serviceAccountPath := os.Getenv("FIREBASE_SERVICE_ACCOUNT")
 	if serviceAccountPath == "" {
@@ -99,6 +148,45 @@ func InitFirebase() (*AppClient, error) {
 	return client, nil
 }
 
+// initEmulator initializes Firebase services against the Emulator Suite
+func initEmulator(ctx context.Context) (*AppClient, error) {
+	projectID := EmulatorProjectID()
+	log.Printf("Using Firebase emulators for project %s (auth: %q, firestore: %q)",
+		projectID, os.Getenv(AuthEmulatorHostEnv), os.Getenv(FirestoreEmulatorHostEnv))
+
+	if os.Getenv(AuthEmulatorHostEnv) == "" || os.Getenv(FirestoreEmulatorHostEnv) == "" {
+		log.Println("Only one emulator host is set, the other service will fail without credentials")
+	}
+
+	app, err := firebase.NewApp(ctx, &firebase.Config{ProjectID: projectID}, option.WithoutAuthentication())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Firebase for the emulators: %w", err)
+	}
+
+	authClient, err := app.Auth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Auth client: %w", err)
+	}
+
+	firestoreClient, err := app.Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Firestore client: %w", err)
+	}
+
+	client := &AppClient{
+		App:       app,
+		Auth:      authClient,
+		Firestore: firestoreClient,
+		ProjectID: projectID,
+		Emulator:  true,
+	}
+
+	// Set the global client
+	Client = client
+
+	return client, nil
+}
+
 // CloseFirebase closes Firebase connections
 func CloseFirebase() error {
 	if Client != nil && Client.Firestore != nil {
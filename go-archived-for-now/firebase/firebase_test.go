@@ -0,0 +1,43 @@
+package firebase
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEmulatorProjectID(t *testing.T) {
+	t.Setenv("FIREBASE_PROJECT_ID", "")
+	t.Setenv("GCLOUD_PROJECT", "")
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+
+	if got := EmulatorProjectID(); got != defaultEmulatorProjectID {
+		t.Errorf("EmulatorProjectID() = %q, want %q", got, defaultEmulatorProjectID)
+	}
+
+	t.Setenv("GCLOUD_PROJECT", "demo-gcloud")
+	if got := EmulatorProjectID(); got != "demo-gcloud" {
+		t.Errorf("EmulatorProjectID() = %q, want %q", got, "demo-gcloud")
+	}
+
+	t.Setenv("FIREBASE_PROJECT_ID", "demo-firebase")
+	if got := EmulatorProjectID(); got != "demo-firebase" {
+		t.Errorf("EmulatorProjectID() = %q, want %q", got, "demo-firebase")
+	}
+}
+
+func TestUseEmulatorsKeepsExistingHosts(t *testing.T) {
+	t.Setenv(AuthEmulatorHostEnv, "localhost:1234")
+	t.Setenv(FirestoreEmulatorHostEnv, "")
+
+	UseEmulators()
+
+	if !EmulatorEnabled() {
+		t.Fatal("Expected emulators to be enabled")
+	}
+	if got := os.Getenv(AuthEmulatorHostEnv); got != "localhost:1234" {
+		t.Errorf("Auth emulator host = %q, want the existing value", got)
+	}
+	if got := os.Getenv(FirestoreEmulatorHostEnv); got != defaultFirestoreEmulatorHost {
+		t.Errorf("Firestore emulator host = %q, want %q", got, defaultFirestoreEmulatorHost)
+	}
+}
@@ -3,7 +3,10 @@ package firebase
 import (
 	"context"
 	"errors"
-	"reflect"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
@@ -11,6 +14,9 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// ErrDocumentNotFound is returned by reads and updates of a document that does not exist
+var ErrDocumentNotFound = errors.New("document not found")
+
 // FirestoreService provides Firestore database functionality
 type FirestoreService struct {
 	client *firestore.Client
@@ -52,35 +58,66 @@ func (s *FirestoreService) Create(ctx context.Context, collectionPath string, da
 	return ref.ID, nil
 }
 
-// Set creates or overwrites a document
-func (s *FirestoreService) Set(ctx context.Context, collectionPath, documentID string, data interface{}) error {
+// Set creates or overwrites a document. With a precondition the document
+// must still exist with the expected update time, or a *ConflictError is returned.
+func (s *FirestoreService) Set(ctx context.Context, collectionPath, documentID string, data interface{}, preconds ...Precondition) error {
 	if s.client == nil {
 		return errors.New("firestore client not initialized")
 	}
 
-	_, err := s.client.Collection(collectionPath).Doc(documentID).Set(ctx, data)
+	ref := s.client.Collection(collectionPath).Doc(documentID)
+	if expected, ok := expectedUpdate(preconds); ok {
+		return s.writeIfUnchanged(ctx, ref, expected, func(tx *firestore.Transaction) error {
+			return tx.Set(ref, data)
+		})
+	}
+	_, err := ref.Set(ctx, data)
 	return err
 }
 
-// Update updates specific fields of a document
-func (s *FirestoreService) Update(ctx context.Context, collectionPath, documentID string, updates map[string]interface{}) error {
+// Update updates specific fields of a document. With a precondition the
+// document must still have the expected update time, or a *ConflictError is returned.
+func (s *FirestoreService) Update(ctx context.Context, collectionPath, documentID string, updates map[string]interface{}, preconds ...Precondition) error {
 	if s.client == nil {
 		return errors.New("firestore client not initialized")
 	}
 
-	// Convert map to slice of firestore.Update
-	var updateFields []firestore.Update
-	for key, value := range updates {
-		updateFields = append(updateFields, firestore.Update{
-			Path:  key,
-			Value: value,
+	updateFields, err := firestoreUpdates(updates)
+	if err != nil {
+		return err
+	}
+	ref := s.client.Collection(collectionPath).Doc(documentID)
+	if expected, ok := expectedUpdate(preconds); ok {
+		return s.writeIfUnchanged(ctx, ref, expected, func(tx *firestore.Transaction) error {
+			return tx.Update(ref, updateFields)
 		})
 	}
-
-	_, err := s.client.Collection(collectionPath).Doc(documentID).Update(ctx, updateFields)
+	_, err = ref.Update(ctx, updateFields)
+	if status.Code(err) == codes.NotFound {
+		return ErrDocumentNotFound
+	}
 	return err
 }
 
+// writeIfUnchanged runs write in a transaction that first reads the document,
+// returning a *ConflictError instead when it was updated or deleted after expected
+func (s *FirestoreService) writeIfUnchanged(ctx context.Context, ref *firestore.DocumentRef, expected time.Time, write func(tx *firestore.Transaction) error) error {
+	return s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		var actual time.Time
+		if snap != nil && snap.Exists() {
+			actual = snap.UpdateTime
+		}
+		if !actual.Equal(expected) {
+			return &ConflictError{Path: documentPath(ref), Expected: expected, Actual: actual}
+		}
+		return write(tx)
+	})
+}
+
 // Get retrieves a document
 func (s *FirestoreService) Get(ctx context.Context, collectionPath, documentID string, dest interface{}) error {
 	if s.client == nil {
@@ -91,7 +128,7 @@ func (s *FirestoreService) Get(ctx context.Context, collectionPath, documentID s
 	docSnap, err := docRef.Get(ctx)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
-			return errors.New("document not found")
+			return ErrDocumentNotFound
 		}
 		return err
 	}
@@ -99,16 +136,150 @@ func (s *FirestoreService) Get(ctx context.Context, collectionPath, documentID s
 	return docSnap.DataTo(dest)
 }
 
-// Delete removes a document
-func (s *FirestoreService) Delete(ctx context.Context, collectionPath, documentID string) error {
+// GetDocument retrieves a document along with its create and update times
+func (s *FirestoreService) GetDocument(ctx context.Context, collectionPath, documentID string) (Document, error) {
+	if s.client == nil {
+		return Document{}, errors.New("firestore client not initialized")
+	}
+
+	docSnap, err := s.client.Collection(collectionPath).Doc(documentID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return Document{}, ErrDocumentNotFound
+		}
+		return Document{}, err
+	}
+	return snapshotDocument(docSnap), nil
+}
+
+// Delete removes a document. With a precondition the document must still
+// have the expected update time, or a *ConflictError is returned.
+func (s *FirestoreService) Delete(ctx context.Context, collectionPath, documentID string, preconds ...Precondition) error {
 	if s.client == nil {
 		return errors.New("firestore client not initialized")
 	}
 
-	_, err := s.client.Collection(collectionPath).Doc(documentID).Delete(ctx)
+	ref := s.client.Collection(collectionPath).Doc(documentID)
+	if expected, ok := expectedUpdate(preconds); ok {
+		return s.writeIfUnchanged(ctx, ref, expected, func(tx *firestore.Transaction) error {
+			return tx.Delete(ref)
+		})
+	}
+	_, err := ref.Delete(ctx)
 	return err
 }
 
+// BatchDelete removes documents by path, such as "profiles/abc/records/xyz",
+// committing at most MaxBatchSize deletes per batched write
+func (s *FirestoreService) BatchDelete(ctx context.Context, documentPaths []string) error {
+	return batchDelete(ctx, s, documentPaths)
+}
+
+// CommitBatch applies up to MaxBatchSize writes atomically in one batched write
+func (s *FirestoreService) CommitBatch(ctx context.Context, writes []Write) error {
+	if s.client == nil {
+		return errors.New("firestore client not initialized")
+	}
+	if err := checkBatch(writes); err != nil {
+		return err
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+
+	batch := s.client.Batch()
+	for _, w := range writes {
+		ref := s.client.Doc(strings.Trim(w.Path, "/"))
+		switch w.Kind {
+		case SetWrite:
+			batch.Set(ref, w.Data)
+		case UpdateWrite:
+			updates, err := firestoreUpdates(w.Updates)
+			if err != nil {
+				return err
+			}
+			batch.Update(ref, updates)
+		case DeleteWrite:
+			batch.Delete(ref)
+		}
+	}
+	_, err := batch.Commit(ctx)
+	if status.Code(err) == codes.NotFound {
+		return ErrDocumentNotFound
+	}
+	return err
+}
+
+// RunTransaction runs fn in a Firestore transaction, running it again when
+// the documents it read change before its writes commit
+func (s *FirestoreService) RunTransaction(ctx context.Context, fn func(tx Tx) error) error {
+	if s.client == nil {
+		return errors.New("firestore client not initialized")
+	}
+
+	return s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		return fn(&firestoreTx{client: s.client, tx: tx})
+	}, firestore.MaxAttempts(MaxTransactionAttempts))
+}
+
+// firestoreTx is a Tx on a Firestore transaction
+type firestoreTx struct {
+	client *firestore.Client
+	tx     *firestore.Transaction
+	wrote  bool
+}
+
+// Get reads a document with its create and update times
+func (t *firestoreTx) Get(collectionPath, documentID string) (Document, error) {
+	if t.wrote {
+		return Document{}, ErrReadAfterWrite
+	}
+	snap, err := t.tx.Get(t.client.Collection(collectionPath).Doc(documentID))
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return Document{}, ErrDocumentNotFound
+		}
+		return Document{}, err
+	}
+	return snapshotDocument(snap), nil
+}
+
+// Set creates or overwrites a document when the transaction commits
+func (t *firestoreTx) Set(collectionPath, documentID string, data interface{}) error {
+	t.wrote = true
+	return t.tx.Set(t.client.Collection(collectionPath).Doc(documentID), data)
+}
+
+// Update updates specific fields of a document when the transaction commits
+func (t *firestoreTx) Update(collectionPath, documentID string, updates map[string]interface{}) error {
+	t.wrote = true
+	fields, err := firestoreUpdates(updates)
+	if err != nil {
+		return err
+	}
+	return t.tx.Update(t.client.Collection(collectionPath).Doc(documentID), fields)
+}
+
+// Delete removes a document when the transaction commits
+func (t *firestoreTx) Delete(collectionPath, documentID string) error {
+	t.wrote = true
+	return t.tx.Delete(t.client.Collection(collectionPath).Doc(documentID))
+}
+
+// firestoreUpdates converts updates keyed by dotted field paths to Firestore
+// updates, keeping the dots of quoted field names
+func firestoreUpdates(updates map[string]interface{}) ([]firestore.Update, error) {
+	fields := make([]firestore.Update, 0, len(updates))
+	for key, value := range updates {
+		path, err := splitFieldPath(key)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, firestore.Update{FieldPath: firestore.FieldPath(path), Value: value})
+	}
+	return fields, nil
+}
+
 // List retrieves all documents in a collection
 func (s *FirestoreService) List(ctx context.Context, collectionPath string) ([]map[string]interface{}, error) {
 	if s.client == nil {
@@ -135,29 +306,109 @@ func (s *FirestoreService) List(ctx context.Context, collectionPath string) ([]m
 	return results, nil
 }
 
-// Query executes a query on a collection
-func (s *FirestoreService) Query(ctx context.Context, collectionPath string, dest interface{}, queries ...firestore.Query) error {
+// ListCollections returns the IDs of the subcollections of a document, or of
+// the root collections when documentPath is empty, in sorted order
+func (s *FirestoreService) ListCollections(ctx context.Context, documentPath string) ([]string, error) {
 	if s.client == nil {
-		return errors.New("firestore client not initialized")
+		return nil, errors.New("firestore client not initialized")
+	}
+
+	var iter *firestore.CollectionIterator
+	if documentPath == "" {
+		iter = s.client.Collections(ctx)
+	} else {
+		doc := s.client.Doc(documentPath)
+		if doc == nil {
+			return nil, fmt.Errorf("invalid document path %q", documentPath)
+		}
+		iter = doc.Collections(ctx)
+	}
+
+	var ids []string
+	for {
+		col, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, col.ID)
+	}
+
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Reference returns a reference to the document at documentPath, for storing
+// in a field
+func (s *FirestoreService) Reference(documentPath string) (*firestore.DocumentRef, error) {
+	if s.client == nil {
+		return nil, errors.New("firestore client not initialized")
 	}
+	if _, _, err := SplitDocumentPath(documentPath); err != nil {
+		return nil, err
+	}
+	return s.client.Doc(strings.Trim(documentPath, "/")), nil
+}
 
-	// Verify dest is a pointer to a slice
-	destVal := reflect.ValueOf(dest)
-	if destVal.Kind() != reflect.Ptr || destVal.Elem().Kind() != reflect.Slice {
-		return errors.New("destination must be a pointer to a slice")
+// Query executes a query and returns the matching documents
+func (s *FirestoreService) Query(ctx context.Context, q Query) ([]Document, error) {
+	var results []Document
+	err := s.QueryEach(ctx, q, func(d Document) error {
+		results = append(results, d)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return results, nil
+}
 
-	// Create a slice to store the results
-	sliceType := destVal.Elem().Type()
-	elemType := sliceType.Elem()
-	results := reflect.MakeSlice(sliceType, 0, 0)
+// QueryEach executes a query and calls fn with each matching document as it
+// arrives, stopping at the first error
+func (s *FirestoreService) QueryEach(ctx context.Context, q Query, fn func(Document) error) error {
+	if s.client == nil {
+		return errors.New("firestore client not initialized")
+	}
+	if err := q.Validate(); err != nil {
+		return err
+	}
 
-	// Start with the collection reference
-	query := s.client.Collection(collectionPath).Query
+	// Start with the collection or collection group
+	var query firestore.Query
+	if q.AllDescendants {
+		query = s.client.CollectionGroup(q.Collection).Query
+	} else {
+		query = s.client.Collection(q.Collection).Query
+	}
 
-	// Apply all query filters
-	for _, q := range queries {
-		query = q
+	// Apply all query clauses
+	for _, f := range q.Filters {
+		query = query.WherePath(firestore.FieldPath(queryFieldPath(f.Path)), f.Op, f.Value)
+	}
+	for _, o := range q.Orders {
+		dir := firestore.Asc
+		if o.Direction == Desc {
+			dir = firestore.Desc
+		}
+		query = query.OrderByPath(firestore.FieldPath(queryFieldPath(o.Path)), dir)
+	}
+	if len(q.StartAfter) > 0 {
+		if len(q.Orders) == 0 {
+			query = query.OrderBy(firestore.DocumentID, firestore.Asc)
+		}
+		query = query.StartAfter(q.StartAfter...)
+	}
+	if q.LimitTo > 0 {
+		query = query.Limit(q.LimitTo)
+	}
+	if len(q.Fields) > 0 {
+		paths := make([]firestore.FieldPath, len(q.Fields))
+		for i, field := range q.Fields {
+			paths[i] = queryFieldPath(field)
+		}
+		query = query.SelectPaths(paths...)
 	}
 
 	// Execute the query
@@ -167,35 +418,32 @@ func (s *FirestoreService) Query(ctx context.Context, collectionPath string, des
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
-			break
+			return nil
 		}
 		if err != nil {
 			return err
 		}
-
-		// Create a new element of the slice's element type
-		elem := reflect.New(elemType).Elem()
-		elemAddr := elem.Addr().Interface()
-
-		// If the element is a struct, use DataTo
-		if elemType.Kind() == reflect.Struct {
-			if err := doc.DataTo(elemAddr); err != nil {
-				return err
-			}
-		} else if elemType.Kind() == reflect.Map {
-			// If the element is a map, use Data
-			data := doc.Data()
-			data["id"] = doc.Ref.ID
-			elem.Set(reflect.ValueOf(data))
-		} else {
-			return errors.New("unsupported element type in slice")
+		if err := fn(snapshotDocument(doc)); err != nil {
+			return err
 		}
+	}
+}
 
-		// Append the element to the results
-		results = reflect.Append(results, elem)
+// snapshotDocument converts a snapshot to a Document
+func snapshotDocument(doc *firestore.DocumentSnapshot) Document {
+	return Document{
+		ID:         doc.Ref.ID,
+		Path:       documentPath(doc.Ref),
+		Data:       doc.Data(),
+		CreateTime: doc.CreateTime,
+		UpdateTime: doc.UpdateTime,
 	}
+}
 
-	// Set the results back to the destination
-	destVal.Elem().Set(results)
-	return nil
+// documentPath returns the path of a document relative to the database root
+func documentPath(ref *firestore.DocumentRef) string {
+	if _, path, ok := strings.Cut(ref.Path, "/documents/"); ok {
+		return path
+	}
+	return ref.Path
 }
@@ -0,0 +1,156 @@
+package firebase
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// demoFixture is the data set used by the --demo mode
+//
+//go:embed fixtures/demo.json
+var demoFixture []byte
+
+// Fixture is the on-disk format used to seed the in-memory backend.
+//
+// Collections are keyed by collection path, so subcollections are written
+// as e.g. "profiles/{id}/records". String values in RFC 3339 format are
+// loaded as timestamps, and whole numbers as integers.
+type Fixture struct {
+	Users       []FixtureUser                                `json:"users"`
+	Collections map[string]map[string]map[string]interface{} `json:"collections"`
+}
+
+// FixtureUser describes a single Auth user in a fixture
+type FixtureUser struct {
+	UID           string                 `json:"uid"`
+	Email         string                 `json:"email"`
+	DisplayName   string                 `json:"displayName"`
+	PhoneNumber   string                 `json:"phoneNumber"`
+	PhotoURL      string                 `json:"photoURL"`
+	EmailVerified bool                   `json:"emailVerified"`
+	Disabled      bool                   `json:"disabled"`
+	Providers     []string               `json:"providers"`
+	CustomClaims  map[string]interface{} `json:"customClaims"`
+	CreatedAt     time.Time              `json:"createdAt"`
+	LastSignInAt  *time.Time             `json:"lastSignInAt"`
+	LastRefreshAt *time.Time             `json:"lastRefreshAt"`
+}
+
+// LoadFixture reads a fixture and returns in-memory stores seeded with it
+func LoadFixture(r io.Reader) (*MemoryAuth, *MemoryFirestore, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var fixture Fixture
+	if err := dec.Decode(&fixture); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse fixture: %w", err)
+	}
+
+	authStore := NewMemoryAuth()
+	for _, user := range fixture.Users {
+		if user.UID == "" {
+			return nil, nil, fmt.Errorf("fixture user %q has no uid", user.Email)
+		}
+		authStore.addUser(user.toRecord())
+	}
+
+	docStore := NewMemoryFirestore()
+	for path, docs := range fixture.Collections {
+		for id, data := range docs {
+			doc, ok := fixtureValue(data).(map[string]interface{})
+			if !ok {
+				return nil, nil, fmt.Errorf("fixture document %s/%s is not an object", path, id)
+			}
+			docStore.put(path, id, doc)
+		}
+	}
+
+	return authStore, docStore, nil
+}
+
+// LoadDemoFixture returns in-memory stores seeded with the built-in demo data
+func LoadDemoFixture() (*MemoryAuth, *MemoryFirestore, error) {
+	return LoadFixture(bytes.NewReader(demoFixture))
+}
+
+// toRecord converts a fixture user to the record the Admin SDK would return
+func (u FixtureUser) toRecord() *auth.ExportedUserRecord {
+	record := &auth.UserRecord{
+		UserInfo: &auth.UserInfo{
+			UID:         u.UID,
+			Email:       u.Email,
+			DisplayName: u.DisplayName,
+			PhoneNumber: u.PhoneNumber,
+			PhotoURL:    u.PhotoURL,
+			ProviderID:  "firebase",
+		},
+		CustomClaims:  u.CustomClaims,
+		EmailVerified: u.EmailVerified,
+		Disabled:      u.Disabled,
+		UserMetadata: &auth.UserMetadata{
+			CreationTimestamp: u.CreatedAt.UnixMilli(),
+		},
+	}
+	if u.LastSignInAt != nil {
+		record.UserMetadata.LastLogInTimestamp = u.LastSignInAt.UnixMilli()
+	}
+	if u.LastRefreshAt != nil {
+		record.UserMetadata.LastRefreshTimestamp = u.LastRefreshAt.UnixMilli()
+	}
+
+	for _, provider := range u.Providers {
+		info := &auth.UserInfo{
+			UID:         u.UID,
+			Email:       u.Email,
+			DisplayName: u.DisplayName,
+			PhotoURL:    u.PhotoURL,
+			ProviderID:  provider,
+		}
+		switch provider {
+		case "password":
+			info.UID = u.Email
+		case "phone":
+			info.UID = u.PhoneNumber
+			info.PhoneNumber = u.PhoneNumber
+		}
+		record.ProviderUserInfo = append(record.ProviderUserInfo, info)
+	}
+
+	return &auth.ExportedUserRecord{UserRecord: record}
+}
+
+// fixtureValue converts decoded JSON into Firestore value types
+func fixtureValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case string:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t
+		}
+		return v
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = fixtureValue(item)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = fixtureValue(item)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,244 @@
+package firebase
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+	"firebase.google.com/go/v4/auth/hash"
+)
+
+// MaxImportBatchSize is the maximum number of users Firebase imports in one call
+const MaxImportBatchSize = 1000
+
+// ImportUser is a user account to import. Importing a user whose UID already
+// exists replaces that account. Empty fields are left unset.
+type ImportUser struct {
+	UID           string
+	Email         string
+	EmailVerified bool
+	DisplayName   string
+	PhoneNumber   string
+	PhotoURL      string
+	Disabled      bool
+	CustomClaims  map[string]interface{}
+
+	// PasswordHash and PasswordSalt are the password as hashed by the system
+	// the user comes from, using the algorithm in HashConfig
+	PasswordHash []byte
+	PasswordSalt []byte
+}
+
+// HashConfig describes how imported password hashes were made
+type HashConfig struct {
+	// Algorithm is one of HashAlgorithms, such as SCRYPT or BCRYPT
+	Algorithm string
+	// Key is the signer key of SCRYPT and the key of the HMAC algorithms
+	Key []byte
+	// SaltSeparator is used by SCRYPT
+	SaltSeparator []byte
+	// Rounds is used by SCRYPT, the plain hashes and PBKDF2
+	Rounds int
+	// MemoryCost is used by SCRYPT and STANDARD_SCRYPT
+	MemoryCost int
+	// BlockSize, Parallelization and DerivedKeyLength are used by STANDARD_SCRYPT
+	BlockSize        int
+	Parallelization  int
+	DerivedKeyLength int
+}
+
+// HashAlgorithms are the password hash algorithms Firebase can import
+var HashAlgorithms = []string{
+	"BCRYPT", "SCRYPT", "STANDARD_SCRYPT",
+	"HMAC_MD5", "HMAC_SHA1", "HMAC_SHA256", "HMAC_SHA512",
+	"MD5", "SHA1", "SHA256", "SHA512",
+	"PBKDF_SHA1", "PBKDF2_SHA256",
+}
+
+// toAuth converts the hash config to the Admin SDK representation
+func (h *HashConfig) toAuth() (auth.UserImportHash, error) {
+	var algorithm auth.UserImportHash
+	switch strings.ToUpper(h.Algorithm) {
+	case "BCRYPT":
+		algorithm = hash.Bcrypt{}
+	case "SCRYPT":
+		algorithm = hash.Scrypt{Key: h.Key, SaltSeparator: h.SaltSeparator, Rounds: h.Rounds, MemoryCost: h.MemoryCost}
+	case "STANDARD_SCRYPT":
+		// The SDK doesn't check these, and wrong ones make every password fail
+		if h.MemoryCost <= 0 || h.BlockSize <= 0 || h.Parallelization <= 0 || h.DerivedKeyLength <= 0 {
+			return nil, errors.New("invalid STANDARD_SCRYPT hash config: memory cost, block size, parallelization and derived key length are required")
+		}
+		algorithm = hash.StandardScrypt{
+			MemoryCost:       h.MemoryCost,
+			BlockSize:        h.BlockSize,
+			Parallelization:  h.Parallelization,
+			DerivedKeyLength: h.DerivedKeyLength,
+		}
+	case "HMAC_MD5":
+		algorithm = hash.HMACMD5{Key: h.Key}
+	case "HMAC_SHA1":
+		algorithm = hash.HMACSHA1{Key: h.Key}
+	case "HMAC_SHA256":
+		algorithm = hash.HMACSHA256{Key: h.Key}
+	case "HMAC_SHA512":
+		algorithm = hash.HMACSHA512{Key: h.Key}
+	case "MD5":
+		algorithm = hash.MD5{Rounds: h.Rounds}
+	case "SHA1":
+		algorithm = hash.SHA1{Rounds: h.Rounds}
+	case "SHA256":
+		algorithm = hash.SHA256{Rounds: h.Rounds}
+	case "SHA512":
+		algorithm = hash.SHA512{Rounds: h.Rounds}
+	case "PBKDF_SHA1":
+		algorithm = hash.PBKDFSHA1{Rounds: h.Rounds}
+	case "PBKDF2_SHA256":
+		algorithm = hash.PBKDF2SHA256{Rounds: h.Rounds}
+	default:
+		return nil, fmt.Errorf("unknown hash algorithm %q, use one of %s", h.Algorithm, strings.Join(HashAlgorithms, ", "))
+	}
+
+	// Check the parameters now rather than when the first batch is sent
+	if _, err := algorithm.Config(); err != nil {
+		return nil, fmt.Errorf("invalid %s hash config: %w", strings.ToUpper(h.Algorithm), err)
+	}
+	return algorithm, nil
+}
+
+// Validate checks that the hash config can be used for an import
+func (h *HashConfig) Validate() error {
+	_, err := h.toAuth()
+	return err
+}
+
+// toAuth converts the user to the Admin SDK representation
+func (u *ImportUser) toAuth() *auth.UserToImport {
+	user := (&auth.UserToImport{}).UID(u.UID).EmailVerified(u.EmailVerified).Disabled(u.Disabled)
+	if u.Email != "" {
+		user.Email(u.Email)
+	}
+	if u.DisplayName != "" {
+		user.DisplayName(u.DisplayName)
+	}
+	if u.PhoneNumber != "" {
+		user.PhoneNumber(u.PhoneNumber)
+	}
+	if u.PhotoURL != "" {
+		user.PhotoURL(u.PhotoURL)
+	}
+	if len(u.CustomClaims) > 0 {
+		user.CustomClaims(u.CustomClaims)
+	}
+	if len(u.PasswordHash) > 0 {
+		user.PasswordHash(u.PasswordHash)
+		if len(u.PasswordSalt) > 0 {
+			user.PasswordSalt(u.PasswordSalt)
+		}
+	}
+	return user
+}
+
+// toRecord converts the user to the record the Admin SDK would return once imported
+func (u *ImportUser) toRecord() *auth.ExportedUserRecord {
+	record := &auth.UserRecord{
+		UserInfo: &auth.UserInfo{
+			UID:         u.UID,
+			Email:       u.Email,
+			DisplayName: u.DisplayName,
+			PhoneNumber: u.PhoneNumber,
+			PhotoURL:    u.PhotoURL,
+			ProviderID:  "firebase",
+		},
+		EmailVerified: u.EmailVerified,
+		Disabled:      u.Disabled,
+		UserMetadata: &auth.UserMetadata{
+			CreationTimestamp: time.Now().UnixMilli(),
+		},
+	}
+	if len(u.CustomClaims) > 0 {
+		record.CustomClaims = make(map[string]interface{}, len(u.CustomClaims))
+		for k, v := range u.CustomClaims {
+			record.CustomClaims[k] = v
+		}
+	}
+	if u.Email != "" && len(u.PasswordHash) > 0 {
+		record.ProviderUserInfo = append(record.ProviderUserInfo, &auth.UserInfo{UID: u.Email, Email: u.Email, ProviderID: "password"})
+	}
+	if u.PhoneNumber != "" {
+		record.ProviderUserInfo = append(record.ProviderUserInfo, &auth.UserInfo{UID: u.PhoneNumber, PhoneNumber: u.PhoneNumber, ProviderID: "phone"})
+	}
+
+	return &auth.ExportedUserRecord{
+		UserRecord:   record,
+		PasswordHash: base64.StdEncoding.EncodeToString(u.PasswordHash),
+		PasswordSalt: base64.StdEncoding.EncodeToString(u.PasswordSalt),
+	}
+}
+
+// hasPasswords reports whether any of the users has a password hash
+func hasPasswords(users []*ImportUser) bool {
+	for _, u := range users {
+		if len(u.PasswordHash) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportFailure is a user that couldn't be imported. Index is the position
+// of the user in the list given to the import.
+type ImportFailure struct {
+	Index  int
+	UID    string
+	Reason string
+}
+
+// ImportResult is the outcome of importing users
+type ImportResult struct {
+	Imported int
+	Failures []ImportFailure
+}
+
+// ImportProgress reports how far an import in chunks has got
+type ImportProgress struct {
+	Done  int
+	Total int
+}
+
+// ImportUsersInChunks imports any number of users in chunks of
+// MaxImportBatchSize. A chunk that fails as a whole marks each of its users
+// as failed, and the remaining chunks are still imported. progress may be nil.
+func ImportUsersInChunks(ctx context.Context, users UserStore, records []*ImportUser, hash *HashConfig, progress func(ImportProgress)) *ImportResult {
+	if progress == nil {
+		progress = func(ImportProgress) {}
+	}
+
+	result := &ImportResult{}
+	for start := 0; start < len(records); start += MaxImportBatchSize {
+		end := min(start+MaxImportBatchSize, len(records))
+		progress(ImportProgress{Done: start, Total: len(records)})
+
+		chunk, err := users.ImportUsers(ctx, records[start:end], hash)
+		if err != nil {
+			for i := start; i < end; i++ {
+				result.Failures = append(result.Failures, ImportFailure{Index: i, UID: records[i].UID, Reason: err.Error()})
+			}
+			continue
+		}
+
+		result.Imported += chunk.Imported
+		for _, f := range chunk.Failures {
+			f.Index += start
+			result.Failures = append(result.Failures, f)
+		}
+	}
+	progress(ImportProgress{Done: len(records), Total: len(records)})
+
+	sort.Slice(result.Failures, func(i, j int) bool { return result.Failures[i].Index < result.Failures[j].Index })
+	return result
+}
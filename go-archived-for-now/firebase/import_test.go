@@ -0,0 +1,89 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// countingAuth counts the import calls made to a MemoryAuth
+type countingAuth struct {
+	*MemoryAuth
+	batches []int
+}
+
+func (c *countingAuth) ImportUsers(ctx context.Context, users []*ImportUser, hash *HashConfig) (*ImportResult, error) {
+	c.batches = append(c.batches, len(users))
+	return c.MemoryAuth.ImportUsers(ctx, users, hash)
+}
+
+func TestImportUsersInChunks(t *testing.T) {
+	authStore, _ := loadTestFixture(t)
+	store := &countingAuth{MemoryAuth: authStore}
+	ctx := context.Background()
+
+	users := make([]*ImportUser, 2500)
+	for i := range users {
+		users[i] = &ImportUser{UID: fmt.Sprintf("imported-%04d", i), Email: fmt.Sprintf("user%d@example.com", i)}
+	}
+	users[1500].UID = ""
+	// An existing account is replaced
+	users[7] = &ImportUser{UID: "user-a", Email: "a@example.com", DisplayName: "Imported A"}
+
+	var last ImportProgress
+	result := ImportUsersInChunks(ctx, store, users, nil, func(p ImportProgress) { last = p })
+
+	if fmt.Sprint(store.batches) != "[1000 1000 500]" {
+		t.Errorf("Imported in batches of %v, want [1000 1000 500]", store.batches)
+	}
+	if result.Imported != 2499 || len(result.Failures) != 1 || result.Failures[0].Index != 1500 {
+		t.Errorf("Imported %d with failures %+v, want 2499 and row 1500 failing", result.Imported, result.Failures)
+	}
+	if last.Done != 2500 || last.Total != 2500 {
+		t.Errorf("Last progress %+v, want 2500/2500", last)
+	}
+	if user, _ := authStore.GetUser(ctx, "user-a"); user.DisplayName != "Imported A" {
+		t.Errorf("Expected user-a to be replaced, got display name %q", user.DisplayName)
+	}
+	if _, err := authStore.GetUser(ctx, "missing"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestImportUsersPasswordHashes(t *testing.T) {
+	authStore, _ := loadTestFixture(t)
+	ctx := context.Background()
+	users := []*ImportUser{{UID: "hashed", Email: "hashed@example.com", PasswordHash: []byte("hash"), PasswordSalt: []byte("salt")}}
+
+	// A chunk failing as a whole fails every user in it
+	result := ImportUsersInChunks(ctx, authStore, users, nil, nil)
+	if result.Imported != 0 || len(result.Failures) != 1 || !strings.Contains(result.Failures[0].Reason, "hash algorithm") {
+		t.Errorf("Expected the import to need a hash algorithm, got %+v", result)
+	}
+
+	if err := (&HashConfig{Algorithm: "SCRYPT", Rounds: 8, MemoryCost: 14}).Validate(); err == nil || !strings.Contains(err.Error(), "signer key") {
+		t.Errorf("Expected SCRYPT without a key to be rejected, got %v", err)
+	}
+	if err := (&HashConfig{Algorithm: "STANDARD_SCRYPT", MemoryCost: 14}).Validate(); err == nil || !strings.Contains(err.Error(), "block size") {
+		t.Errorf("Expected STANDARD_SCRYPT without a block size to be rejected, got %v", err)
+	}
+	if err := (&HashConfig{Algorithm: "STANDARD_SCRYPT", MemoryCost: 14, BlockSize: 8, Parallelization: 1, DerivedKeyLength: 64}).Validate(); err != nil {
+		t.Errorf("Expected a complete STANDARD_SCRYPT config to be valid, got %v", err)
+	}
+	if err := (&HashConfig{Algorithm: "ROT13"}).Validate(); err == nil {
+		t.Error("Expected an unknown algorithm to be rejected")
+	}
+
+	hash := &HashConfig{Algorithm: "scrypt", Key: []byte("key"), SaltSeparator: []byte("Bw=="), Rounds: 8, MemoryCost: 14}
+	if result := ImportUsersInChunks(ctx, authStore, users, hash, nil); result.Imported != 1 {
+		t.Fatalf("Expected the user to be imported, got %+v", result)
+	}
+	page, _ := authStore.ListUsers(ctx, 10, "")
+	for _, u := range page.Users {
+		if u.UID == "hashed" && (u.PasswordHash != "aGFzaA==" || len(u.ProviderUserInfo) != 1) {
+			t.Errorf("Expected the password hash and provider to be stored, got %q and %d providers", u.PasswordHash, len(u.ProviderUserInfo))
+		}
+	}
+}
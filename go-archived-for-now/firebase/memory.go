@@ -0,0 +1,1214 @@
+package firebase
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"firebase.google.com/go/v4/auth"
+)
+
+// MemoryAuth is an in-memory UserStore used by tests and demo mode
+type MemoryAuth struct {
+	mu    sync.RWMutex
+	users map[string]*auth.ExportedUserRecord
+}
+
+// NewMemoryAuth creates an empty MemoryAuth
+func NewMemoryAuth() *MemoryAuth {
+	return &MemoryAuth{
+		users: make(map[string]*auth.ExportedUserRecord),
+	}
+}
+
+// GetUser gets a user by their UID
+func (s *MemoryAuth) GetUser(ctx context.Context, uid string) (*auth.UserRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[uid]
+	if !ok {
+		return nil, fmt.Errorf("%w: no user exists with the uid: %q", ErrUserNotFound, uid)
+	}
+	return copyUserRecord(user.UserRecord), nil
+}
+
+// GetUserByEmail gets a user by their email address, ignoring case
+func (s *MemoryAuth) GetUserByEmail(ctx context.Context, email string) (*auth.UserRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email != "" && strings.EqualFold(user.Email, email) {
+			return copyUserRecord(user.UserRecord), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no user exists with the email: %q", ErrUserNotFound, email)
+}
+
+// GetUsers gets the users matching any of up to MaxGetUsersBatchSize
+// identifiers. Emails match ignoring case, as in GetUserByEmail.
+func (s *MemoryAuth) GetUsers(ctx context.Context, identifiers []auth.UserIdentifier) (*auth.GetUsersResult, error) {
+	if len(identifiers) > MaxGetUsersBatchSize {
+		return nil, fmt.Errorf("`identifiers` parameter must have <= %d entries", MaxGetUsersBatchSize)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := &auth.GetUsersResult{}
+	found := make(map[string]bool)
+	for _, id := range identifiers {
+		var match *auth.ExportedUserRecord
+		for _, user := range s.users {
+			if identifierMatches(id, user.UserRecord) {
+				match = user
+				break
+			}
+		}
+		switch {
+		case match == nil:
+			result.NotFound = append(result.NotFound, id)
+		case !found[match.UID]:
+			found[match.UID] = true
+			result.Users = append(result.Users, copyUserRecord(match.UserRecord))
+		}
+	}
+	return result, nil
+}
+
+// identifierMatches reports whether a GetUsers identifier matches a user
+func identifierMatches(id auth.UserIdentifier, user *auth.UserRecord) bool {
+	switch id := id.(type) {
+	case auth.UIDIdentifier:
+		return user.UID == id.UID
+	case auth.EmailIdentifier:
+		return user.Email != "" && strings.EqualFold(user.Email, id.Email)
+	case auth.PhoneIdentifier:
+		return user.PhoneNumber != "" && user.PhoneNumber == id.PhoneNumber
+	case auth.ProviderIdentifier:
+		for _, info := range user.ProviderUserInfo {
+			if info.ProviderID == id.ProviderID && info.UID == id.ProviderUID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ListUsers lists a single page of users ordered by UID.
+// The page token is the UID of the last user on the previous page.
+func (s *MemoryAuth) ListUsers(ctx context.Context, maxResults int, pageToken string) (*UserPage, error) {
+	if maxResults <= 0 || maxResults > 1000 {
+		maxResults = 1000
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	uids := make([]string, 0, len(s.users))
+	for uid := range s.users {
+		if uid > pageToken {
+			uids = append(uids, uid)
+		}
+	}
+	sort.Strings(uids)
+
+	page := &UserPage{}
+	for i, uid := range uids {
+		if i == maxResults {
+			page.NextPageToken = uids[i-1]
+			break
+		}
+		user := s.users[uid]
+		page.Users = append(page.Users, &auth.ExportedUserRecord{
+			UserRecord:   copyUserRecord(user.UserRecord),
+			PasswordHash: user.PasswordHash,
+			PasswordSalt: user.PasswordSalt,
+		})
+	}
+
+	return page, nil
+}
+
+// CreateUser creates a new user
+func (s *MemoryAuth) CreateUser(ctx context.Context, params *UserToCreate) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	uid := params.UID
+	if uid == "" {
+		uid = randomID(28)
+	}
+	if _, ok := s.users[uid]; ok {
+		return "", fmt.Errorf("user with the provided uid already exists: %q", uid)
+	}
+	if err := s.checkUnique("", params.Email, params.PhoneNumber); err != nil {
+		return "", err
+	}
+
+	user := &auth.UserRecord{
+		UserInfo: &auth.UserInfo{
+			UID:         uid,
+			Email:       params.Email,
+			DisplayName: params.DisplayName,
+			PhoneNumber: params.PhoneNumber,
+			PhotoURL:    params.PhotoURL,
+			ProviderID:  "firebase",
+		},
+		EmailVerified: params.EmailVerified,
+		Disabled:      params.Disabled,
+		UserMetadata: &auth.UserMetadata{
+			CreationTimestamp: time.Now().UnixMilli(),
+		},
+	}
+	if params.Email != "" && params.Password != "" {
+		user.ProviderUserInfo = append(user.ProviderUserInfo, &auth.UserInfo{
+			UID:        params.Email,
+			Email:      params.Email,
+			ProviderID: "password",
+		})
+	}
+	if params.PhoneNumber != "" {
+		user.ProviderUserInfo = append(user.ProviderUserInfo, &auth.UserInfo{
+			UID:         params.PhoneNumber,
+			PhoneNumber: params.PhoneNumber,
+			ProviderID:  "phone",
+		})
+	}
+
+	s.users[uid] = &auth.ExportedUserRecord{UserRecord: user}
+	return uid, nil
+}
+
+// UpdateUser updates a user
+func (s *MemoryAuth) UpdateUser(ctx context.Context, uid string, params *UserToUpdate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[uid]
+	if !ok {
+		return fmt.Errorf("%w: no user exists with the uid: %q", ErrUserNotFound, uid)
+	}
+
+	var email, phone string
+	if params.Email != nil {
+		email = *params.Email
+	}
+	if params.PhoneNumber != nil {
+		phone = *params.PhoneNumber
+	}
+	if err := s.checkUnique(uid, email, phone); err != nil {
+		return err
+	}
+
+	if params.Email != nil {
+		user.Email = *params.Email
+	}
+	if params.DisplayName != nil {
+		user.DisplayName = *params.DisplayName
+	}
+	if params.PhoneNumber != nil {
+		user.PhoneNumber = *params.PhoneNumber
+	}
+	if params.PhotoURL != nil {
+		user.PhotoURL = *params.PhotoURL
+	}
+	if params.EmailVerified != nil {
+		user.EmailVerified = *params.EmailVerified
+	}
+	if params.Disabled != nil {
+		user.Disabled = *params.Disabled
+	}
+	return nil
+}
+
+// DeleteUser deletes a user
+func (s *MemoryAuth) DeleteUser(ctx context.Context, uid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[uid]; !ok {
+		return fmt.Errorf("%w: no user exists with the uid: %q", ErrUserNotFound, uid)
+	}
+	delete(s.users, uid)
+	return nil
+}
+
+// RevokeRefreshTokens marks every token issued to a user before now as revoked
+func (s *MemoryAuth) RevokeRefreshTokens(ctx context.Context, uid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[uid]
+	if !ok {
+		return fmt.Errorf("%w: no user exists with the uid: %q", ErrUserNotFound, uid)
+	}
+	// Firebase stores the time in whole seconds
+	user.TokensValidAfterMillis = time.Now().Unix() * 1000
+	return nil
+}
+
+// SetCustomUserClaims replaces the custom claims of a user, checking them the
+// same way Firebase does
+func (s *MemoryAuth) SetCustomUserClaims(ctx context.Context, uid string, claims map[string]interface{}) error {
+	if err := ValidateCustomClaims(claims); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[uid]
+	if !ok {
+		return fmt.Errorf("%w: no user exists with the uid: %q", ErrUserNotFound, uid)
+	}
+	user.CustomClaims = nil
+	if len(claims) > 0 {
+		user.CustomClaims = make(map[string]interface{}, len(claims))
+		for k, v := range claims {
+			user.CustomClaims[k] = v
+		}
+	}
+	return nil
+}
+
+// ImportUsers imports up to MaxImportBatchSize users, replacing existing
+// accounts with the same UID like Firebase does. Users without a UID are
+// reported as failures.
+func (s *MemoryAuth) ImportUsers(ctx context.Context, users []*ImportUser, hash *HashConfig) (*ImportResult, error) {
+	if len(users) > MaxImportBatchSize {
+		return nil, fmt.Errorf("can't import more than %d users at once, got %d", MaxImportBatchSize, len(users))
+	}
+	if hash != nil && hash.Algorithm != "" {
+		if err := hash.Validate(); err != nil {
+			return nil, err
+		}
+	} else if hasPasswords(users) {
+		return nil, errors.New("users with password hashes need a hash algorithm")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := &ImportResult{}
+	for i, u := range users {
+		if u.UID == "" {
+			result.Failures = append(result.Failures, ImportFailure{Index: i, Reason: "uid is required"})
+			continue
+		}
+		if err := ValidateCustomClaims(u.CustomClaims); err != nil {
+			result.Failures = append(result.Failures, ImportFailure{Index: i, UID: u.UID, Reason: err.Error()})
+			continue
+		}
+		s.users[u.UID] = u.toRecord()
+		result.Imported++
+	}
+	return result, nil
+}
+
+// Links generated by MemoryAuth point at the action handler of the demo project
+const memoryActionURL = "https://demo-arrogance.firebaseapp.com/__/auth/action"
+
+// PasswordResetLink generates a password reset link for the user with the email
+func (s *MemoryAuth) PasswordResetLink(ctx context.Context, email string, settings *ActionCodeSettings) (string, error) {
+	return s.actionLink("resetPassword", email, settings)
+}
+
+// EmailVerificationLink generates an email verification link for the user with the email
+func (s *MemoryAuth) EmailVerificationLink(ctx context.Context, email string, settings *ActionCodeSettings) (string, error) {
+	return s.actionLink("verifyEmail", email, settings)
+}
+
+// EmailSignInLink generates a sign-in link for the user with the email
+func (s *MemoryAuth) EmailSignInLink(ctx context.Context, email string, settings *ActionCodeSettings) (string, error) {
+	if settings == nil || settings.ContinueURL == "" {
+		return "", ErrContinueURLRequired
+	}
+	return s.actionLink("signIn", email, settings)
+}
+
+// actionLink builds a link like the ones Firebase sends, with a random code
+func (s *MemoryAuth) actionLink(mode, email string, settings *ActionCodeSettings) (string, error) {
+	if _, err := s.GetUserByEmail(context.Background(), email); err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("mode", mode)
+	query.Set("oobCode", randomID(32))
+	query.Set("apiKey", "demo-api-key")
+	if settings != nil && settings.ContinueURL != "" {
+		query.Set("continueUrl", settings.ContinueURL)
+	}
+	query.Set("lang", "en")
+	return memoryActionURL + "?" + query.Encode(), nil
+}
+
+// addUser stores a fully populated user record, used when loading fixtures
+func (s *MemoryAuth) addUser(user *auth.ExportedUserRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[user.UID] = user
+}
+
+// checkUnique reports an error if another user already has the email or phone number
+func (s *MemoryAuth) checkUnique(uid, email, phone string) error {
+	for _, other := range s.users {
+		if other.UID == uid {
+			continue
+		}
+		if email != "" && strings.EqualFold(other.Email, email) {
+			return fmt.Errorf("%w: %q", ErrEmailExists, email)
+		}
+		if phone != "" && other.PhoneNumber == phone {
+			return fmt.Errorf("%w: %q", ErrPhoneNumberExists, phone)
+		}
+	}
+	return nil
+}
+
+// copyUserRecord returns a copy of user that shares no mutable state with it
+func copyUserRecord(user *auth.UserRecord) *auth.UserRecord {
+	out := *user
+	if user.UserInfo != nil {
+		info := *user.UserInfo
+		out.UserInfo = &info
+	}
+	if user.UserMetadata != nil {
+		meta := *user.UserMetadata
+		out.UserMetadata = &meta
+	}
+	out.ProviderUserInfo = nil
+	for _, provider := range user.ProviderUserInfo {
+		info := *provider
+		out.ProviderUserInfo = append(out.ProviderUserInfo, &info)
+	}
+	if user.CustomClaims != nil {
+		out.CustomClaims = make(map[string]interface{}, len(user.CustomClaims))
+		for k, v := range user.CustomClaims {
+			out.CustomClaims[k] = v
+		}
+	}
+	return &out
+}
+
+// MemoryFirestore is an in-memory DocumentStore used by tests and demo mode.
+// Documents are keyed by their full collection path, so subcollections such
+// as "profiles/{id}/records" are stored alongside root collections.
+type MemoryFirestore struct {
+	mu          sync.RWMutex
+	collections map[string]map[string]*memoryDocument
+}
+
+// memoryDocument is a stored document with its metadata
+type memoryDocument struct {
+	data       map[string]interface{}
+	createTime time.Time
+	updateTime time.Time
+}
+
+// NewMemoryFirestore creates an empty MemoryFirestore
+func NewMemoryFirestore() *MemoryFirestore {
+	return &MemoryFirestore{
+		collections: make(map[string]map[string]*memoryDocument),
+	}
+}
+
+// Create adds a new document to the specified collection
+func (s *MemoryFirestore) Create(ctx context.Context, collectionPath string, data interface{}) (string, error) {
+	doc, err := encodeData(data)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := randomID(20)
+	s.put(collectionPath, id, doc)
+	return id, nil
+}
+
+// Set creates or overwrites a document, which must still have the update
+// time expected by preconds when they're given
+func (s *MemoryFirestore) Set(ctx context.Context, collectionPath, documentID string, data interface{}, preconds ...Precondition) error {
+	doc, err := encodeData(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkUnchanged(collectionPath, documentID, preconds); err != nil {
+		return err
+	}
+	s.put(collectionPath, documentID, doc)
+	return nil
+}
+
+// Update updates specific fields of a document, which must still have the
+// update time expected by preconds when they're given.
+// Keys are dotted field paths, so "workout.date" updates a nested field;
+// quote names containing dots with QuoteField.
+func (s *MemoryFirestore) Update(ctx context.Context, collectionPath, documentID string, updates map[string]interface{}, preconds ...Precondition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkUnchanged(collectionPath, documentID, preconds); err != nil {
+		return err
+	}
+	doc, ok := s.collections[collectionPath][documentID]
+	if !ok {
+		return ErrDocumentNotFound
+	}
+
+	data, err := applyUpdates(doc.data, updates)
+	if err != nil {
+		return err
+	}
+	doc.data = data
+	doc.updateTime = s.now(doc.updateTime)
+	return nil
+}
+
+// applyUpdates returns a copy of data with updates keyed by dotted field
+// paths applied, so a failed update leaves the document unchanged
+func applyUpdates(data, updates map[string]interface{}) (map[string]interface{}, error) {
+	data = copyData(data)
+	for key, value := range updates {
+		path, err := splitFieldPath(key)
+		if err != nil {
+			return nil, err
+		}
+		if value == firestore.Delete {
+			deleteFieldPath(data, path)
+			continue
+		}
+		encoded, err := encodeValue(value)
+		if err != nil {
+			return nil, err
+		}
+		setFieldPath(data, path, encoded)
+	}
+	return data, nil
+}
+
+// Get retrieves a document
+func (s *MemoryFirestore) Get(ctx context.Context, collectionPath, documentID string, dest interface{}) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc, ok := s.collections[collectionPath][documentID]
+	if !ok {
+		return ErrDocumentNotFound
+	}
+	return decodeData(doc.data, dest)
+}
+
+// GetDocument retrieves a copy of a document along with its create and update times
+func (s *MemoryFirestore) GetDocument(ctx context.Context, collectionPath, documentID string) (Document, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc, ok := s.collections[collectionPath][documentID]
+	if !ok {
+		return Document{}, ErrDocumentNotFound
+	}
+	return Document{
+		ID:         documentID,
+		Path:       collectionPath + "/" + documentID,
+		Data:       copyData(doc.data),
+		CreateTime: doc.createTime,
+		UpdateTime: doc.updateTime,
+	}, nil
+}
+
+// Delete removes a document, which must still have the update time expected
+// by preconds when they're given. Deleting a missing document is not an error.
+func (s *MemoryFirestore) Delete(ctx context.Context, collectionPath, documentID string, preconds ...Precondition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkUnchanged(collectionPath, documentID, preconds); err != nil {
+		return err
+	}
+	delete(s.collections[collectionPath], documentID)
+	return nil
+}
+
+// BatchDelete removes documents by path, MaxBatchSize at a time
+func (s *MemoryFirestore) BatchDelete(ctx context.Context, documentPaths []string) error {
+	return batchDelete(ctx, s, documentPaths)
+}
+
+// CommitBatch applies up to MaxBatchSize writes atomically
+func (s *MemoryFirestore) CommitBatch(ctx context.Context, writes []Write) error {
+	if err := checkBatch(writes); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.applyWrites(writes)
+}
+
+// RunTransaction runs fn with a transaction whose writes apply atomically,
+// provided none of the documents it read have changed since. Otherwise fn
+// runs again, up to MaxTransactionAttempts times, like Firestore does.
+func (s *MemoryFirestore) RunTransaction(ctx context.Context, fn func(tx Tx) error) error {
+	for attempt := 0; attempt < MaxTransactionAttempts; attempt++ {
+		tx := &memoryTx{store: s, reads: make(map[string]time.Time)}
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		if s.changedSince(tx.reads) {
+			s.mu.Unlock()
+			continue
+		}
+		err := s.applyWrites(tx.writes)
+		s.mu.Unlock()
+		return err
+	}
+	return fmt.Errorf("transaction failed after %d attempts: the documents it read kept changing", MaxTransactionAttempts)
+}
+
+// changedSince reports whether any document was written after the update
+// time it was read with, zero for documents that didn't exist. It must be
+// called with the lock held.
+func (s *MemoryFirestore) changedSince(reads map[string]time.Time) bool {
+	for path, read := range reads {
+		collection, id, _ := SplitDocumentPath(path)
+		var actual time.Time
+		if doc, ok := s.collections[collection][id]; ok {
+			actual = doc.updateTime
+		}
+		if !actual.Equal(read) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyWrites applies writes in order, either all of them or, when one
+// fails, none. It must be called with the lock held.
+func (s *MemoryFirestore) applyWrites(writes []Write) error {
+	// Stage the data each write leaves, nil for deleted documents
+	staged := make(map[string]map[string]interface{})
+	var order []string
+	current := func(path string) (map[string]interface{}, bool) {
+		if data, ok := staged[path]; ok {
+			return data, data != nil
+		}
+		collection, id, _ := SplitDocumentPath(path)
+		doc, ok := s.collections[collection][id]
+		if !ok {
+			return nil, false
+		}
+		return doc.data, true
+	}
+
+	for _, w := range writes {
+		collection, id, err := SplitDocumentPath(w.Path)
+		if err != nil {
+			return err
+		}
+		path := collection + "/" + id
+
+		var data map[string]interface{}
+		switch w.Kind {
+		case SetWrite:
+			encoded, err := encodeData(w.Data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", w.Path, err)
+			}
+			data = encoded
+		case UpdateWrite:
+			existing, ok := current(path)
+			if !ok {
+				return fmt.Errorf("%s: %w", w.Path, ErrDocumentNotFound)
+			}
+			updated, err := applyUpdates(existing, w.Updates)
+			if err != nil {
+				return fmt.Errorf("%s: %w", w.Path, err)
+			}
+			data = updated
+		}
+		if _, ok := staged[path]; !ok {
+			order = append(order, path)
+		}
+		staged[path] = data
+	}
+
+	for _, path := range order {
+		collection, id, _ := SplitDocumentPath(path)
+		if staged[path] == nil {
+			delete(s.collections[collection], id)
+			continue
+		}
+		s.put(collection, id, staged[path])
+	}
+	return nil
+}
+
+// memoryTx is a Tx that records what it reads and buffers its writes until
+// the transaction commits
+type memoryTx struct {
+	store  *MemoryFirestore
+	reads  map[string]time.Time
+	writes []Write
+}
+
+// Get reads a document, remembering its update time
+func (t *memoryTx) Get(collectionPath, documentID string) (Document, error) {
+	if len(t.writes) > 0 {
+		return Document{}, ErrReadAfterWrite
+	}
+	doc, err := t.store.GetDocument(context.Background(), collectionPath, documentID)
+	if err != nil && err != ErrDocumentNotFound {
+		return Document{}, err
+	}
+	t.reads[collectionPath+"/"+documentID] = doc.UpdateTime
+	return doc, err
+}
+
+// Set creates or overwrites a document when the transaction commits
+func (t *memoryTx) Set(collectionPath, documentID string, data interface{}) error {
+	return t.write(Write{Kind: SetWrite, Path: collectionPath + "/" + documentID, Data: data})
+}
+
+// Update updates specific fields of a document when the transaction commits
+func (t *memoryTx) Update(collectionPath, documentID string, updates map[string]interface{}) error {
+	return t.write(Write{Kind: UpdateWrite, Path: collectionPath + "/" + documentID, Updates: updates})
+}
+
+// Delete removes a document when the transaction commits
+func (t *memoryTx) Delete(collectionPath, documentID string) error {
+	return t.write(Write{Kind: DeleteWrite, Path: collectionPath + "/" + documentID})
+}
+
+// write buffers a write once its path is known to be a document path
+func (t *memoryTx) write(w Write) error {
+	if _, _, err := SplitDocumentPath(w.Path); err != nil {
+		return err
+	}
+	t.writes = append(t.writes, w)
+	return nil
+}
+
+// List retrieves all documents in a collection ordered by document ID
+func (s *MemoryFirestore) List(ctx context.Context, collectionPath string) ([]map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var results []map[string]interface{}
+	for _, id := range s.documentIDs(collectionPath) {
+		data := copyData(s.collections[collectionPath][id].data)
+		data["id"] = id
+		results = append(results, data)
+	}
+	return results, nil
+}
+
+// ListCollections returns the IDs of the subcollections of a document, or of
+// the root collections when documentPath is empty, in sorted order. As in
+// Firestore, a collection is listed while any document is stored below it.
+func (s *MemoryFirestore) ListCollections(ctx context.Context, documentPath string) ([]string, error) {
+	prefix := ""
+	if documentPath != "" {
+		if _, _, err := SplitDocumentPath(documentPath); err != nil {
+			return nil, err
+		}
+		prefix = strings.Trim(documentPath, "/") + "/"
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	for path, docs := range s.collections {
+		if len(docs) == 0 || !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		id, _, _ := strings.Cut(strings.TrimPrefix(path, prefix), "/")
+		seen[id] = true
+	}
+
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Reference returns a reference to the document at documentPath, for storing
+// in a field. References are rooted in the emulators' default project.
+func (s *MemoryFirestore) Reference(documentPath string) (*firestore.DocumentRef, error) {
+	_, id, err := SplitDocumentPath(documentPath)
+	if err != nil {
+		return nil, err
+	}
+	root := "projects/" + defaultEmulatorProjectID + "/databases/(default)/documents/"
+	return &firestore.DocumentRef{ID: id, Path: root + strings.Trim(documentPath, "/")}, nil
+}
+
+// Query executes a query and returns the matching documents
+func (s *MemoryFirestore) Query(ctx context.Context, q Query) ([]Document, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Collect the documents that match every filter
+	var results []Document
+	for path, docs := range s.collections {
+		if !queryCovers(q, path) {
+			continue
+		}
+		for id, doc := range docs {
+			matched, err := matchFilters(doc.data, q.Filters)
+			if err != nil {
+				return nil, err
+			}
+			if !matched || !hasOrderFields(doc.data, q.Orders) {
+				continue
+			}
+			results = append(results, Document{
+				ID:         id,
+				Path:       path + "/" + id,
+				Data:       doc.data,
+				CreateTime: doc.createTime,
+				UpdateTime: doc.updateTime,
+			})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return compareDocuments(results[i], results[j], q.Orders) < 0
+	})
+
+	// Skip everything up to and including the cursor
+	if len(q.StartAfter) > 0 {
+		start := len(results)
+		for i, doc := range results {
+			if compareCursor(doc, q) > 0 {
+				start = i
+				break
+			}
+		}
+		results = results[start:]
+	}
+
+	if q.LimitTo > 0 && len(results) > q.LimitTo {
+		results = results[:q.LimitTo]
+	}
+
+	// Hand out copies restricted to the selected fields
+	for i := range results {
+		results[i].Data = selectFields(results[i].Data, q.Fields)
+	}
+
+	return results, nil
+}
+
+// QueryEach executes a query and calls fn with each matching document,
+// stopping at the first error
+func (s *MemoryFirestore) QueryEach(ctx context.Context, q Query, fn func(Document) error) error {
+	results, err := s.Query(ctx, q)
+	if err != nil {
+		return err
+	}
+	for _, d := range results {
+		if err := fn(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// put stores document data, keeping the create time of an existing document.
+// Callers must hold the write lock.
+func (s *MemoryFirestore) put(collectionPath, documentID string, data map[string]interface{}) {
+	docs, ok := s.collections[collectionPath]
+	if !ok {
+		docs = make(map[string]*memoryDocument)
+		s.collections[collectionPath] = docs
+	}
+
+	if doc, ok := docs[documentID]; ok {
+		doc.data = data
+		doc.updateTime = s.now(doc.updateTime)
+		return
+	}
+	now := s.now(time.Time{})
+	docs[documentID] = &memoryDocument{data: data, createTime: now, updateTime: now}
+}
+
+// now returns the current time, strictly after previous so that every write
+// produces a distinct update time
+func (s *MemoryFirestore) now(previous time.Time) time.Time {
+	now := time.Now().UTC()
+	if !now.After(previous) {
+		now = previous.Add(time.Microsecond)
+	}
+	return now
+}
+
+// checkUnchanged returns a *ConflictError when a document no longer has the
+// update time expected by preconds
+func (s *MemoryFirestore) checkUnchanged(collectionPath, documentID string, preconds []Precondition) error {
+	expected, ok := expectedUpdate(preconds)
+	if !ok {
+		return nil
+	}
+	var actual time.Time
+	if doc, ok := s.collections[collectionPath][documentID]; ok {
+		actual = doc.updateTime
+	}
+	if !actual.Equal(expected) {
+		return &ConflictError{Path: collectionPath + "/" + documentID, Expected: expected, Actual: actual}
+	}
+	return nil
+}
+
+// documentIDs returns the sorted IDs of a collection's documents
+func (s *MemoryFirestore) documentIDs(path string) []string {
+	ids := make([]string, 0, len(s.collections[path]))
+	for id := range s.collections[path] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// queryCovers reports whether a query reads the collection at path
+func queryCovers(q Query, path string) bool {
+	if !q.AllDescendants {
+		return path == q.Collection
+	}
+	return path == q.Collection || strings.HasSuffix(path, "/"+q.Collection)
+}
+
+// matchFilters reports whether document data satisfies every filter
+func matchFilters(data map[string]interface{}, filters []Filter) (bool, error) {
+	for _, f := range filters {
+		ok, err := matchFilter(data, f)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// hasOrderFields reports whether data has every order by field; Firestore
+// leaves out documents missing any of them
+func hasOrderFields(data map[string]interface{}, orders []Order) bool {
+	for _, o := range orders {
+		if _, ok := getFieldPath(data, queryFieldPath(o.Path)); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// compareDocuments orders documents by the order by clauses, then by path
+func compareDocuments(a, b Document, orders []Order) int {
+	for _, o := range orders {
+		av, _ := getFieldPath(a.Data, queryFieldPath(o.Path))
+		bv, _ := getFieldPath(b.Data, queryFieldPath(o.Path))
+		c := compareValues(av, bv)
+		if o.Direction == Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a.Path, b.Path)
+}
+
+// compareCursor compares a document with the query's start after cursor
+func compareCursor(doc Document, q Query) int {
+	if len(q.Orders) == 0 {
+		cursor := fmt.Sprint(q.StartAfter[0])
+		if q.AllDescendants {
+			return strings.Compare(doc.Path, cursor)
+		}
+		return strings.Compare(doc.ID, cursor)
+	}
+
+	for i, value := range q.StartAfter {
+		o := q.Orders[i]
+		field, _ := getFieldPath(doc.Data, queryFieldPath(o.Path))
+		cursor, _ := encodeValue(value)
+		c := compareValues(field, cursor)
+		if o.Direction == Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// selectFields copies data, keeping only the given fields when any are given
+func selectFields(data map[string]interface{}, fields []string) map[string]interface{} {
+	if len(fields) == 0 {
+		return copyData(data)
+	}
+	out := make(map[string]interface{})
+	for _, field := range fields {
+		path := queryFieldPath(field)
+		if value, ok := getFieldPath(data, path); ok {
+			copied, _ := encodeValue(value)
+			setFieldPath(out, path, copied)
+		}
+	}
+	return out
+}
+
+// copyData deep-copies document data
+func copyData(data map[string]interface{}) map[string]interface{} {
+	copied, _ := encodeValue(data)
+	return copied.(map[string]interface{})
+}
+
+// getFieldPath looks up a dotted field path in document data
+func getFieldPath(data map[string]interface{}, path []string) (interface{}, bool) {
+	value, ok := data[path[0]]
+	if !ok || len(path) == 1 {
+		return value, ok
+	}
+	nested, isMap := value.(map[string]interface{})
+	if !isMap {
+		return nil, false
+	}
+	return getFieldPath(nested, path[1:])
+}
+
+// setFieldPath sets a dotted field path, creating intermediate maps
+func setFieldPath(data map[string]interface{}, path []string, value interface{}) {
+	if len(path) == 1 {
+		data[path[0]] = value
+		return
+	}
+	nested, ok := data[path[0]].(map[string]interface{})
+	if !ok {
+		nested = make(map[string]interface{})
+		data[path[0]] = nested
+	}
+	setFieldPath(nested, path[1:], value)
+}
+
+// deleteFieldPath removes a dotted field path if it exists
+func deleteFieldPath(data map[string]interface{}, path []string) {
+	if len(path) == 1 {
+		delete(data, path[0])
+		return
+	}
+	if nested, ok := data[path[0]].(map[string]interface{}); ok {
+		deleteFieldPath(nested, path[1:])
+	}
+}
+
+// matchFilter reports whether a document satisfies a filter
+func matchFilter(doc map[string]interface{}, f Filter) (bool, error) {
+	value, ok := getFieldPath(doc, queryFieldPath(f.Path))
+	want, err := encodeValue(f.Value)
+	if err != nil {
+		return false, err
+	}
+
+	switch f.Op {
+	case "==":
+		return ok && compareValues(value, want) == 0, nil
+	case "!=":
+		// Like not-in, != only matches documents where the field is set and not null
+		return ok && value != nil && compareValues(value, want) != 0, nil
+	case "<":
+		return ok && sameType(value, want) && compareValues(value, want) < 0, nil
+	case "<=":
+		return ok && sameType(value, want) && compareValues(value, want) <= 0, nil
+	case ">":
+		return ok && sameType(value, want) && compareValues(value, want) > 0, nil
+	case ">=":
+		return ok && sameType(value, want) && compareValues(value, want) >= 0, nil
+	case "in", "not-in":
+		candidates, isList := want.([]interface{})
+		if !isList {
+			return false, fmt.Errorf("%q filter requires a slice value", f.Op)
+		}
+		found := false
+		for _, candidate := range candidates {
+			if ok && compareValues(value, candidate) == 0 {
+				found = true
+				break
+			}
+		}
+		if f.Op == "in" {
+			return found, nil
+		}
+		return ok && value != nil && !found, nil
+	case "array-contains", "array-contains-any":
+		items, isList := value.([]interface{})
+		if !ok || !isList {
+			return false, nil
+		}
+		candidates := []interface{}{want}
+		if f.Op == "array-contains-any" {
+			list, isList := want.([]interface{})
+			if !isList {
+				return false, fmt.Errorf("%q filter requires a slice value", f.Op)
+			}
+			candidates = list
+		}
+		for _, item := range items {
+			for _, candidate := range candidates {
+				if compareValues(item, candidate) == 0 {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}
+
+	return false, fmt.Errorf("unsupported filter operator %q", f.Op)
+}
+
+// typeOrder ranks values by type, following Firestore's cross-type ordering
+func typeOrder(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case int64, float64:
+		return 2
+	case time.Time:
+		return 3
+	case string:
+		return 4
+	case []byte:
+		return 5
+	case *firestore.DocumentRef:
+		return 6
+	case []interface{}:
+		return 8
+	case map[string]interface{}:
+		return 9
+	}
+	return 7
+}
+
+// sameType reports whether a and b are of the same Firestore type, which
+// range filters require
+func sameType(a, b interface{}) bool {
+	return typeOrder(a) == typeOrder(b)
+}
+
+// compareValues orders two encoded values the way Firestore does
+func compareValues(a, b interface{}) int {
+	if ta, tb := typeOrder(a), typeOrder(b); ta != tb {
+		return ta - tb
+	}
+
+	switch av := a.(type) {
+	case nil:
+		return 0
+	case bool:
+		bv := b.(bool)
+		if av == bv {
+			return 0
+		}
+		if !av {
+			return -1
+		}
+		return 1
+	case int64, float64:
+		return compareNumbers(toFloat(a), toFloat(b))
+	case time.Time:
+		return av.Compare(b.(time.Time))
+	case string:
+		return strings.Compare(av, b.(string))
+	case []byte:
+		return strings.Compare(string(av), string(b.([]byte)))
+	case *firestore.DocumentRef:
+		return strings.Compare(av.Path, b.(*firestore.DocumentRef).Path)
+	case []interface{}:
+		bv := b.([]interface{})
+		for i := 0; i < len(av) && i < len(bv); i++ {
+			if c := compareValues(av[i], bv[i]); c != 0 {
+				return c
+			}
+		}
+		return len(av) - len(bv)
+	case map[string]interface{}:
+		// Maps compare entry by entry in key order, as in Firestore
+		bv := b.(map[string]interface{})
+		ak, bk := sortedKeys(av), sortedKeys(bv)
+		for i := 0; i < len(ak) && i < len(bk); i++ {
+			if c := strings.Compare(ak[i], bk[i]); c != 0 {
+				return c
+			}
+			if c := compareValues(av[ak[i]], bv[bk[i]]); c != 0 {
+				return c
+			}
+		}
+		return len(ak) - len(bk)
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func toFloat(v interface{}) float64 {
+	if n, ok := v.(int64); ok {
+		return float64(n)
+	}
+	return v.(float64)
+}
+
+func compareNumbers(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// randomID generates an alphanumeric ID like the ones Firebase assigns
+func randomID(n int) string {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b)
+}
@@ -0,0 +1,461 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testFixture = `{
+  "users": [
+    {"uid": "user-a", "email": "a@example.com", "displayName": "User A", "providers": ["password"], "createdAt": "2024-01-01T00:00:00Z"},
+    {"uid": "user-b", "email": "b@example.com", "displayName": "User B", "disabled": true, "createdAt": "2024-02-01T00:00:00Z"},
+    {"uid": "user-c", "email": "c@example.com", "displayName": "User C", "createdAt": "2024-03-01T00:00:00Z"}
+  ],
+  "collections": {
+    "routines": {
+      "r1": {"name": "Push", "uid": "user-a", "exercises": ["e1", "e2"], "createdAt": "2024-01-02T00:00:00Z"},
+      "r2": {"name": "Pull", "uid": "user-a", "exercises": ["e3"], "createdAt": "2024-01-03T00:00:00Z"},
+      "r3": {"name": "Legs", "uid": "user-b", "exercises": [], "createdAt": "2024-02-02T00:00:00Z"}
+    },
+    "profiles/p1/records": {
+      "rec1": {"name": "Bench Press", "weight": 80.5, "reps": 5}
+    }
+  }
+}`
+
+func loadTestFixture(t *testing.T) (*MemoryAuth, *MemoryFirestore) {
+	t.Helper()
+	authStore, docStore, err := LoadFixture(strings.NewReader(testFixture))
+	if err != nil {
+		t.Fatalf("LoadFixture() error = %v", err)
+	}
+	return authStore, docStore
+}
+
+func TestLoadDemoFixture(t *testing.T) {
+	authStore, docStore, err := LoadDemoFixture()
+	if err != nil {
+		t.Fatalf("LoadDemoFixture() error = %v", err)
+	}
+
+	page, err := authStore.ListUsers(context.Background(), 1000, "")
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+	if len(page.Users) == 0 {
+		t.Error("Expected demo fixture to contain users")
+	}
+
+	routines, err := docStore.List(context.Background(), "routines")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(routines) == 0 {
+		t.Error("Expected demo fixture to contain routines")
+	}
+}
+
+func TestMemoryAuthListUsersPagination(t *testing.T) {
+	authStore, _ := loadTestFixture(t)
+	ctx := context.Background()
+
+	first, err := authStore.ListUsers(ctx, 2, "")
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+	if len(first.Users) != 2 || first.NextPageToken == "" {
+		t.Fatalf("Expected a full first page with a next token, got %d users, token %q", len(first.Users), first.NextPageToken)
+	}
+
+	second, err := authStore.ListUsers(ctx, 2, first.NextPageToken)
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+	if len(second.Users) != 1 || second.Users[0].UID != "user-c" {
+		t.Fatalf("Expected second page to hold user-c only, got %d users", len(second.Users))
+	}
+	if second.NextPageToken != "" {
+		t.Errorf("Expected no next page token, got %q", second.NextPageToken)
+	}
+}
+
+func TestMemoryAuthCRUD(t *testing.T) {
+	authStore, _ := loadTestFixture(t)
+	ctx := context.Background()
+
+	uid, err := authStore.CreateUser(ctx, &UserToCreate{Email: "new@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	if _, err := authStore.CreateUser(ctx, &UserToCreate{Email: "NEW@example.com"}); !errors.Is(err, ErrEmailExists) {
+		t.Errorf("Expected duplicate email to be rejected with ErrEmailExists, got %v", err)
+	}
+
+	if err := authStore.UpdateUser(ctx, uid, &UserToUpdate{DisplayName: String("New User"), Disabled: Bool(true)}); err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+
+	user, err := authStore.GetUser(ctx, uid)
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+	if user.DisplayName != "New User" || !user.Disabled {
+		t.Errorf("Update not applied, got display name %q, disabled %v", user.DisplayName, user.Disabled)
+	}
+
+	if byEmail, err := authStore.GetUserByEmail(ctx, "New@Example.com"); err != nil || byEmail.UID != uid {
+		t.Errorf("GetUserByEmail() = %v, %v, want uid %q", byEmail, err, uid)
+	}
+
+	// Mutating a returned record must not change the store
+	user.DisplayName = "Changed"
+	again, _ := authStore.GetUser(ctx, uid)
+	if again.DisplayName != "New User" {
+		t.Error("GetUser returned a record sharing state with the store")
+	}
+
+	before := time.Now().Unix() * 1000
+	if err := authStore.RevokeRefreshTokens(ctx, uid); err != nil {
+		t.Fatalf("RevokeRefreshTokens() error = %v", err)
+	}
+	if again, _ := authStore.GetUser(ctx, uid); again.TokensValidAfterMillis < before {
+		t.Errorf("Expected tokens to be revoked, valid after %d", again.TokensValidAfterMillis)
+	}
+
+	if err := authStore.DeleteUser(ctx, uid); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if _, err := authStore.GetUser(ctx, uid); err == nil {
+		t.Error("Expected deleted user to be gone")
+	}
+
+	// Every operation on a missing user reports ErrUserNotFound
+	for name, err := range map[string]error{
+		"UpdateUser":          authStore.UpdateUser(ctx, uid, &UserToUpdate{DisplayName: String("Gone")}),
+		"DeleteUser":          authStore.DeleteUser(ctx, uid),
+		"RevokeRefreshTokens": authStore.RevokeRefreshTokens(ctx, uid),
+		"SetCustomUserClaims": authStore.SetCustomUserClaims(ctx, uid, map[string]interface{}{"admin": true}),
+	} {
+		if !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("%s: expected ErrUserNotFound, got %v", name, err)
+		}
+	}
+}
+
+func TestMemoryAuthEmailLinks(t *testing.T) {
+	authStore, _ := loadTestFixture(t)
+	ctx := context.Background()
+	settings := &ActionCodeSettings{ContinueURL: "https://app.example.com/done"}
+
+	link, err := authStore.PasswordResetLink(ctx, "a@example.com", settings)
+	if err != nil {
+		t.Fatalf("PasswordResetLink() error = %v", err)
+	}
+	if !strings.Contains(link, "mode=resetPassword") || !strings.Contains(link, "continueUrl=https%3A%2F%2Fapp.example.com%2Fdone") {
+		t.Errorf("Unexpected password reset link %q", link)
+	}
+
+	if link, err := authStore.EmailVerificationLink(ctx, "b@example.com", nil); err != nil || strings.Contains(link, "continueUrl") {
+		t.Errorf("EmailVerificationLink() = %q, %v, want a link without a continue URL", link, err)
+	}
+
+	if _, err := authStore.EmailSignInLink(ctx, "a@example.com", nil); !errors.Is(err, ErrContinueURLRequired) {
+		t.Errorf("Expected sign-in links without a continue URL to fail with ErrContinueURLRequired, got %v", err)
+	}
+	if _, err := authStore.PasswordResetLink(ctx, "nobody@example.com", nil); err == nil {
+		t.Error("Expected no link for an unknown email")
+	}
+}
+
+func TestMemoryFirestoreDocuments(t *testing.T) {
+	_, docStore := loadTestFixture(t)
+	ctx := context.Background()
+
+	type routine struct {
+		Name      string    `firestore:"name"`
+		UID       string    `firestore:"uid"`
+		Exercises []string  `firestore:"exercises"`
+		CreatedAt time.Time `firestore:"createdAt"`
+	}
+
+	var r routine
+	if err := docStore.Get(ctx, "routines", "r1", &r); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if r.Name != "Push" || len(r.Exercises) != 2 || r.CreatedAt.IsZero() {
+		t.Errorf("Unexpected routine %+v", r)
+	}
+
+	if err := docStore.Update(ctx, "routines", "r1", map[string]interface{}{"meta.version": 2}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	var data map[string]interface{}
+	if err := docStore.Get(ctx, "routines", "r1", &data); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if meta, ok := data["meta"].(map[string]interface{}); !ok || meta["version"] != int64(2) {
+		t.Errorf("Expected nested field to be set, got %v", data["meta"])
+	}
+
+	// A quoted field name keeps its dots
+	if err := docStore.Update(ctx, "routines", "r1", map[string]interface{}{QuoteField("meta.version"): 3}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	data = nil
+	_ = docStore.Get(ctx, "routines", "r1", &data)
+	if meta := data["meta"].(map[string]interface{}); data["meta.version"] != int64(3) || meta["version"] != int64(2) {
+		t.Errorf("Expected a top-level field named meta.version, got %v", data)
+	}
+
+	if err := docStore.Update(ctx, "routines", "missing", map[string]interface{}{"name": "x"}); err != ErrDocumentNotFound {
+		t.Errorf("Expected ErrDocumentNotFound, got %v", err)
+	}
+
+	id, err := docStore.Create(ctx, "routines", routine{Name: "Core", UID: "user-c"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := docStore.Delete(ctx, "routines", id); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := docStore.Get(ctx, "routines", id, &r); err != ErrDocumentNotFound {
+		t.Errorf("Expected ErrDocumentNotFound after delete, got %v", err)
+	}
+
+	records, err := docStore.List(ctx, "profiles/p1/records")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(records) != 1 || records[0]["id"] != "rec1" || records[0]["reps"] != int64(5) {
+		t.Errorf("Unexpected subcollection contents %v", records)
+	}
+}
+
+func TestMemoryFirestoreQuery(t *testing.T) {
+	_, docStore := loadTestFixture(t)
+	ctx := context.Background()
+
+	routines := From("routines")
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"no filters", routines, []string{"r1", "r2", "r3"}},
+		{"equality", routines.Where("uid", "==", "user-a"), []string{"r1", "r2"}},
+		{"combined", routines.Where("uid", "==", "user-a").Where("name", "==", "Pull"), []string{"r2"}},
+		{"range on timestamps", routines.Where("createdAt", ">", time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)), []string{"r2", "r3"}},
+		{"in", routines.Where("name", "in", []string{"Legs", "Push"}), []string{"r1", "r3"}},
+		{"array-contains", routines.Where("exercises", "array-contains", "e3"), []string{"r2"}},
+		{"order by desc", routines.OrderBy("createdAt", Desc), []string{"r3", "r2", "r1"}},
+		{"limit", routines.OrderBy("name", Asc).Limit(2), []string{"r3", "r2"}},
+		{"start after", routines.OrderBy("name", Asc).After("Pull"), []string{"r1"}},
+		{"start after document ID", routines.After("r1"), []string{"r2", "r3"}},
+		{"order by missing field", routines.OrderBy("archived", Asc), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := docStore.Query(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+
+			var got []string
+			for _, doc := range docs {
+				got = append(got, doc.ID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Query() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// QueryEach stops at the first error
+	var seen []string
+	stop := errors.New("stop")
+	err := docStore.QueryEach(ctx, routines, func(d Document) error {
+		seen = append(seen, d.ID)
+		if d.ID == "r2" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || strings.Join(seen, ",") != "r1,r2" {
+		t.Errorf("QueryEach() saw %v, %v", seen, err)
+	}
+
+	// Quoted field names with dots filter, order and select top-level fields
+	dotted := QuoteField("meta.version")
+	_ = docStore.Set(ctx, "notes", "n1", map[string]interface{}{"meta.version": 2, "meta": map[string]interface{}{"version": 9}})
+	_ = docStore.Set(ctx, "notes", "n2", map[string]interface{}{"meta.version": 1})
+	docs, err := docStore.Query(ctx, From("notes").Where(dotted, ">", 0).OrderBy(dotted, Asc).Select(dotted))
+	if err != nil || len(docs) != 2 || docs[0].ID != "n2" {
+		t.Fatalf("Query() = %v, %v", docs, err)
+	}
+	if len(docs[1].Data) != 1 || docs[1].Data["meta.version"] != int64(2) {
+		t.Errorf("Expected only the top-level meta.version field, got %v", docs[1].Data)
+	}
+	// != and not-in skip documents where the field is missing or null
+	_ = docStore.Set(ctx, "notes", "n3", map[string]interface{}{"meta.version": nil})
+	_ = docStore.Set(ctx, "notes", "n4", map[string]interface{}{"title": "no version"})
+	for _, op := range []string{"!=", "not-in"} {
+		var value interface{} = 1
+		if op == "not-in" {
+			value = []interface{}{1}
+		}
+		docs, err := docStore.Query(ctx, From("notes").Where(dotted, op, value))
+		if err != nil || len(docs) != 1 || docs[0].ID != "n1" {
+			t.Errorf("Query(%s) = %v, %v, want only n1", op, docs, err)
+		}
+	}
+	if _, err := docStore.Query(ctx, From("notes").Where("`meta", "==", 1)); err == nil {
+		t.Error("Expected an error for an unterminated quoted field name")
+	}
+}
+
+func TestCompareValuesMaps(t *testing.T) {
+	type m = map[string]interface{}
+	tests := []struct {
+		a, b m
+		want int
+	}{
+		{m{"a": int64(1), "b": int64(2)}, m{"b": int64(1)}, -1},
+		{m{"a": int64(1)}, m{"a": int64(1), "b": int64(0)}, -1},
+		{m{"a": int64(2)}, m{"a": int64(1), "z": int64(0)}, 1},
+		{m{"a": int64(1), "b": int64(1)}, m{"b": int64(1), "a": int64(1)}, 0},
+	}
+	for _, tt := range tests {
+		// Go randomizes map iteration, so an unsorted comparison shows up over a few runs
+		for i := 0; i < 20; i++ {
+			got := compareValues(tt.a, tt.b)
+			if (got > 0) != (tt.want > 0) || (got < 0) != (tt.want < 0) {
+				t.Fatalf("compareValues(%v, %v) = %d, want sign of %d", tt.a, tt.b, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestMemoryFirestoreCollectionGroupAndSelect(t *testing.T) {
+	_, docStore := loadTestFixture(t)
+	ctx := context.Background()
+
+	docs, err := docStore.Query(ctx, CollectionGroup("records").Where("weight", ">", 50).Select("name"))
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("Expected one record, got %d", len(docs))
+	}
+	if docs[0].Path != "profiles/p1/records/rec1" {
+		t.Errorf("Unexpected document path %q", docs[0].Path)
+	}
+	if _, ok := docs[0].Data["weight"]; ok || docs[0].Data["name"] != "Bench Press" {
+		t.Errorf("Expected only the selected field, got %v", docs[0].Data)
+	}
+
+	var record struct {
+		Name string `firestore:"name"`
+	}
+	if err := docs[0].DataTo(&record); err != nil || record.Name != "Bench Press" {
+		t.Errorf("DataTo() = %+v, %v", record, err)
+	}
+}
+
+func TestMemoryFirestoreListCollections(t *testing.T) {
+	_, docStore := loadTestFixture(t)
+	ctx := context.Background()
+
+	// Collections holding only subcollections are listed, as in Firestore
+	root, err := docStore.ListCollections(ctx, "")
+	if err != nil {
+		t.Fatalf("ListCollections() error = %v", err)
+	}
+	if strings.Join(root, ",") != "profiles,routines" {
+		t.Errorf("Unexpected root collections %v", root)
+	}
+
+	subs, err := docStore.ListCollections(ctx, "profiles/p1")
+	if err != nil || strings.Join(subs, ",") != "records" {
+		t.Errorf("ListCollections(profiles/p1) = %v, %v", subs, err)
+	}
+	if subs, err := docStore.ListCollections(ctx, "routines/r1"); err != nil || len(subs) != 0 {
+		t.Errorf("Expected no subcollections of routines/r1, got %v, %v", subs, err)
+	}
+	if _, err := docStore.ListCollections(ctx, "routines"); err == nil {
+		t.Error("Expected an error for a collection path")
+	}
+
+	// Collections whose documents are all deleted are no longer listed
+	if err := docStore.Delete(ctx, "profiles/p1/records", "rec1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if root, _ := docStore.ListCollections(ctx, ""); strings.Join(root, ",") != "routines" {
+		t.Errorf("Expected only routines after deleting the last record, got %v", root)
+	}
+}
+
+func TestMemoryFirestorePreconditions(t *testing.T) {
+	_, docStore := loadTestFixture(t)
+	ctx := context.Background()
+
+	doc, err := docStore.GetDocument(ctx, "routines", "r1")
+	if err != nil {
+		t.Fatalf("GetDocument() error = %v", err)
+	}
+	if doc.Path != "routines/r1" || doc.Data["name"] != "Push" || doc.UpdateTime.IsZero() {
+		t.Errorf("Unexpected document %+v", doc)
+	}
+	if _, err := docStore.GetDocument(ctx, "routines", "missing"); err != ErrDocumentNotFound {
+		t.Errorf("Expected ErrDocumentNotFound, got %v", err)
+	}
+
+	// The first update matches the time read, the second one no longer does
+	read := LastUpdateTime(doc.UpdateTime)
+	if err := docStore.Update(ctx, "routines", "r1", map[string]interface{}{"name": "Push A"}, read); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	updated, _ := docStore.GetDocument(ctx, "routines", "r1")
+
+	for name, write := range map[string]func() error{
+		"Update": func() error {
+			return docStore.Update(ctx, "routines", "r1", map[string]interface{}{"name": "Push B"}, read)
+		},
+		"Set": func() error {
+			return docStore.Set(ctx, "routines", "r1", map[string]interface{}{"name": "Push B"}, read)
+		},
+		"Delete": func() error { return docStore.Delete(ctx, "routines", "r1", read) },
+	} {
+		var conflict *ConflictError
+		if err := write(); !errors.As(err, &conflict) {
+			t.Errorf("%s: expected a ConflictError, got %v", name, err)
+		} else if conflict.Path != "routines/r1" || !conflict.Expected.Equal(doc.UpdateTime) || !conflict.Actual.Equal(updated.UpdateTime) {
+			t.Errorf("%s: unexpected conflict %+v", name, conflict)
+		}
+	}
+	if current, _ := docStore.GetDocument(ctx, "routines", "r1"); current.Data["name"] != "Push A" || !current.UpdateTime.Equal(updated.UpdateTime) {
+		t.Errorf("Expected only the first update, got %v at %v", current.Data["name"], current.UpdateTime)
+	}
+
+	// Deleting with the current time succeeds, after which writes expecting it conflict
+	if err := docStore.Delete(ctx, "routines", "r1", LastUpdateTime(updated.UpdateTime)); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	var conflict *ConflictError
+	err = docStore.Set(ctx, "routines", "r1", map[string]interface{}{"name": "Push C"}, LastUpdateTime(updated.UpdateTime))
+	if !errors.As(err, &conflict) || !conflict.Actual.IsZero() || !strings.Contains(err.Error(), "deleted") {
+		t.Errorf("Expected a conflict for the deleted routine, got %v", err)
+	}
+
+	ref, err := docStore.Reference("exercises/e1")
+	if err != nil || ref.ID != "e1" || !strings.HasSuffix(ref.Path, "/documents/exercises/e1") {
+		t.Errorf("Reference() = %+v, %v", ref, err)
+	}
+	if _, err := docStore.Reference("exercises"); err == nil {
+		t.Error("Expected an error for a collection path")
+	}
+}
@@ -0,0 +1,180 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Profile is a document of the profiles collection. A user can have several
+// profiles, each with its own personal records.
+type Profile struct {
+	ID        string    `firestore:"-"`
+	Name      string    `firestore:"name"`
+	UID       string    `firestore:"uid"`
+	CreatedAt time.Time `firestore:"createdAt"`
+	UpdatedAt time.Time `firestore:"updatedAt"`
+}
+
+// Record is a personal record of a profile, stored in profiles/{id}/records
+type Record struct {
+	ID       string    `firestore:"-"`
+	Exercise string    `firestore:"exercise"` // the ID of the exercise
+	Name     string    `firestore:"name"`
+	Weight   float64   `firestore:"weight"`
+	Reps     int       `firestore:"reps"`
+	Date     time.Time `firestore:"date"`
+}
+
+// Exercise is a document of the exercises collection, defined by a user
+type Exercise struct {
+	ID        string    `firestore:"-"`
+	Name      string    `firestore:"name"`
+	UID       string    `firestore:"uid"`
+	CreatedAt time.Time `firestore:"createdAt"`
+	UpdatedAt time.Time `firestore:"updatedAt"`
+}
+
+// Workout is the workout a history records
+type Workout struct {
+	Name string    `firestore:"name"`
+	Date time.Time `firestore:"date"`
+}
+
+// History is a document of the histories collection, a finished workout
+type History struct {
+	ID        string    `firestore:"-"`
+	UID       string    `firestore:"uid"`
+	Workout   Workout   `firestore:"workout"`
+	CreatedAt time.Time `firestore:"createdAt"`
+	UpdatedAt time.Time `firestore:"updatedAt"`
+}
+
+// Routine is a document of the routines collection, an ordered list of exercises
+type Routine struct {
+	ID        string    `firestore:"-"`
+	Name      string    `firestore:"name"`
+	UID       string    `firestore:"uid"`
+	Exercises []string  `firestore:"exercises"` // the IDs of the exercises
+	CreatedAt time.Time `firestore:"createdAt"`
+	UpdatedAt time.Time `firestore:"updatedAt"`
+}
+
+// ProfileFrom converts a profile document to a Profile. Missing fields are
+// left empty; timestamps are read as time.Time, zero when unset.
+func ProfileFrom(d Document) (Profile, error) {
+	var p Profile
+	err := decodeModel(d, "profile", &p)
+	p.ID = d.ID
+	return p, err
+}
+
+// RecordFrom converts a record document to a Record
+func RecordFrom(d Document) (Record, error) {
+	var r Record
+	err := decodeModel(d, "record", &r)
+	r.ID = d.ID
+	return r, err
+}
+
+// ExerciseFrom converts an exercise document to an Exercise
+func ExerciseFrom(d Document) (Exercise, error) {
+	var e Exercise
+	err := decodeModel(d, "exercise", &e)
+	e.ID = d.ID
+	return e, err
+}
+
+// HistoryFrom converts a history document to a History
+func HistoryFrom(d Document) (History, error) {
+	var h History
+	err := decodeModel(d, "history", &h)
+	h.ID = d.ID
+	return h, err
+}
+
+// RoutineFrom converts a routine document to a Routine
+func RoutineFrom(d Document) (Routine, error) {
+	var r Routine
+	err := decodeModel(d, "routine", &r)
+	r.ID = d.ID
+	return r, err
+}
+
+// decodeModel copies document data into the model dest, naming the document
+// in errors
+func decodeModel(d Document, kind string, dest interface{}) error {
+	if err := d.DataTo(dest); err != nil {
+		return fmt.Errorf("invalid %s %s: %w", kind, d.Path, err)
+	}
+	return nil
+}
+
+// ProfilesByUser returns the profiles of a user
+func ProfilesByUser(ctx context.Context, docs DocumentStore, uid string) ([]Profile, error) {
+	return queryModels(ctx, docs, From("profiles").Where("uid", "==", uid), ProfileFrom)
+}
+
+// RecordsOfProfile returns the personal records of a profile
+func RecordsOfProfile(ctx context.Context, docs DocumentStore, profileID string) ([]Record, error) {
+	return queryModels(ctx, docs, From("profiles/"+profileID+"/records"), RecordFrom)
+}
+
+// ExercisesByUser returns the exercises a user has defined
+func ExercisesByUser(ctx context.Context, docs DocumentStore, uid string) ([]Exercise, error) {
+	return queryModels(ctx, docs, From("exercises").Where("uid", "==", uid), ExerciseFrom)
+}
+
+// HistoriesByUser returns the histories of a user
+func HistoriesByUser(ctx context.Context, docs DocumentStore, uid string) ([]History, error) {
+	return queryModels(ctx, docs, From("histories").Where("uid", "==", uid), HistoryFrom)
+}
+
+// RoutinesByUser returns the routines of a user
+func RoutinesByUser(ctx context.Context, docs DocumentStore, uid string) ([]Routine, error) {
+	return queryModels(ctx, docs, From("routines").Where("uid", "==", uid), RoutineFrom)
+}
+
+// ExercisesByID returns the exercises with the given IDs, keyed by ID.
+// Exercises that don't exist are left out.
+func ExercisesByID(ctx context.Context, docs DocumentStore, ids []string) (map[string]Exercise, error) {
+	exercises := make(map[string]Exercise, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		d, err := docs.GetDocument(ctx, "exercises", id)
+		if errors.Is(err, ErrDocumentNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch exercise %s: %w", id, err)
+		}
+		e, err := ExerciseFrom(d)
+		if err != nil {
+			return nil, err
+		}
+		exercises[id] = e
+	}
+	return exercises, nil
+}
+
+// queryModels runs a query and converts every document it returns with from
+func queryModels[T any](ctx context.Context, docs DocumentStore, q Query, from func(Document) (T, error)) ([]T, error) {
+	results, err := docs.Query(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", q.Collection, err)
+	}
+	models := make([]T, 0, len(results))
+	for _, d := range results {
+		m, err := from(d)
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, m)
+	}
+	return models, nil
+}
@@ -0,0 +1,105 @@
+package firebase
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestModelsByUser(t *testing.T) {
+	_, docStore, err := LoadDemoFixture()
+	if err != nil {
+		t.Fatalf("LoadDemoFixture() error = %v", err)
+	}
+	ctx := context.Background()
+	const uid = "3xKq9PzR1vT7bN2mYc5LwA8dFe4H"
+
+	routines, err := RoutinesByUser(ctx, docStore, uid)
+	if err != nil || len(routines) != 2 {
+		t.Fatalf("RoutinesByUser() = %d routines, %v", len(routines), err)
+	}
+	var push Routine
+	for _, r := range routines {
+		if r.ID == "rMayaPush" {
+			push = r
+		}
+	}
+	want := Routine{
+		ID:        "rMayaPush",
+		Name:      "Push Day",
+		UID:       uid,
+		Exercises: []string{"eBench01", "eOHP01"},
+		CreatedAt: time.Date(2024, 4, 11, 7, 5, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2025, 9, 1, 6, 0, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(push, want) {
+		t.Errorf("Routine = %+v, want %+v", push, want)
+	}
+
+	histories, err := HistoriesByUser(ctx, docStore, uid)
+	if err != nil || len(histories) != 2 {
+		t.Fatalf("HistoriesByUser() = %d histories, %v", len(histories), err)
+	}
+	for _, h := range histories {
+		if h.Workout.Name == "" || h.Workout.Date.IsZero() || h.CreatedAt.IsZero() {
+			t.Errorf("Expected a named and dated workout, got %+v", h)
+		}
+	}
+
+	profiles, err := ProfilesByUser(ctx, docStore, uid)
+	if err != nil || len(profiles) != 2 {
+		t.Errorf("ProfilesByUser() = %d profiles, %v", len(profiles), err)
+	}
+	exercises, err := ExercisesByUser(ctx, docStore, uid)
+	if err != nil || len(exercises) != 4 {
+		t.Errorf("ExercisesByUser() = %d exercises, %v", len(exercises), err)
+	}
+
+	records, err := RecordsOfProfile(ctx, docStore, "pMaya01")
+	if err != nil || len(records) != 3 {
+		t.Fatalf("RecordsOfProfile() = %d records, %v", len(records), err)
+	}
+	for _, r := range records {
+		if r.Exercise == "eBench01" && (r.Name != "Bench Press" || r.Weight != 70 || r.Reps != 5 || r.Date.IsZero()) {
+			t.Errorf("Unexpected bench press record %+v", r)
+		}
+	}
+
+	byID, err := ExercisesByID(ctx, docStore, []string{"eBench01", "missing", "eBench01"})
+	if err != nil || len(byID) != 1 || byID["eBench01"].Name != "Bench Press" {
+		t.Errorf("ExercisesByID() = %v, %v", byID, err)
+	}
+}
+
+func TestModelConversion(t *testing.T) {
+	docStore := NewMemoryFirestore()
+	ctx := context.Background()
+
+	// Models are written with their firestore tags, without their ID
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	routine := Routine{ID: "ignored", Name: "Legs", UID: "u1", Exercises: []string{"e1"}, CreatedAt: created, UpdatedAt: created}
+	if err := docStore.Set(ctx, "routines", "r1", routine); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	d, _ := docStore.GetDocument(ctx, "routines", "r1")
+	if _, ok := d.Data["ID"]; ok || d.Data["createdAt"] != created {
+		t.Errorf("Unexpected routine data %v", d.Data)
+	}
+	read, err := RoutineFrom(d)
+	routine.ID = "r1"
+	if err != nil || !reflect.DeepEqual(read, routine) {
+		t.Errorf("RoutineFrom() = %+v, %v", read, err)
+	}
+
+	// Missing timestamps are zero, and fields of the wrong type are errors
+	p, err := ProfileFrom(Document{ID: "p1", Path: "profiles/p1", Data: map[string]interface{}{"name": "Maya"}})
+	if err != nil || p.ID != "p1" || p.Name != "Maya" || !p.CreatedAt.IsZero() {
+		t.Errorf("ProfileFrom() = %+v, %v", p, err)
+	}
+	_, err = HistoryFrom(Document{Path: "histories/h1", Data: map[string]interface{}{"workout": map[string]interface{}{"date": "yesterday"}}})
+	if err == nil || !strings.Contains(err.Error(), "invalid history histories/h1") {
+		t.Errorf("Expected an error naming the history, got %v", err)
+	}
+}
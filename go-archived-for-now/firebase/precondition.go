@@ -0,0 +1,45 @@
+package firebase
+
+import (
+	"fmt"
+	"time"
+)
+
+// Precondition makes a Set, Update or Delete apply only if the document is
+// still as it was read, so two admins editing the same document don't
+// silently overwrite each other
+type Precondition struct {
+	lastUpdate time.Time
+}
+
+// LastUpdateTime is the precondition that a document was last updated at t,
+// which is the UpdateTime of the Document as it was read
+func LastUpdateTime(t time.Time) Precondition {
+	return Precondition{lastUpdate: t}
+}
+
+// expectedUpdate returns the update time expected by the last of preconds,
+// and false when there are none
+func expectedUpdate(preconds []Precondition) (time.Time, bool) {
+	if len(preconds) == 0 {
+		return time.Time{}, false
+	}
+	return preconds[len(preconds)-1].lastUpdate, true
+}
+
+// ConflictError is returned by a write with a precondition when the document
+// was updated or deleted since it was read
+type ConflictError struct {
+	Path     string
+	Expected time.Time // the update time the write expected
+	Actual   time.Time // the update time the document has now, zero if it was deleted
+}
+
+// Error describes what happened to the document since it was read
+func (e *ConflictError) Error() string {
+	if e.Actual.IsZero() {
+		return fmt.Sprintf("%s was deleted after it was read", e.Path)
+	}
+	return fmt.Sprintf("%s was updated at %s, after the version from %s was read",
+		e.Path, e.Actual.UTC().Format(time.RFC3339Nano), e.Expected.UTC().Format(time.RFC3339Nano))
+}
@@ -0,0 +1,536 @@
+package firebase
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Direction is the sort order of an OrderBy clause
+type Direction int
+
+const (
+	// Asc sorts in ascending order
+	Asc Direction = iota
+	// Desc sorts in descending order
+	Desc
+)
+
+// String returns the direction as written in a query
+func (d Direction) String() string {
+	if d == Desc {
+		return "desc"
+	}
+	return "asc"
+}
+
+// Filter is a single field condition, such as
+// Filter{Path: "uid", Op: "==", Value: uid}.
+// Path may be a dotted path into nested maps, like "workout.date".
+type Filter struct {
+	Path  string
+	Op    string
+	Value interface{}
+}
+
+// Order is a single OrderBy clause
+type Order struct {
+	Path      string
+	Direction Direction
+}
+
+// Query describes a Firestore query independently of the backend that runs it.
+// Builder methods return a new Query, so a base query can be safely extended.
+type Query struct {
+	// Collection is a collection path, or a collection ID for group queries
+	Collection string
+	// AllDescendants queries every collection with the ID Collection
+	AllDescendants bool
+
+	Filters    []Filter
+	Orders     []Order
+	LimitTo    int
+	StartAfter []interface{}
+	Fields     []string
+}
+
+// Document is a single query result
+type Document struct {
+	ID         string
+	Path       string
+	Data       map[string]interface{}
+	CreateTime time.Time
+	UpdateTime time.Time
+}
+
+// DataTo copies the document data into dest, which must be a pointer
+func (d Document) DataTo(dest interface{}) error {
+	return decodeData(d.Data, dest)
+}
+
+// From starts a query on the collection at collectionPath
+func From(collectionPath string) Query {
+	return Query{Collection: collectionPath}
+}
+
+// CollectionGroup starts a query on every collection with the given ID,
+// such as all "records" subcollections of all profiles
+func CollectionGroup(collectionID string) Query {
+	return Query{Collection: collectionID, AllDescendants: true}
+}
+
+// Where adds a filter
+func (q Query) Where(path, op string, value interface{}) Query {
+	q.Filters = append(append([]Filter(nil), q.Filters...), Filter{Path: path, Op: op, Value: value})
+	return q
+}
+
+// OrderBy adds a sort order
+func (q Query) OrderBy(path string, dir Direction) Query {
+	q.Orders = append(append([]Order(nil), q.Orders...), Order{Path: path, Direction: dir})
+	return q
+}
+
+// Limit caps the number of results
+func (q Query) Limit(n int) Query {
+	q.LimitTo = n
+	return q
+}
+
+// After starts results after the given values of the OrderBy fields,
+// or after the given document ID when there is no OrderBy clause
+func (q Query) After(values ...interface{}) Query {
+	q.StartAfter = values
+	return q
+}
+
+// Select restricts the fields returned for each document
+func (q Query) Select(fields ...string) Query {
+	q.Fields = fields
+	return q
+}
+
+// Validate reports queries that no backend can execute
+func (q Query) Validate() error {
+	if q.Collection == "" {
+		return errors.New("query has no collection")
+	}
+	if q.AllDescendants && strings.Contains(q.Collection, "/") {
+		return fmt.Errorf("collection group %q must be a collection ID, not a path", q.Collection)
+	}
+	paths := q.Fields
+	for _, f := range q.Filters {
+		if !validOps[f.Op] {
+			return fmt.Errorf("unsupported filter operator %q", f.Op)
+		}
+		paths = append(paths[:len(paths):len(paths)], f.Path)
+	}
+	for _, o := range q.Orders {
+		paths = append(paths[:len(paths):len(paths)], o.Path)
+	}
+	for _, path := range paths {
+		if _, err := splitFieldPath(path); err != nil {
+			return err
+		}
+	}
+	if q.LimitTo < 0 {
+		return errors.New("query limit must not be negative")
+	}
+	if len(q.Orders) > 0 && len(q.StartAfter) > len(q.Orders) {
+		return errors.New("too many start after values for the order by clauses")
+	}
+	if len(q.Orders) == 0 && len(q.StartAfter) > 1 {
+		return errors.New("start after without order by takes a single document ID")
+	}
+	return nil
+}
+
+// String formats the query in the syntax accepted by ParseQuery
+func (q Query) String() string {
+	var sb strings.Builder
+	if q.AllDescendants {
+		sb.WriteString("group ")
+	}
+	sb.WriteString(q.Collection)
+	for i, f := range q.Filters {
+		if i == 0 {
+			sb.WriteString(" where ")
+		} else {
+			sb.WriteString(" and ")
+		}
+		fmt.Fprintf(&sb, "%s %s %s", f.Path, f.Op, formatQueryValue(f.Value))
+	}
+	for i, o := range q.Orders {
+		if i == 0 {
+			sb.WriteString(" order by ")
+		} else {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "%s %s", o.Path, o.Direction)
+	}
+	if len(q.StartAfter) > 0 {
+		values := make([]string, len(q.StartAfter))
+		for i, v := range q.StartAfter {
+			values[i] = formatQueryValue(v)
+		}
+		sb.WriteString(" after " + strings.Join(values, ", "))
+	}
+	if q.LimitTo > 0 {
+		fmt.Fprintf(&sb, " limit %d", q.LimitTo)
+	}
+	if len(q.Fields) > 0 {
+		sb.WriteString(" select " + strings.Join(q.Fields, ", "))
+	}
+	return sb.String()
+}
+
+var validOps = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+	"in": true, "not-in": true, "array-contains": true, "array-contains-any": true,
+}
+
+// ParseQuery parses a query typed by a user, for example
+//
+//	routines where uid == "abc" and createdAt >= 2024-01-01T00:00:00Z order by createdAt desc limit 20
+//	group records where weight > 100 select name, weight
+//
+// Clauses are where/and, order by, after, limit and select, in any order after
+// the collection. Values may be quoted strings, numbers, true, false, null,
+// RFC 3339 timestamps or [lists]; anything else is read as a bare string.
+func ParseQuery(input string) (Query, error) {
+	tokens, err := tokenizeQuery(input)
+	if err != nil {
+		return Query{}, err
+	}
+	p := &queryParser{tokens: tokens}
+
+	var q Query
+	if p.acceptWord("group") {
+		q.AllDescendants = true
+	}
+	collection, ok := p.next()
+	if !ok {
+		return Query{}, errors.New("query has no collection")
+	}
+	q.Collection = collection.text
+
+	for !p.done() {
+		switch {
+		case p.acceptWord("where"), p.acceptWord("and"):
+			f, err := p.parseFilter()
+			if err != nil {
+				return Query{}, err
+			}
+			q.Filters = append(q.Filters, f)
+		case p.acceptWord("order"):
+			if !p.acceptWord("by") {
+				return Query{}, errors.New(`expected "by" after "order"`)
+			}
+			for {
+				path, ok := p.next()
+				if !ok {
+					return Query{}, errors.New("expected a field after order by")
+				}
+				order := Order{Path: path.text}
+				if p.acceptWord("desc") {
+					order.Direction = Desc
+				} else {
+					p.acceptWord("asc")
+				}
+				q.Orders = append(q.Orders, order)
+				if !p.accept(",") {
+					break
+				}
+			}
+		case p.acceptWord("after"):
+			for {
+				v, err := p.parseValue()
+				if err != nil {
+					return Query{}, err
+				}
+				q.StartAfter = append(q.StartAfter, v)
+				if !p.accept(",") {
+					break
+				}
+			}
+		case p.acceptWord("limit"):
+			tok, ok := p.next()
+			n, err := strconv.Atoi(tok.text)
+			if !ok || err != nil || n < 0 {
+				return Query{}, fmt.Errorf("invalid limit %q", tok.text)
+			}
+			q.LimitTo = n
+		case p.acceptWord("select"):
+			for {
+				field, ok := p.next()
+				if !ok {
+					return Query{}, errors.New("expected a field after select")
+				}
+				q.Fields = append(q.Fields, field.text)
+				if !p.accept(",") {
+					break
+				}
+			}
+		default:
+			tok, _ := p.next()
+			return Query{}, fmt.Errorf("unexpected %q in query", tok.text)
+		}
+	}
+
+	return q, q.Validate()
+}
+
+// ParseFilter parses a single condition such as `uid == "abc"`
+func ParseFilter(input string) (Filter, error) {
+	tokens, err := tokenizeQuery(input)
+	if err != nil {
+		return Filter{}, err
+	}
+	p := &queryParser{tokens: tokens}
+	f, err := p.parseFilter()
+	if err != nil {
+		return Filter{}, err
+	}
+	if !p.done() {
+		return Filter{}, fmt.Errorf("unexpected %q after filter", p.tokens[p.pos].text)
+	}
+	return f, nil
+}
+
+// ParseValue parses a single typed value using the query value syntax
+func ParseValue(input string) (interface{}, error) {
+	tokens, err := tokenizeQuery(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	v, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q after value", p.tokens[p.pos].text)
+	}
+	return v, nil
+}
+
+type queryToken struct {
+	text   string
+	quoted bool
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *queryParser) next() (queryToken, bool) {
+	if p.done() {
+		return queryToken{}, false
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	return tok, true
+}
+
+// accept consumes the next token if it is the given punctuation
+func (p *queryParser) accept(text string) bool {
+	if !p.done() && !p.tokens[p.pos].quoted && p.tokens[p.pos].text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// acceptWord consumes the next token if it is the given keyword
+func (p *queryParser) acceptWord(word string) bool {
+	if !p.done() && !p.tokens[p.pos].quoted && strings.EqualFold(p.tokens[p.pos].text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) parseFilter() (Filter, error) {
+	path, ok := p.next()
+	if !ok {
+		return Filter{}, errors.New("expected a field in filter")
+	}
+	op, ok := p.next()
+	if !ok || !validOps[op.text] {
+		return Filter{}, fmt.Errorf("invalid operator %q in filter on %s", op.text, path.text)
+	}
+	value, err := p.parseValue()
+	if err != nil {
+		return Filter{}, err
+	}
+	return Filter{Path: path.text, Op: op.text, Value: value}, nil
+}
+
+func (p *queryParser) parseValue() (interface{}, error) {
+	if p.accept("[") {
+		items := []interface{}{}
+		if p.accept("]") {
+			return items, nil
+		}
+		for {
+			item, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			if p.accept("]") {
+				return items, nil
+			}
+			if !p.accept(",") {
+				return nil, errors.New("expected , or ] in list")
+			}
+		}
+	}
+
+	tok, ok := p.next()
+	if !ok {
+		return nil, errors.New("expected a value")
+	}
+	if tok.quoted {
+		return tok.text, nil
+	}
+	return typedQueryValue(tok.text), nil
+}
+
+// typedQueryValue interprets an unquoted value
+func typedQueryValue(text string) interface{} {
+	switch strings.ToLower(text) {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f
+	}
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		return t
+	}
+	if t, err := time.Parse(time.DateOnly, text); err == nil {
+		return t
+	}
+	return text
+}
+
+// formatQueryValue writes a value so that ParseQuery reads it back unchanged
+func formatQueryValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = formatQueryValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	encoded, err := encodeValue(v)
+	if list, ok := encoded.([]interface{}); ok && err == nil {
+		return formatQueryValue(list)
+	}
+	return fmt.Sprint(v)
+}
+
+// unquoteQueryString reads a quoted string of a query with Go's escapes, the
+// reverse of strconv.Quote in formatQueryValue. Single-quoted strings take the
+// same escapes, with \' for a quote.
+func unquoteQueryString(quoted []rune) (string, error) {
+	original := string(quoted)
+	if quoted[0] == '\'' {
+		var sb strings.Builder
+		sb.WriteRune('"')
+		inner := quoted[1 : len(quoted)-1]
+		for i := 0; i < len(inner); i++ {
+			switch {
+			case inner[i] == '\\' && i+1 < len(inner) && inner[i+1] == '\'':
+				sb.WriteRune('\'')
+				i++
+			case inner[i] == '\\' && i+1 < len(inner):
+				sb.WriteString(string(inner[i : i+2]))
+				i++
+			case inner[i] == '"':
+				sb.WriteString(`\"`)
+			default:
+				sb.WriteRune(inner[i])
+			}
+		}
+		sb.WriteRune('"')
+		quoted = []rune(sb.String())
+	}
+	text, err := strconv.Unquote(string(quoted))
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s in query", original)
+	}
+	return text, nil
+}
+
+// tokenizeQuery splits a query into words, operators, punctuation and quoted strings
+func tokenizeQuery(input string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(runes) {
+				return nil, errors.New("unterminated string in query")
+			}
+			text, err := unquoteQueryString(runes[i : j+1])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, queryToken{text: text, quoted: true})
+			i = j + 1
+		case r == ',' || r == '[' || r == ']':
+			tokens = append(tokens, queryToken{text: string(r)})
+			i++
+		case strings.ContainsRune("=!<>", r):
+			j := i + 1
+			if j < len(runes) && runes[j] == '=' {
+				j++
+			}
+			tokens = append(tokens, queryToken{text: string(runes[i:j])})
+			i = j
+		default:
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && !strings.ContainsRune(",[]\"'=!<>", runes[j]) {
+				j++
+			}
+			tokens = append(tokens, queryToken{text: string(runes[i:j])})
+			i = j
+		}
+	}
+
+	return tokens, nil
+}
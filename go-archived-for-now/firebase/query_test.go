@@ -0,0 +1,111 @@
+package firebase
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Query
+	}{
+		{
+			name:  "collection only",
+			input: "routines",
+			want:  From("routines"),
+		},
+		{
+			name:  "filters and order",
+			input: `routines where uid == "abc" and exercises array-contains e1 order by createdAt desc, name limit 20`,
+			want: From("routines").
+				Where("uid", "==", "abc").
+				Where("exercises", "array-contains", "e1").
+				OrderBy("createdAt", Desc).
+				OrderBy("name", Asc).
+				Limit(20),
+		},
+		{
+			name:  "typed values",
+			input: `histories where workout.date >= 2024-01-01T00:00:00Z and done != false and score in [1, 2.5, null]`,
+			want: From("histories").
+				Where("workout.date", ">=", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)).
+				Where("done", "!=", false).
+				Where("score", "in", []interface{}{int64(1), 2.5, nil}),
+		},
+		{
+			name:  "collection group with cursor and select",
+			input: `group records order by weight desc after 100 select name, weight`,
+			want:  CollectionGroup("records").OrderBy("weight", Desc).After(int64(100)).Select("name", "weight"),
+		},
+		{
+			name:  "escaped strings",
+			input: `notes where title == "say \"hi\"\n" and dir == 'C:\\notes' and note == 'it\'s "ok"'`,
+			want: From("notes").
+				Where("title", "==", "say \"hi\"\n").
+				Where("dir", "==", `C:\notes`).
+				Where("note", "==", `it's "ok"`),
+		},
+		{
+			name:  "subcollection path",
+			input: `profiles/p1/records where reps<=5`,
+			want:  From("profiles/p1/records").Where("reps", "<=", int64(5)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuery(tt.input)
+			if err != nil {
+				t.Fatalf("ParseQuery() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseQuery() = %#v, want %#v", got, tt.want)
+			}
+
+			// Formatting and parsing again must give the same query
+			again, err := ParseQuery(got.String())
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) error = %v", got.String(), err)
+			}
+			if !reflect.DeepEqual(again, got) {
+				t.Errorf("Round trip of %q = %#v, want %#v", got.String(), again, got)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	inputs := []string{
+		"",
+		"routines where uid",
+		"routines where uid ~ 1",
+		`routines where name == "unterminated`,
+		`routines where name == "bad \q escape"`,
+		"routines limit many",
+		"routines order name",
+		"group profiles/p1/records",
+		"routines after 1, 2",
+	}
+
+	for _, input := range inputs {
+		if _, err := ParseQuery(input); err == nil {
+			t.Errorf("ParseQuery(%q) expected an error", input)
+		}
+	}
+}
+
+func TestBuilderDoesNotShareState(t *testing.T) {
+	base := From("routines").Where("uid", "==", "a")
+	first := base.Where("name", "==", "Push")
+	second := base.Where("name", "==", "Pull")
+
+	if len(base.Filters) != 1 {
+		t.Errorf("Base query was modified: %v", base.Filters)
+	}
+	if first.Filters[1].Value != "Push" || second.Filters[1].Value != "Pull" {
+		t.Errorf("Derived queries share filters: %v, %v", first.Filters, second.Filters)
+	}
+}
@@ -0,0 +1,53 @@
+package firebase
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"firebase.google.com/go/v4/auth"
+)
+
+// UserStore is the set of user management operations the admin tool depends on.
+// AuthService implements it on top of Firebase Authentication.
+type UserStore interface {
+	GetUser(ctx context.Context, uid string) (*auth.UserRecord, error)
+	GetUserByEmail(ctx context.Context, email string) (*auth.UserRecord, error)
+	GetUsers(ctx context.Context, identifiers []auth.UserIdentifier) (*auth.GetUsersResult, error)
+	ListUsers(ctx context.Context, maxResults int, pageToken string) (*UserPage, error)
+	CreateUser(ctx context.Context, params *UserToCreate) (string, error)
+	UpdateUser(ctx context.Context, uid string, params *UserToUpdate) error
+	DeleteUser(ctx context.Context, uid string) error
+	RevokeRefreshTokens(ctx context.Context, uid string) error
+	SetCustomUserClaims(ctx context.Context, uid string, claims map[string]interface{}) error
+	PasswordResetLink(ctx context.Context, email string, settings *ActionCodeSettings) (string, error)
+	EmailVerificationLink(ctx context.Context, email string, settings *ActionCodeSettings) (string, error)
+	EmailSignInLink(ctx context.Context, email string, settings *ActionCodeSettings) (string, error)
+	ImportUsers(ctx context.Context, users []*ImportUser, hash *HashConfig) (*ImportResult, error)
+}
+
+// DocumentStore is the set of document operations the admin tool depends on.
+// FirestoreService implements it on top of Cloud Firestore.
+type DocumentStore interface {
+	Create(ctx context.Context, collectionPath string, data interface{}) (string, error)
+	Set(ctx context.Context, collectionPath, documentID string, data interface{}, preconds ...Precondition) error
+	Update(ctx context.Context, collectionPath, documentID string, updates map[string]interface{}, preconds ...Precondition) error
+	Get(ctx context.Context, collectionPath, documentID string, dest interface{}) error
+	GetDocument(ctx context.Context, collectionPath, documentID string) (Document, error)
+	Delete(ctx context.Context, collectionPath, documentID string, preconds ...Precondition) error
+	BatchDelete(ctx context.Context, documentPaths []string) error
+	CommitBatch(ctx context.Context, writes []Write) error
+	RunTransaction(ctx context.Context, fn func(tx Tx) error) error
+	List(ctx context.Context, collectionPath string) ([]map[string]interface{}, error)
+	ListCollections(ctx context.Context, documentPath string) ([]string, error)
+	Reference(documentPath string) (*firestore.DocumentRef, error)
+	Query(ctx context.Context, q Query) ([]Document, error)
+	QueryEach(ctx context.Context, q Query, fn func(Document) error) error
+}
+
+// Compile-time checks that the backends satisfy the store interfaces
+var (
+	_ UserStore     = (*AuthService)(nil)
+	_ DocumentStore = (*FirestoreService)(nil)
+	_ UserStore     = (*MemoryAuth)(nil)
+	_ DocumentStore = (*MemoryFirestore)(nil)
+)
@@ -0,0 +1,21 @@
+package firebase
+
+import "errors"
+
+// MaxTransactionAttempts is how many times RunTransaction runs its function
+// before giving up when the documents it read keep changing
+const MaxTransactionAttempts = 5
+
+// ErrReadAfterWrite is returned by reads in a transaction that has already written
+var ErrReadAfterWrite = errors.New("transactions must read every document before writing")
+
+// Tx is a transaction started by DocumentStore.RunTransaction. Every read
+// comes before the first write, and the writes only apply if none of the
+// documents read were changed in the meantime; otherwise the transaction
+// function runs again with fresh reads.
+type Tx interface {
+	Get(collectionPath, documentID string) (Document, error)
+	Set(collectionPath, documentID string, data interface{}) error
+	Update(collectionPath, documentID string, updates map[string]interface{}) error
+	Delete(collectionPath, documentID string) error
+}
@@ -0,0 +1,94 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemoryFirestoreRunTransaction(t *testing.T) {
+	_, docStore := loadTestFixture(t)
+	ctx := context.Background()
+
+	// Another write between the read and the commit runs the function again
+	attempts := 0
+	err := docStore.RunTransaction(ctx, func(tx Tx) error {
+		attempts++
+		doc, err := tx.Get("routines", "r1")
+		if err != nil {
+			return err
+		}
+		if attempts == 1 {
+			if err := docStore.Update(ctx, "routines", "r1", map[string]interface{}{"name": "Theirs"}); err != nil {
+				return err
+			}
+		}
+		if err := tx.Update("routines", "r1", map[string]interface{}{"name": doc.Data["name"].(string) + " 2"}); err != nil {
+			return err
+		}
+		return tx.Set("routines", "r1-copy", doc.Data)
+	})
+	if err != nil {
+		t.Fatalf("RunTransaction() error = %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attempts)
+	}
+	var r1, copied map[string]interface{}
+	_ = docStore.Get(ctx, "routines", "r1", &r1)
+	_ = docStore.Get(ctx, "routines", "r1-copy", &copied)
+	if r1["name"] != "Theirs 2" || copied["name"] != "Theirs" {
+		t.Errorf("Expected the writes of the second attempt, got %v and %v", r1["name"], copied["name"])
+	}
+
+	// An error from the function discards its writes
+	boom := errors.New("boom")
+	err = docStore.RunTransaction(ctx, func(tx Tx) error {
+		if err := tx.Delete("routines", "r2"); err != nil {
+			return err
+		}
+		return boom
+	})
+	if err != boom {
+		t.Errorf("Expected the function's error, got %v", err)
+	}
+	if _, err := docStore.GetDocument(ctx, "routines", "r2"); err != nil {
+		t.Errorf("Expected r2 to remain, got %v", err)
+	}
+
+	// Reads come before writes
+	err = docStore.RunTransaction(ctx, func(tx Tx) error {
+		_ = tx.Delete("routines", "r2")
+		_, err := tx.Get("routines", "r3")
+		return err
+	})
+	if err != ErrReadAfterWrite {
+		t.Errorf("Expected ErrReadAfterWrite, got %v", err)
+	}
+
+	// A document read as missing conflicts when it's created, and one that
+	// keeps changing makes the transaction give up
+	attempts = 0
+	err = docStore.RunTransaction(ctx, func(tx Tx) error {
+		attempts++
+		if _, err := tx.Get("routines", "r4"); err != ErrDocumentNotFound {
+			return err
+		}
+		return docStore.Set(ctx, "routines", "r4", map[string]interface{}{"attempt": attempts})
+	})
+	if err != nil || attempts != 2 {
+		t.Errorf("Expected the missing document to be read again, got %d attempts and %v", attempts, err)
+	}
+
+	attempts = 0
+	err = docStore.RunTransaction(ctx, func(tx Tx) error {
+		attempts++
+		if _, err := tx.Get("routines", "r3"); err != nil {
+			return err
+		}
+		return docStore.Update(ctx, "routines", "r3", map[string]interface{}{"attempt": attempts})
+	})
+	if err == nil || attempts != MaxTransactionAttempts {
+		t.Errorf("Expected to give up after %d attempts, got %d attempts and %v", MaxTransactionAttempts, attempts, err)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// formField is a single text or checkbox field of a form
+type formField struct {
+	label string
+	input textinput.Model
+
+	// Checkbox fields are toggled instead of typed into
+	checkbox bool
+	checked  bool
+
+	// validate checks the value of a text field before the form is submitted
+	validate func(value string) error
+	err      string
+}
+
+// textField creates a text field of a form
+func textField(label, value, placeholder string, validate func(string) error) formField {
+	input := textinput.New()
+	input.Prompt = ""
+	input.Placeholder = placeholder
+	input.SetValue(value)
+	input.CharLimit = 512
+	input.Width = 40
+	return formField{label: label, input: input, validate: validate}
+}
+
+// passwordField creates a text field that hides what's typed
+func passwordField(label, placeholder string, validate func(string) error) formField {
+	f := textField(label, "", placeholder, validate)
+	f.input.EchoMode = textinput.EchoPassword
+	f.input.EchoCharacter = '•'
+	return f
+}
+
+// checkboxField creates a checkbox field of a form
+func checkboxField(label string, checked bool) formField {
+	return formField{label: label, checkbox: true, checked: checked}
+}
+
+// formKeyMap holds the keys of a form
+type formKeyMap struct {
+	Next   key.Binding
+	Prev   key.Binding
+	Toggle key.Binding
+	Submit key.Binding
+	Cancel key.Binding
+}
+
+var formKeys = formKeyMap{
+	Next:   key.NewBinding(key.WithKeys("tab", "down"), key.WithHelp("tab/↓", "next field")),
+	Prev:   key.NewBinding(key.WithKeys("shift+tab", "up"), key.WithHelp("shift+tab/↑", "previous field")),
+	Toggle: key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "toggle")),
+	Submit: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "save")),
+	Cancel: key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+}
+
+// ShortHelp returns the keys shown in the footer
+func (k formKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Next, k.Toggle, k.Submit, k.Cancel}
+}
+
+// FullHelp returns every key of the form
+func (k formKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{{k.Next, k.Prev, k.Toggle}, {k.Submit, k.Cancel}}
+}
+
+// form is a list of fields edited one at a time. The screen showing the
+// form handles submitting and cancelling it.
+type form struct {
+	fields []formField
+	focus  int
+	keys   formKeyMap
+}
+
+// newForm creates a form with the first field focused
+func newForm(fields ...formField) form {
+	f := form{fields: fields, keys: formKeys}
+	return f.focusField(0)
+}
+
+// focusField moves the focus to the field at index, wrapping around
+func (f form) focusField(index int) form {
+	fields := append([]formField(nil), f.fields...)
+	f.focus = (index%len(fields) + len(fields)) % len(fields)
+	for i := range fields {
+		if i == f.focus && !fields[i].checkbox {
+			fields[i].input.Focus()
+		} else {
+			fields[i].input.Blur()
+		}
+	}
+	f.fields = fields
+	return f
+}
+
+// KeyMap returns the keys of the form, leaving out toggling when a text field is focused
+func (f form) KeyMap() formKeyMap {
+	k := f.keys
+	k.Toggle.SetEnabled(f.fields[f.focus].checkbox)
+	return k
+}
+
+// Update moves between fields, toggles checkboxes and types into text fields
+func (f form) Update(msg tea.Msg) (form, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		// Keep the cursor blinking
+		return f.updateFocused(msg)
+	}
+
+	switch {
+	case key.Matches(keyMsg, f.keys.Next):
+		return f.focusField(f.focus + 1), textinput.Blink
+	case key.Matches(keyMsg, f.keys.Prev):
+		return f.focusField(f.focus - 1), textinput.Blink
+	}
+
+	if field := f.fields[f.focus]; field.checkbox {
+		if key.Matches(keyMsg, f.keys.Toggle) {
+			fields := append([]formField(nil), f.fields...)
+			fields[f.focus].checked = !field.checked
+			f.fields = fields
+		}
+		return f, nil
+	}
+	return f.updateFocused(msg)
+}
+
+// updateFocused sends a message to the focused text field
+func (f form) updateFocused(msg tea.Msg) (form, tea.Cmd) {
+	if f.fields[f.focus].checkbox {
+		return f, nil
+	}
+	fields := append([]formField(nil), f.fields...)
+	var cmd tea.Cmd
+	fields[f.focus].input, cmd = fields[f.focus].input.Update(msg)
+	fields[f.focus].err = ""
+	f.fields = fields
+	return f, cmd
+}
+
+// validate checks every text field, keeping the errors to show next to the
+// fields. It focuses the first invalid field and reports whether all are valid.
+func (f form) validate() (form, bool) {
+	fields := append([]formField(nil), f.fields...)
+	first := -1
+	for i, field := range fields {
+		fields[i].err = ""
+		if field.checkbox || field.validate == nil {
+			continue
+		}
+		if err := field.validate(strings.TrimSpace(field.input.Value())); err != nil {
+			fields[i].err = err.Error()
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	f.fields = fields
+	if first >= 0 {
+		return f.focusField(first), false
+	}
+	return f, true
+}
+
+// setError shows an error next to the field with the given label
+func (f form) setError(label, message string) form {
+	fields := append([]formField(nil), f.fields...)
+	for i := range fields {
+		if fields[i].label == label {
+			fields[i].err = message
+			f.fields = fields
+			return f.focusField(i)
+		}
+	}
+	return f
+}
+
+// value returns the trimmed value of the text field with the given label
+func (f form) value(label string) string {
+	for _, field := range f.fields {
+		if field.label == label {
+			return strings.TrimSpace(field.input.Value())
+		}
+	}
+	return ""
+}
+
+// checked reports whether the checkbox with the given label is checked
+func (f form) checked(label string) bool {
+	for _, field := range f.fields {
+		if field.label == label {
+			return field.checked
+		}
+	}
+	return false
+}
+
+// View renders the fields with their errors
+func (f form) View() string {
+	var sb strings.Builder
+	for i, field := range f.fields {
+		label := labelStyle.Render(field.label)
+		if i == f.focus {
+			label = labelStyle.Foreground(highlightColor).Bold(true).Render(field.label)
+		}
+
+		value := field.input.View()
+		if field.checkbox {
+			value = "[ ]"
+			if field.checked {
+				value = "[x]"
+			}
+			if i == f.focus {
+				value = highlightStyle.Render(value)
+			}
+		}
+
+		sb.WriteString(label + value + "\n")
+		if field.err != "" {
+			sb.WriteString(labelStyle.Render("") + errorStyle.UnsetMarginLeft().Render(field.err) + "\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
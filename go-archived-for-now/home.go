@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+
+	"arrogance/firebase"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// homeScreen shows what the app is connected to
+type homeScreen struct {
+	frame
+	client     *firebase.AppClient
+	demo       bool
+	authReady  bool
+	storeReady bool
+}
+
+// newHomeScreen creates the home screen for a connection
+func newHomeScreen(client *firebase.AppClient, demo bool, authSvc firebase.UserStore, storeSvc firebase.DocumentStore) homeScreen {
+	return homeScreen{
+		client:     client,
+		demo:       demo,
+		authReady:  authSvc != nil,
+		storeReady: storeSvc != nil,
+	}
+}
+
+// Init has nothing to load
+func (s homeScreen) Init() tea.Cmd {
+	return nil
+}
+
+// Update tracks the window size
+func (s homeScreen) Update(msg tea.Msg) (Screen, tea.Cmd) {
+	s.track(msg)
+	return s, nil
+}
+
+// KeyMap has no keys besides navigation
+func (s homeScreen) KeyMap() help.KeyMap {
+	return homeKeyMap{}
+}
+
+// View shows the connection status
+func (s homeScreen) View() string {
+	var contentText string
+	if s.client != nil || s.demo {
+		// Show connected status with service details
+		if s.demo {
+			contentText = "Welcome to Arrogance Admin!\n\nRunning in demo mode with in-memory sample data.\n\n"
+		} else if s.client.Emulator {
+			contentText = fmt.Sprintf("Welcome to Arrogance Admin! %s\n\nConnected to the Firebase emulators for project %s.\n\n",
+				emulatorBadgeStyle.Render("EMULATOR"), s.client.ProjectID)
+		} else {
+			contentText = "Welcome to Arrogance Admin!\n\nFirebase is initialized and ready to use.\n\n"
+		}
+
+		if s.authReady {
+			contentText += "✓ Auth service ready\n"
+		} else {
+			contentText += "✗ Auth service not available\n"
+		}
+
+		if s.storeReady {
+			contentText += "✓ Firestore service ready\n"
+		} else {
+			contentText += "✗ Firestore service not available\n"
+		}
+
+		contentText += "\nUse the tabs above to navigate."
+	} else {
+		// Firebase client is null but not loading - error state
+		contentText = "Welcome to Arrogance Admin!\n\nFirebase initialization failed.\n\nPlease check your configuration and restart the application."
+	}
+
+	return lipgloss.NewStyle().
+		Width(s.width-8).
+		Height(s.height-12).
+		Padding(1, 2).
+		Render(contentText)
+}
+
+// homeKeyMap is the empty key map of the home screen
+type homeKeyMap struct{}
+
+// ShortHelp returns no keys
+func (homeKeyMap) ShortHelp() []key.Binding { return nil }
+
+// FullHelp returns no keys
+func (homeKeyMap) FullHelp() [][]key.Binding { return nil }
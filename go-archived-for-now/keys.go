@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// inputKeyMap holds the keys of a text input, such as a filter or a confirmation
+type inputKeyMap struct {
+	Submit key.Binding
+	Cancel key.Binding
+}
+
+var filterKeys = inputKeyMap{
+	Submit: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "apply")),
+	Cancel: key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+}
+
+var deleteConfirmKeys = inputKeyMap{
+	Submit: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "delete")),
+	Cancel: key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+}
+
+// ShortHelp returns the keys shown in the footer
+func (k inputKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Submit, k.Cancel}
+}
+
+// FullHelp returns every key of the input
+func (k inputKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+// appKeyMap combines the keys of the top screen with the navigation keys
+// that currently apply to it
+type appKeyMap struct {
+	screen help.KeyMap
+	nav    []key.Binding
+}
+
+// ShortHelp returns the screen's keys followed by the navigation keys
+func (k appKeyMap) ShortHelp() []key.Binding {
+	return append(append([]key.Binding(nil), k.screen.ShortHelp()...), k.nav...)
+}
+
+// FullHelp returns the screen's key groups followed by the navigation keys
+func (k appKeyMap) FullHelp() [][]key.Binding {
+	groups := append([][]key.Binding(nil), k.screen.FullHelp()...)
+	if len(k.nav) > 0 {
+		groups = append(groups, k.nav)
+	}
+	return groups
+}
+
+// keyBindings names every binding that can be remapped from the config file.
+// Screens copy their key maps when they're created, so remapping must happen
+// before the app starts.
+func keyBindings() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"quit":       &navKeys.Quit,
+		"force_quit": &navKeys.ForceQuit,
+		"next_tab":   &navKeys.NextTab,
+		"prev_tab":   &navKeys.PrevTab,
+		"back":       &navKeys.Back,
+		"help":       &navKeys.Help,
+
+		"users.up":         &usersKeys.Up,
+		"users.down":       &usersKeys.Down,
+		"users.open":       &usersKeys.Open,
+		"users.next_page":  &usersKeys.NextPage,
+		"users.prev_page":  &usersKeys.PrevPage,
+		"users.filter":     &usersKeys.Filter,
+		"users.sort":       &usersKeys.Sort,
+		"users.reverse":    &usersKeys.Reverse,
+		"users.create":     &usersKeys.Create,
+		"users.edit":       &usersKeys.Edit,
+		"users.disable":    &usersKeys.Disable,
+		"users.revoke":     &usersKeys.Revoke,
+		"users.select":     &usersKeys.Mark,
+		"users.select_all": &usersKeys.MarkAll,
+		"users.grant_role": &usersKeys.Grant,
+		"users.import":     &usersKeys.Import,
+		"users.export":     &usersKeys.Export,
+
+		"user_detail.up":      &userDetailKeys.Up,
+		"user_detail.down":    &userDetailKeys.Down,
+		"user_detail.delete":  &userDetailKeys.Delete,
+		"user_detail.edit":    &userDetailKeys.Edit,
+		"user_detail.disable": &userDetailKeys.Disable,
+		"user_detail.revoke":  &userDetailKeys.Revoke,
+		"user_detail.claims":  &userDetailKeys.Claims,
+		"user_detail.links":   &userDetailKeys.Links,
+
+		"collection.up":      &collectionKeys.Up,
+		"collection.down":    &collectionKeys.Down,
+		"collection.open":    &collectionKeys.Open,
+		"collection.sort":    &collectionKeys.Sort,
+		"collection.reverse": &collectionKeys.Reverse,
+		"collection.filter":  &collectionKeys.Filter,
+		"collection.export":  &collectionKeys.Export,
+
+		"document.up":   &documentKeys.Up,
+		"document.down": &documentKeys.Down,
+
+		"browser.up":          &browserKeys.Up,
+		"browser.down":        &browserKeys.Down,
+		"browser.open":        &browserKeys.Open,
+		"tree.up":             &documentTreeKeys.Up,
+		"tree.down":           &documentTreeKeys.Down,
+		"tree.edit":           &documentTreeKeys.Edit,
+		"tree.subcollections": &documentTreeKeys.Subcollections,
+
+		"edit.up":     &documentEditKeys.Up,
+		"edit.down":   &documentEditKeys.Down,
+		"edit.field":  &documentEditKeys.Edit,
+		"edit.add":    &documentEditKeys.Add,
+		"edit.delete": &documentEditKeys.Delete,
+		"edit.editor": &documentEditKeys.Editor,
+		"edit.type":   &documentEditKeys.Type,
+		"edit.review": &documentEditKeys.Review,
+		"edit.save":   &documentEditKeys.Save,
+		"edit.reload": &documentEditKeys.Reload,
+		"edit.cancel": &documentEditKeys.Cancel,
+
+		"claims.preset": &claimsEditorKeys.Preset,
+		"claims.save":   &claimsEditorKeys.Save,
+		"claims.cancel": &claimsEditorKeys.Cancel,
+
+		"grant.up":     &grantRoleKeys.Up,
+		"grant.down":   &grantRoleKeys.Down,
+		"grant.submit": &grantRoleKeys.Submit,
+		"grant.cancel": &grantRoleKeys.Cancel,
+
+		"links.up":       &userLinksKeys.Up,
+		"links.down":     &userLinksKeys.Down,
+		"links.generate": &userLinksKeys.Generate,
+		"links.copy":     &userLinksKeys.Copy,
+		"links.close":    &userLinksKeys.Close,
+
+		"import.up":     &userImportKeys.Up,
+		"import.down":   &userImportKeys.Down,
+		"import.submit": &userImportKeys.Submit,
+		"import.cancel": &userImportKeys.Cancel,
+
+		"form.next":   &formKeys.Next,
+		"form.prev":   &formKeys.Prev,
+		"form.toggle": &formKeys.Toggle,
+		"form.save":   &formKeys.Submit,
+		"form.cancel": &formKeys.Cancel,
+
+		"filter.apply":   &filterKeys.Submit,
+		"filter.cancel":  &filterKeys.Cancel,
+		"delete.confirm": &deleteConfirmKeys.Submit,
+		"delete.cancel":  &deleteConfirmKeys.Cancel,
+		"action.confirm": &actionConfirmKeys.Submit,
+		"action.cancel":  &actionConfirmKeys.Cancel,
+	}
+}
+
+// remapKeys replaces the keys of the named bindings, keeping their help text
+func remapKeys(remap map[string][]string) error {
+	bindings := keyBindings()
+
+	names := make([]string, 0, len(remap))
+	for name := range remap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		b, ok := bindings[name]
+		if !ok {
+			return fmt.Errorf("unknown key binding %q", name)
+		}
+		keys := remap[name]
+		if len(keys) == 0 {
+			return fmt.Errorf("key binding %q has no keys", name)
+		}
+		b.SetKeys(keys...)
+		b.SetHelp(strings.Join(keys, "/"), b.Help().Desc)
+	}
+	return nil
+}
+
+// newHelp creates the help view used for the footer and the help overlay
+func newHelp() help.Model {
+	h := help.New()
+	h.Styles.ShortKey = lipgloss.NewStyle().Foreground(highlightColor).Bold(true)
+	h.Styles.FullKey = h.Styles.ShortKey
+	return h
+}
+
+// withScrollKeys makes a viewport scroll with the given up and down bindings
+func withScrollKeys(v viewport.Model, up, down key.Binding) viewport.Model {
+	v.KeyMap.Up = up
+	v.KeyMap.Down = down
+	return v
+}
+
+// withRowKeys makes a table move between rows with the given up and down bindings
+func withRowKeys(t table.Model, up, down key.Binding) table.Model {
+	t.KeyMap.LineUp = up
+	t.KeyMap.LineDown = down
+	return t
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TestHelpLine tests that the footer lists the keys of the active screen
+func TestHelpLine(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, UsersTab)
+
+	view := m.View()
+	for _, want := range []string{"open", "next tab", "? help", "q quit"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Users footer does not contain %q", want)
+		}
+	}
+	// Every user fits on one page
+	if strings.Contains(view, "next page") {
+		t.Error("Users footer shows the next page key without a next page")
+	}
+
+	// Opened screens go back instead of quitting or switching tabs
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	view = m.View()
+	if !strings.Contains(view, "q/esc back") || strings.Contains(view, "next tab") {
+		t.Error("Expected the user detail footer to offer going back")
+	}
+}
+
+// TestHelpOverlay tests opening and closing the full help
+func TestHelpOverlay(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, RoutinesTab)
+
+	m = settle(t, m, keyRunes("?"))
+	if !m.showHelp || !strings.Contains(m.View(), "reverse sort") {
+		t.Fatal("Expected ? to show every key of the screen")
+	}
+
+	// q closes the overlay instead of quitting
+	updated, cmd := m.Update(keyRunes("q"))
+	m = updated.(Model)
+	if m.showHelp || cmd != nil {
+		t.Error("Expected q to close the help overlay")
+	}
+
+	// ? is typed into the user filter
+	m = settle(t, m, keyRunes("u"))
+	m = settle(t, m, keyRunes("?"))
+	if m.showHelp || collectionTab(t, m).filterInput.Value() != "?" {
+		t.Error("Expected ? to be typed into the user filter")
+	}
+}
+
+// TestRemapKeys tests remapping bindings from the config file
+func TestRemapKeys(t *testing.T) {
+	saved := usersKeys
+	t.Cleanup(func() { usersKeys = saved })
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"keys": {"users.next_page": ["ctrl+n", "]"]}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig(path, true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if err := cfg.apply(); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	if !key.Matches(keyRunes("]"), usersKeys.NextPage) || key.Matches(keyRunes("n"), usersKeys.NextPage) {
+		t.Errorf("Expected ] instead of n for the next page, got keys %v", usersKeys.NextPage.Keys())
+	}
+	if h := usersKeys.NextPage.Help(); h.Key != "ctrl+n/]" || h.Desc != "next page" {
+		t.Errorf("Unexpected help %+v", h)
+	}
+
+	// Unknown bindings are rejected
+	if err := (Config{Keys: map[string][]string{"users.nope": {"x"}}}).apply(); err == nil {
+		t.Error("Expected an error for an unknown binding")
+	}
+
+	// A missing default config is fine, a missing explicit one isn't
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	if _, err := loadConfig(missing, false); err != nil {
+		t.Errorf("Unexpected error for a missing default config: %v", err)
+	}
+	if _, err := loadConfig(missing, true); err == nil {
+		t.Error("Expected an error for a missing config file")
+	}
+}
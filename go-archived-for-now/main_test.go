@@ -1,6 +1,9 @@
 package main
 
 import (
+	"arrogance/firebase"
+	"context"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -135,3 +138,85 @@ func TestNewModel(t *testing.T) {
 		t.Errorf("Expected message to be 'This is a TUI application built with Charm.', got '%s'", m.message)
 	}
 }
+
+// TestFetchUsers tests loading users from the in-memory backend
+func TestFetchUsers(t *testing.T) {
+	authSvc, _ := CreateDemoBackend(t)
+
+	msg := fetchUsers(authSvc, "")()
+	loaded, ok := msg.(usersLoadedMsg)
+	if !ok {
+		t.Fatalf("Expected usersLoadedMsg, got %T", msg)
+	}
+	if len(loaded.users) == 0 {
+		t.Error("Expected demo users to be loaded")
+	}
+
+	// An empty project should report no users rather than sample data
+	msg = fetchUsers(firebase.NewMemoryAuth(), "")()
+	loaded, ok = msg.(usersLoadedMsg)
+	if !ok {
+		t.Fatalf("Expected usersLoadedMsg, got %T", msg)
+	}
+	if len(loaded.users) != 0 {
+		t.Errorf("Expected no users, got %d", len(loaded.users))
+	}
+}
+
+// TestHomeViewEmulatorBadge tests that emulator connections are flagged
+func TestHomeViewEmulatorBadge(t *testing.T) {
+	home := func(client *firebase.AppClient) string {
+		s, _ := newHomeScreen(client, false, nil, nil).Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+		return s.View()
+	}
+
+	view := home(&firebase.AppClient{Emulator: true, ProjectID: "demo-test"})
+	if !strings.Contains(view, "EMULATOR") {
+		t.Error("Home view does not show the emulator badge")
+	}
+	if !strings.Contains(view, "demo-test") {
+		t.Error("Home view does not show the emulator project ID")
+	}
+
+	if strings.Contains(home(&firebase.AppClient{}), "EMULATOR") {
+		t.Error("Home view shows the emulator badge for a production connection")
+	}
+}
+
+// TestUsersPagination tests moving between pages of users
+func TestUsersPagination(t *testing.T) {
+	authSvc := firebase.NewMemoryAuth()
+	for i := 0; i < usersPageSize+50; i++ {
+		if _, err := authSvc.CreateUser(context.Background(), &firebase.UserToCreate{UID: fmt.Sprintf("user-%03d", i)}); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	m := newTestApp(t, authSvc, firebase.NewMemoryFirestore(), UsersTab)
+	users := func() usersScreen {
+		return m.router.top().(usersScreen)
+	}
+
+	if s := users(); len(s.users) != usersPageSize || s.nextPageToken == "" {
+		t.Fatalf("Expected a full first page with more available, got %d users", len(s.users))
+	}
+
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	if s := users(); s.page != 1 || len(s.users) != 50 {
+		t.Fatalf("Expected page 2 with 50 users, got page %d with %d users", s.page+1, len(s.users))
+	}
+	if status := users().pageStatus(); !strings.Contains(status, "Page 2") || !strings.Contains(status, "150 loaded") {
+		t.Errorf("Unexpected page status %q", status)
+	}
+
+	// There is no page after the last one
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	if s := users(); s.page != 1 {
+		t.Errorf("Expected to stay on the last page, got page %d", s.page+1)
+	}
+
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'p'}})
+	if s := users(); s.page != 0 || len(s.users) != usersPageSize {
+		t.Errorf("Expected page 1 with %d users, got page %d with %d users", usersPageSize, s.page+1, len(s.users))
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	modalStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("#FF5F87")).
+			Padding(1, 2)
+
+	modalTitleStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("#FF5F87")).
+			MarginBottom(1)
+
+	modalHelpStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240")).
+			MarginTop(1)
+
+	highlightStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(highlightColor)
+)
+
+// renderModal draws a dialog box centered in an area of the given size
+func renderModal(width, height int, title, body, help string) string {
+	content := modalTitleStyle.Render(title) + "\n" + body
+	if help != "" {
+		content += "\n" + modalHelpStyle.Render(help)
+	}
+
+	return lipgloss.Place(
+		width,
+		height,
+		lipgloss.Center,
+		lipgloss.Center,
+		modalStyle.Render(content),
+	)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// outputFormats are the formats commands print results in
+var outputFormats = []string{"table", "json", "yaml"}
+
+// maxCellWidth is where table cells are cut short
+const maxCellWidth = 48
+
+// checkOutputFormat reports an error for an unknown output format
+func checkOutputFormat(output string) error {
+	for _, f := range outputFormats {
+		if f == output {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q, use %s", output, strings.Join(outputFormats, ", "))
+}
+
+// printRecord prints a single exported record. With columns only those are
+// printed, in order; tables show a field per line.
+func printRecord(w io.Writer, output string, columns []string, record map[string]interface{}) error {
+	switch output {
+	case "json":
+		data, err := encodeRecord(record, columns)
+		if err != nil {
+			return err
+		}
+		return writeIndented(w, data)
+	case "yaml":
+		return writeYAML(w, yamlRecord(record, columns))
+	}
+
+	if len(columns) == 0 {
+		columns = recordColumns(record)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, column := range columns {
+		fmt.Fprintf(tw, "%s\t%s\n", column, exportCell(recordValue(record, column)))
+	}
+	return tw.Flush()
+}
+
+// printRecords prints exported records as a table with a row per record, or
+// as a JSON or YAML list. Without columns, tables show every field and JSON
+// and YAML the records as they are.
+func printRecords(w io.Writer, output string, columns []string, records []map[string]interface{}) error {
+	switch output {
+	case "json":
+		var buf bytes.Buffer
+		buf.WriteByte('[')
+		for i, record := range records {
+			data, err := encodeRecord(record, columns)
+			if err != nil {
+				return err
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(data)
+		}
+		buf.WriteByte(']')
+		return writeIndented(w, buf.Bytes())
+	case "yaml":
+		list := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, record := range records {
+			list.Content = append(list.Content, yamlRecord(record, columns))
+		}
+		return writeYAML(w, list)
+	}
+
+	if len(columns) == 0 {
+		columns = documentColumns(records)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = strings.ToUpper(column)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, record := range records {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = truncate(exportCell(recordValue(record, column)), maxCellWidth)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// recordColumns returns the top-level fields of a record, id and path first
+func recordColumns(record map[string]interface{}) []string {
+	return documentColumns([]map[string]interface{}{record})
+}
+
+// truncate cuts s to at most n runes, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// writeIndented writes compact JSON indented, with a final newline
+func writeIndented(w io.Writer, data []byte) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// yamlRecord builds a YAML mapping of the columns of a record, in order, or
+// of the whole record without columns
+func yamlRecord(record map[string]interface{}, columns []string) *yaml.Node {
+	if len(columns) == 0 {
+		whole := &yaml.Node{}
+		if err := whole.Encode(record); err == nil {
+			return whole
+		}
+		columns = recordColumns(record)
+	}
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, column := range columns {
+		value := &yaml.Node{}
+		if err := value.Encode(recordValue(record, column)); err != nil {
+			value = &yaml.Node{Kind: yaml.ScalarNode, Value: exportCell(recordValue(record, column))}
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: column}, value)
+	}
+	return node
+}
+
+// writeYAML writes a YAML document
+func writeYAML(w io.Writer, node *yaml.Node) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
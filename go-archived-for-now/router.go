@@ -0,0 +1,272 @@
+package main
+
+import (
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Screen is a single screen of the TUI. Each screen owns its state; the
+// router keeps a stack of screens per tab, so a screen can open another one
+// on top of itself and the one below is shown again when it's closed.
+type Screen interface {
+	// Init returns the command that loads the screen when it's opened
+	Init() tea.Cmd
+	// Update handles a message and returns the updated screen
+	Update(msg tea.Msg) (Screen, tea.Cmd)
+	// View renders the screen inside the content box
+	View() string
+	// KeyMap describes the keys the screen handles
+	KeyMap() help.KeyMap
+}
+
+// inputCapturer is implemented by screens that sometimes need every key,
+// such as while typing into a text input or while a delete is running,
+// so the router's navigation keys aren't applied
+type inputCapturer interface {
+	CapturesInput() bool
+}
+
+// loader is implemented by screens that show a spinner while loading
+type loader interface {
+	Loading() bool
+}
+
+// PushScreenMsg opens a screen on top of the active tab
+type pushScreenMsg struct {
+	screen Screen
+}
+
+// PopScreenMsg closes the top screen of the active tab
+type popScreenMsg struct{}
+
+// ScreenActivatedMsg is sent to the screen at the top of a tab whenever the
+// tab is shown, so it can refresh its data
+type screenActivatedMsg struct{}
+
+// pushScreen returns a command that opens screen on top of the active tab
+func pushScreen(screen Screen) tea.Cmd {
+	return func() tea.Msg {
+		return pushScreenMsg{screen: screen}
+	}
+}
+
+// popScreen is a command that closes the top screen of the active tab
+func popScreen() tea.Msg {
+	return popScreenMsg{}
+}
+
+// navKeyMap holds the keys the router handles for every screen
+type navKeyMap struct {
+	Quit      key.Binding
+	ForceQuit key.Binding
+	NextTab   key.Binding
+	PrevTab   key.Binding
+	Back      key.Binding
+	Help      key.Binding
+}
+
+var navKeys = navKeyMap{
+	Quit:      key.NewBinding(key.WithKeys("q"), key.WithHelp("q", "quit")),
+	ForceQuit: key.NewBinding(key.WithKeys("ctrl+c"), key.WithHelp("ctrl+c", "quit")),
+	NextTab:   key.NewBinding(key.WithKeys("tab", "right", "l"), key.WithHelp("tab/→", "next tab")),
+	PrevTab:   key.NewBinding(key.WithKeys("shift+tab", "left", "h"), key.WithHelp("shift+tab/←", "previous tab")),
+	Back:      key.NewBinding(key.WithKeys("q", "esc"), key.WithHelp("q/esc", "back")),
+	Help:      key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "help")),
+}
+
+// route is a tab of the app with the stack of screens opened in it.
+// The first screen of the stack is the one the tab starts with.
+type route struct {
+	title string
+	stack []Screen
+}
+
+// router shows one tab at a time and sends messages to its screens
+type router struct {
+	routes []route
+	active int
+}
+
+// newRouter creates a router for the given tabs, each starting with its root screen
+func newRouter(routes ...route) router {
+	return router{routes: routes}
+}
+
+// ready reports whether there are any tabs to show
+func (r router) ready() bool {
+	return len(r.routes) > 0
+}
+
+// top returns the screen shown in the active tab
+func (r router) top() Screen {
+	stack := r.routes[r.active].stack
+	return stack[len(stack)-1]
+}
+
+// depth returns the number of screens open in the active tab
+func (r router) depth() int {
+	return len(r.routes[r.active].stack)
+}
+
+// withStack returns a copy of r with the stack of the active tab replaced.
+// Routes are copied so earlier models never see later changes.
+func (r router) withStack(stack []Screen) router {
+	routes := make([]route, len(r.routes))
+	copy(routes, r.routes)
+	routes[r.active].stack = stack
+	r.routes = routes
+	return r
+}
+
+// setTop replaces the screen shown in the active tab
+func (r router) setTop(screen Screen) router {
+	stack := append([]Screen(nil), r.routes[r.active].stack...)
+	stack[len(stack)-1] = screen
+	return r.withStack(stack)
+}
+
+// push opens screen on top of the active tab
+func (r router) push(screen Screen) router {
+	stack := append([]Screen(nil), r.routes[r.active].stack...)
+	return r.withStack(append(stack, screen))
+}
+
+// pop closes the top screen of the active tab, keeping the tab's root screen
+func (r router) pop() router {
+	stack := r.routes[r.active].stack
+	if len(stack) <= 1 {
+		return r
+	}
+	return r.withStack(append([]Screen(nil), stack[:len(stack)-1]...))
+}
+
+// activate shows the tab at index, wrapping around, and lets its top screen refresh
+func (r router) activate(index int) (router, tea.Cmd) {
+	r.active = (index%len(r.routes) + len(r.routes)) % len(r.routes)
+	return r.updateTop(screenActivatedMsg{})
+}
+
+// updateTop sends a message to the screen shown in the active tab
+func (r router) updateTop(msg tea.Msg) (router, tea.Cmd) {
+	screen, cmd := r.top().Update(msg)
+	return r.setTop(screen), cmd
+}
+
+// broadcast sends a message to every open screen of every tab. Screens
+// ignore the results of commands they didn't start.
+func (r router) broadcast(msg tea.Msg) (router, tea.Cmd) {
+	routes := make([]route, len(r.routes))
+	var cmds []tea.Cmd
+	for i, rt := range r.routes {
+		stack := make([]Screen, len(rt.stack))
+		for j, screen := range rt.stack {
+			var cmd tea.Cmd
+			stack[j], cmd = screen.Update(msg)
+			cmds = append(cmds, cmd)
+		}
+		routes[i] = route{title: rt.title, stack: stack}
+	}
+	r.routes = routes
+	return r, tea.Batch(cmds...)
+}
+
+// handleKey applies the navigation keys and sends other keys to the top screen
+func (r router) handleKey(msg tea.KeyMsg) (router, tea.Cmd) {
+	if r.capturing() {
+		return r.updateTop(msg)
+	}
+
+	// Opened screens go back to the one below
+	if r.depth() > 1 {
+		if key.Matches(msg, navKeys.Back) {
+			return r.pop(), nil
+		}
+		return r.updateTop(msg)
+	}
+
+	switch {
+	case key.Matches(msg, navKeys.Quit):
+		return r, tea.Quit
+	case key.Matches(msg, navKeys.NextTab):
+		return r.activate(r.active + 1)
+	case key.Matches(msg, navKeys.PrevTab):
+		return r.activate(r.active - 1)
+	}
+	return r.updateTop(msg)
+}
+
+// loading reports whether any open screen is loading
+func (r router) loading() bool {
+	for _, rt := range r.routes {
+		for _, screen := range rt.stack {
+			if l, ok := screen.(loader); ok && l.Loading() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// titles returns the tab titles in order
+func (r router) titles() []string {
+	titles := make([]string, len(r.routes))
+	for i, rt := range r.routes {
+		titles[i] = rt.title
+	}
+	return titles
+}
+
+// capturing reports whether the top screen currently needs every key
+func (r router) capturing() bool {
+	c, ok := r.top().(inputCapturer)
+	return ok && c.CapturesInput()
+}
+
+// keyMap returns the keys that apply to the top screen, including the
+// navigation keys the router handles for it
+func (r router) keyMap() help.KeyMap {
+	keys := appKeyMap{screen: r.top().KeyMap()}
+	switch {
+	case r.capturing():
+		// Only the screen's own keys work while it captures input
+	case r.depth() > 1:
+		keys.nav = []key.Binding{navKeys.Back, navKeys.Help}
+	default:
+		keys.nav = []key.Binding{navKeys.NextTab, navKeys.PrevTab, navKeys.Help, navKeys.Quit}
+	}
+	return keys
+}
+
+// frame tracks the terminal size and spinner of a screen
+type frame struct {
+	width      int
+	height     int
+	spinnerIdx int
+}
+
+// track updates the frame from window size and tick messages
+func (f *frame) track(msg tea.Msg) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		f.width = msg.Width
+		f.height = msg.Height
+	case tickMsg:
+		f.spinnerIdx = (f.spinnerIdx + 1) % len(spinnerChars)
+	}
+}
+
+// spinner returns the current spinner character
+func (f frame) spinner() string {
+	return spinnerChars[f.spinnerIdx]
+}
+
+// statusBox renders a loading, error or empty message filling the content box
+func (f frame) statusBox(text string) string {
+	return lipgloss.NewStyle().
+		Width(f.width-8).
+		Height(f.height-10).
+		Padding(2, 2).
+		Render(text)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+)
+
+// tableSort is the column a table is sorted by and the direction
+type tableSort struct {
+	col  int
+	desc bool
+}
+
+// nextColumn sorts by the column after the current one, ascending, wrapping around
+func (ts tableSort) nextColumn(columns int) tableSort {
+	return tableSort{col: (ts.col + 1) % columns}
+}
+
+// reversed toggles between ascending and descending
+func (ts tableSort) reversed() tableSort {
+	ts.desc = !ts.desc
+	return ts
+}
+
+// headers returns the columns with the sorted one marked by ▲ or ▼
+func (ts tableSort) headers(columns []table.Column) []table.Column {
+	indicator := " ▲"
+	if ts.desc {
+		indicator = " ▼"
+	}
+
+	headers := make([]table.Column, len(columns))
+	copy(headers, columns)
+	if ts.col >= 0 && ts.col < len(headers) {
+		headers[ts.col].Title += indicator
+	}
+	return headers
+}
+
+// sortItems sorts items by the key of the sorted column. Items with equal
+// keys are ordered by tie, ascending, so the order never depends on how
+// the items were loaded.
+func sortItems[T any](items []T, ts tableSort, key func(item T, col int) string, tie func(item T) string) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := key(items[i], ts.col), key(items[j], ts.col)
+		if a == b {
+			return tie(items[i]) < tie(items[j])
+		}
+		if ts.desc {
+			return a > b
+		}
+		return a < b
+	})
+}
+
+// timestampKey returns a sort key for a millisecond timestamp
+func timestampKey(millis int64) string {
+	return int64Key(millis)
+}
+
+// int64Key returns a sort key for an integer. Flipping the sign bit maps
+// negative values below positive ones as unsigned, so the keys compare in
+// numeric order.
+func int64Key(v int64) string {
+	return fmt.Sprintf("%020d", uint64(v)^1<<63)
+}
+
+// timeKey returns a sort key for a time that orders by instant, with unset
+// times first
+func timeKey(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return int64Key(t.UnixNano())
+}
+
+// setSortedRows replaces the columns and rows of a table
+func setSortedRows(t table.Model, columns []table.Column, rows []table.Row) table.Model {
+	// Clear the rows first so the cursor stays in range while columns change
+	t.SetRows(nil)
+	t.SetColumns(columns)
+	t.SetRows(rows)
+	return t
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"strings"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -100,3 +101,79 @@ func TestStyleRendering(t *testing.T) {
 		t.Errorf("Fifth line should contain quit instructions, got: %s", lines[4])
 	}
 }
+
+// TestUsersSort tests sorting the users table by any column
+func TestUsersSort(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, UsersTab)
+	users := func() usersScreen {
+		return m.router.top().(usersScreen)
+	}
+
+	// Oldest first by default
+	s := users()
+	if title := s.table.Columns()[userCreatedColumn].Title; title != "Created ▲" {
+		t.Errorf("Expected the created column to be marked, got %q", title)
+	}
+	if s.shown[0].Email != "oscar.wijaya@example.com" {
+		t.Errorf("Expected the oldest user first, got %q", s.shown[0].Email)
+	}
+
+	// s moves to the next column ascending, S reverses it
+	m = settle(t, m, keyRunes("s"))
+	m = settle(t, m, keyRunes("s"))
+	m = settle(t, m, keyRunes("s"))
+	m = settle(t, m, keyRunes("s"))
+	s = users()
+	if s.sort.col != 1 || s.sort.desc {
+		t.Fatalf("Expected an ascending email sort, got column %d desc %v", s.sort.col, s.sort.desc)
+	}
+	// The user without an email sorts first, then by email
+	var emails []string
+	for _, row := range s.table.Rows() {
+		emails = append(emails, row[1])
+	}
+	want := ",dimas.pratama@example.com,lena.hartono@example.com,maya.santoso@example.com,oscar.wijaya@example.com,rina.kusuma@example.com"
+	if strings.Join(emails, ",") != want {
+		t.Errorf("Unexpected email order %v", emails)
+	}
+
+	m = settle(t, m, keyRunes("S"))
+	s = users()
+	if title := s.table.Columns()[1].Title; title != "Email ▼" || s.table.Rows()[0][1] != "rina.kusuma@example.com" {
+		t.Errorf("Expected a descending email sort, got header %q and first row %q", title, s.table.Rows()[0][1])
+	}
+
+	// The sort survives filtering
+	m = settle(t, m, keyRunes("/"))
+	m = settle(t, m, keyRunes("provider:password"))
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	rows := users().table.Rows()
+	for i := 1; i < len(rows); i++ {
+		if rows[i-1][1] < rows[i][1] {
+			t.Errorf("Filtered rows not sorted by email descending: %q before %q", rows[i-1][1], rows[i][1])
+		}
+	}
+}
+
+// TestTimeKey tests that time sort keys order by instant
+func TestTimeKey(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	ordered := []time.Time{
+		{},
+		time.Date(1950, 6, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(1969, 12, 31, 23, 59, 59, 0, time.UTC),
+		time.Date(2025, 9, 29, 6, 0, 5, 0, time.UTC),
+		time.Date(2025, 9, 29, 6, 0, 5, 500_000_000, time.UTC),
+		time.Date(2025, 9, 29, 14, 0, 0, 0, jakarta), // 07:00 UTC
+		time.Date(2025, 9, 29, 8, 0, 0, 0, time.UTC),
+	}
+	for i := 1; i < len(ordered); i++ {
+		if timeKey(ordered[i-1]) >= timeKey(ordered[i]) {
+			t.Errorf("Expected %v to sort before %v", ordered[i-1], ordered[i])
+		}
+	}
+	if timestampKey(-1) >= timestampKey(0) || timestampKey(0) >= timestampKey(1) {
+		t.Error("Expected timestamp keys in numeric order around zero")
+	}
+}
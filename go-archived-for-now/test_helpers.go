@@ -3,6 +3,7 @@ package main
 import (
 	"arrogance/firebase"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -50,3 +51,66 @@ func UpdateTestModel(t *testing.T, m *Model) {
 	// Initialize model with test values
 	m.spinnerIdx = 0
 }
+
+// CreateDemoBackend creates in-memory stores seeded with the demo fixture
+func CreateDemoBackend(t *testing.T) (*firebase.MemoryAuth, *firebase.MemoryFirestore) {
+	t.Helper()
+	authSvc, storeSvc, err := firebase.LoadDemoFixture()
+	if err != nil {
+		t.Fatalf("Failed to load demo fixture: %v", err)
+	}
+	return authSvc, storeSvc
+}
+
+// settle applies a message and then every message produced by the commands
+// that follow, the way the Bubble Tea runtime would. Commands that don't
+// return promptly, such as timers, are dropped.
+func settle(t *testing.T, m Model, msg tea.Msg) Model {
+	t.Helper()
+
+	queue := []tea.Msg{msg}
+	for n := 0; len(queue) > 0; n++ {
+		if n > 100 {
+			t.Fatal("Messages kept coming after 100 updates")
+		}
+		msg, queue = queue[0], queue[1:]
+
+		switch msg := msg.(type) {
+		case nil:
+			continue
+		case tea.BatchMsg:
+			for _, cmd := range msg {
+				queue = append(queue, runCmd(cmd))
+			}
+			continue
+		}
+
+		updated, cmd := m.Update(msg)
+		m = updated.(Model)
+		queue = append(queue, runCmd(cmd))
+	}
+	return m
+}
+
+// runCmd runs a command, giving up on it after a short wait
+func runCmd(cmd tea.Cmd) tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	result := make(chan tea.Msg, 1)
+	go func() { result <- cmd() }()
+	select {
+	case msg := <-result:
+		return msg
+	case <-time.After(50 * time.Millisecond):
+		return nil
+	}
+}
+
+// newTestApp creates an app connected to the given backend, showing a tab
+func newTestApp(t *testing.T, authSvc firebase.UserStore, storeSvc firebase.DocumentStore, tab int) Model {
+	t.Helper()
+	m := Model{title: "Test", width: 140, height: 50, loading: true}
+	m = settle(t, m, firebaseInitMsg{authSvc: authSvc, storeSvc: storeSvc})
+	return settle(t, m, tabChangeMsg{index: tab})
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"arrogance/firebase"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// userAction is an action on a user account that's confirmed before it runs
+type userAction int
+
+const (
+	// actionDisable disables the account and revokes its refresh tokens
+	actionDisable userAction = iota
+	// actionEnable enables a disabled account
+	actionEnable
+	// actionRevoke revokes the refresh tokens of the account
+	actionRevoke
+)
+
+// toggleDisabledAction returns the action that flips whether user is disabled
+func toggleDisabledAction(user *auth.UserRecord) userAction {
+	if user.Disabled {
+		return actionEnable
+	}
+	return actionDisable
+}
+
+// title describes the action in the confirmation modal
+func (a userAction) title() string {
+	switch a {
+	case actionDisable:
+		return "Disable user"
+	case actionEnable:
+		return "Enable user"
+	default:
+		return "Sign out everywhere"
+	}
+}
+
+// question asks for confirmation of running the action on the named user
+func (a userAction) question(name string) string {
+	switch a {
+	case actionDisable:
+		return fmt.Sprintf("Disable %s?", name)
+	case actionEnable:
+		return fmt.Sprintf("Enable %s?", name)
+	default:
+		return fmt.Sprintf("Sign %s out of every session?", name)
+	}
+}
+
+// consequences explains what the action does to the user
+func (a userAction) consequences() string {
+	switch a {
+	case actionDisable:
+		return "They can't sign in or refresh their sessions until enabled again.\nID tokens already issued stay valid for up to an hour."
+	case actionEnable:
+		return "They can sign in again."
+	default:
+		return "Every refresh token is revoked, so each session ends when its\nID token expires, within an hour."
+	}
+}
+
+// past describes the finished action
+func (a userAction) past() string {
+	switch a {
+	case actionDisable:
+		return "Disabled and signed out"
+	case actionEnable:
+		return "Enabled"
+	default:
+		return "Signed out"
+	}
+}
+
+// UserActionDoneMsg is sent with the updated user when an action has run
+type userActionDoneMsg struct {
+	action userAction
+	user   *auth.UserRecord
+}
+
+// UserActionErrorMsg is sent when an action on a user fails
+type userActionErrorMsg struct {
+	uid string
+	err error
+}
+
+// runUserAction runs an action on a user and loads the updated record
+func runUserAction(authSvc firebase.UserStore, uid string, action userAction) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+
+		var err error
+		switch action {
+		case actionDisable:
+			// Disabling alone leaves current sessions able to refresh until they next try
+			err = authSvc.UpdateUser(ctx, uid, &firebase.UserToUpdate{Disabled: firebase.Bool(true)})
+			if err == nil {
+				err = authSvc.RevokeRefreshTokens(ctx, uid)
+			}
+		case actionEnable:
+			err = authSvc.UpdateUser(ctx, uid, &firebase.UserToUpdate{Disabled: firebase.Bool(false)})
+		case actionRevoke:
+			err = authSvc.RevokeRefreshTokens(ctx, uid)
+		}
+		if err != nil {
+			return userActionErrorMsg{uid: uid, err: err}
+		}
+
+		user, err := authSvc.GetUser(ctx, uid)
+		if err != nil {
+			return userActionErrorMsg{uid: uid, err: fmt.Errorf("user was updated but couldn't be reloaded: %w", err)}
+		}
+		return userActionDoneMsg{action: action, user: user}
+	}
+}
+
+var actionConfirmKeys = inputKeyMap{
+	Submit: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "confirm")),
+	Cancel: key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+}
+
+// userActionScreen asks for confirmation before running an action on a user
+type userActionScreen struct {
+	frame
+	authSvc firebase.UserStore
+	keys    inputKeyMap
+
+	user    *auth.UserRecord
+	action  userAction
+	running bool
+	err     string
+}
+
+// newUserActionScreen creates the confirmation for running action on user
+func newUserActionScreen(authSvc firebase.UserStore, user *auth.UserRecord, action userAction) userActionScreen {
+	return userActionScreen{
+		authSvc: authSvc,
+		keys:    actionConfirmKeys,
+		user:    user,
+		action:  action,
+	}
+}
+
+// Init has nothing to load
+func (s userActionScreen) Init() tea.Cmd {
+	return nil
+}
+
+// KeyMap returns the keys of the confirmation
+func (s userActionScreen) KeyMap() help.KeyMap {
+	return s.keys
+}
+
+// Loading reports whether the action is running
+func (s userActionScreen) Loading() bool {
+	return s.running
+}
+
+// CapturesInput keeps every key on the confirmation, which is closed with its cancel key
+func (s userActionScreen) CapturesInput() bool {
+	return true
+}
+
+// Update runs the action once confirmed and closes when it's done
+func (s userActionScreen) Update(msg tea.Msg) (Screen, tea.Cmd) {
+	s.track(msg)
+
+	switch msg := msg.(type) {
+	case userActionDoneMsg:
+		if s.running && msg.user.UID == s.user.UID {
+			return s, popScreen
+		}
+
+	case userActionErrorMsg:
+		if s.running && msg.uid == s.user.UID {
+			s.running = false
+			s.err = msg.err.Error()
+		}
+
+	case tea.KeyMsg:
+		if s.running {
+			return s, nil
+		}
+		switch {
+		case key.Matches(msg, s.keys.Cancel):
+			return s, popScreen
+		case key.Matches(msg, s.keys.Submit):
+			s.running = true
+			s.err = ""
+			return s, runUserAction(s.authSvc, s.user.UID, s.action)
+		}
+	}
+
+	return s, nil
+}
+
+// View shows the confirmation in a modal
+func (s userActionScreen) View() string {
+	name := s.user.Email
+	if name == "" {
+		name = s.user.UID
+	}
+	body := s.action.question(highlightStyle.Render(name)) + "\n\n" + s.action.consequences()
+
+	if s.running {
+		body += "\n\n" + loadingStyle.UnsetMarginLeft().Render(s.spinner()+" Working...")
+	} else if s.err != "" {
+		body += "\n\n" + errorStyle.UnsetMarginLeft().Width(60).Render(s.err)
+	}
+
+	return renderModal(s.width-8, s.height-12, s.action.title(), body, newHelp().View(s.keys))
+}
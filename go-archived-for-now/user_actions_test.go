@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TestDisableUser tests disabling and enabling a user from the users table
+func TestDisableUser(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, UsersTab)
+	const uid = "3xKq9PzR1vT7bN2mYc5LwA8dFe4H"
+
+	// Select Maya, who's enabled
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyDown})
+
+	// Cancelling the confirmation leaves the user alone
+	m = settle(t, m, keyRunes("x"))
+	if _, ok := m.router.top().(userActionScreen); !ok {
+		t.Fatalf("Expected the confirmation, got %T", m.router.top())
+	}
+	if view := m.View(); !strings.Contains(view, "Disable maya.santoso@example.com?") {
+		t.Errorf("Confirmation does not name the user:\n%s", view)
+	}
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	if user, _ := authSvc.GetUser(context.Background(), uid); user.Disabled {
+		t.Fatal("Expected cancelling to leave the user enabled")
+	}
+
+	m = settle(t, m, keyRunes("x"))
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	users, ok := m.router.top().(usersScreen)
+	if !ok {
+		t.Fatalf("Expected to return to the users table, got %T", m.router.top())
+	}
+	user, _ := authSvc.GetUser(context.Background(), uid)
+	if !user.Disabled || user.TokensValidAfterMillis == 0 {
+		t.Fatal("Expected the user to be disabled and signed out")
+	}
+	if row := users.table.SelectedRow(); row[0] != uid || row[3] != "disabled" {
+		t.Errorf("Expected the selected row to show the user as disabled, got %v", row)
+	}
+	if !strings.Contains(users.notice, "Disabled and signed out user "+uid) {
+		t.Errorf("Unexpected notice %q", users.notice)
+	}
+
+	// The same key enables the user again
+	m = settle(t, m, keyRunes("x"))
+	if view := m.View(); !strings.Contains(view, "Enable maya.santoso@example.com?") {
+		t.Errorf("Expected the confirmation to enable the user:\n%s", view)
+	}
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if user, _ := authSvc.GetUser(context.Background(), uid); user.Disabled {
+		t.Fatal("Expected the user to be enabled")
+	}
+}
+
+// TestRevokeFromDetail tests signing a user out everywhere from their detail screen
+func TestRevokeFromDetail(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, UsersTab)
+
+	// Oscar is disabled, so the detail shows a badge
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if view := m.View(); !strings.Contains(view, "DISABLED") {
+		t.Errorf("Expected the disabled badge:\n%s", view)
+	}
+
+	m = settle(t, m, keyRunes("r"))
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	detail, ok := m.router.top().(userDetailScreen)
+	if !ok {
+		t.Fatalf("Expected to return to the user detail, got %T", m.router.top())
+	}
+	if detail.user.TokensValidAfterMillis == 0 {
+		t.Error("Expected the detail to show the revoked tokens")
+	}
+}
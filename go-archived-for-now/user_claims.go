@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"arrogance/firebase"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// claimsPreset is a role the app grants through custom claims
+type claimsPreset struct {
+	name   string
+	claims map[string]interface{}
+}
+
+// claimsPresets are the roles the app knows about. The app checks the admin
+// claim for admin features and the role claim for everything else.
+var claimsPresets = []claimsPreset{
+	{name: "admin", claims: map[string]interface{}{"admin": true}},
+	{name: "coach", claims: map[string]interface{}{"role": "coach"}},
+	{name: "member", claims: map[string]interface{}{}},
+}
+
+// changes returns the claim changes that give a user the role, removing the
+// role claims of the other presets and keeping any other claims
+func (p claimsPreset) changes() map[string]interface{} {
+	changes := map[string]interface{}{}
+	for _, other := range claimsPresets {
+		for name := range other.claims {
+			changes[name] = nil
+		}
+	}
+	for name, value := range p.claims {
+		changes[name] = value
+	}
+	return changes
+}
+
+// parseClaims parses the claims typed into the editor. Empty text clears the claims.
+func parseClaims(text string) (map[string]interface{}, error) {
+	if strings.TrimSpace(text) == "" {
+		return map[string]interface{}{}, nil
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &claims); err != nil {
+		return nil, fmt.Errorf("not a JSON object: %v", err)
+	}
+	if claims == nil {
+		return nil, errors.New("not a JSON object")
+	}
+	if err := firebase.ValidateCustomClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// formatClaims formats claims for the editor
+func formatClaims(claims map[string]interface{}) string {
+	if len(claims) == 0 {
+		return "{}"
+	}
+	formatted, err := json.MarshalIndent(claims, "", "  ")
+	if err != nil {
+		return fmt.Sprint(claims)
+	}
+	return string(formatted)
+}
+
+// claimsSize describes how much of the size limit claims use
+func claimsSize(claims map[string]interface{}) string {
+	size, _ := firebase.CustomClaimsSize(claims)
+	return fmt.Sprintf("%d / %d bytes", size, firebase.MaxCustomClaimsSize)
+}
+
+// claimsEditorKeyMap holds the keys of the claims editor
+type claimsEditorKeyMap struct {
+	Preset key.Binding
+	Save   key.Binding
+	Cancel key.Binding
+}
+
+var claimsEditorKeys = claimsEditorKeyMap{
+	Preset: key.NewBinding(key.WithKeys("ctrl+p"), key.WithHelp("ctrl+p", "next role preset")),
+	Save:   key.NewBinding(key.WithKeys("ctrl+s"), key.WithHelp("ctrl+s", "save")),
+	Cancel: key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+}
+
+// ShortHelp returns the keys shown in the footer
+func (k claimsEditorKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Preset, k.Save, k.Cancel}
+}
+
+// FullHelp returns every key of the editor
+func (k claimsEditorKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+// claimsEditorScreen edits the custom claims of a user as JSON
+type claimsEditorScreen struct {
+	frame
+	authSvc firebase.UserStore
+	keys    claimsEditorKeyMap
+
+	user    *auth.UserRecord
+	editor  textarea.Model
+	preset  int // index of the last preset applied, -1 before any
+	claims  map[string]interface{}
+	invalid string
+	saving  bool
+	err     string
+}
+
+// newClaimsEditorScreen creates the claims editor for a user
+func newClaimsEditorScreen(authSvc firebase.UserStore, user *auth.UserRecord) claimsEditorScreen {
+	editor := textarea.New()
+	editor.ShowLineNumbers = false
+	editor.CharLimit = 4 * firebase.MaxCustomClaimsSize
+	editor.SetWidth(60)
+	editor.SetHeight(12)
+	editor.SetValue(formatClaims(user.CustomClaims))
+	editor.Focus()
+
+	s := claimsEditorScreen{
+		authSvc: authSvc,
+		keys:    claimsEditorKeys,
+		user:    user,
+		editor:  editor,
+		preset:  -1,
+	}
+	return s.validate()
+}
+
+// Init starts the cursor blinking
+func (s claimsEditorScreen) Init() tea.Cmd {
+	return textarea.Blink
+}
+
+// KeyMap returns the keys of the editor, leaving out saving invalid claims
+func (s claimsEditorScreen) KeyMap() help.KeyMap {
+	k := s.keys
+	k.Save.SetEnabled(s.invalid == "")
+	return k
+}
+
+// Loading reports whether the claims are being saved
+func (s claimsEditorScreen) Loading() bool {
+	return s.saving
+}
+
+// CapturesInput keeps every key on the editor, which is closed with its cancel key
+func (s claimsEditorScreen) CapturesInput() bool {
+	return true
+}
+
+// Update handles editing and saving the claims
+func (s claimsEditorScreen) Update(msg tea.Msg) (Screen, tea.Cmd) {
+	s.track(msg)
+
+	switch msg := msg.(type) {
+	case userSavedMsg:
+		if s.saving && msg.user.UID == s.user.UID {
+			return s, popScreen
+		}
+		return s, nil
+
+	case userSaveErrorMsg:
+		if s.saving && msg.uid == s.user.UID {
+			s.saving = false
+			s.err = msg.err.Error()
+		}
+		return s, nil
+
+	case tea.KeyMsg:
+		if s.saving {
+			return s, nil
+		}
+		switch {
+		case key.Matches(msg, s.keys.Cancel):
+			return s, popScreen
+		case key.Matches(msg, s.keys.Save):
+			if s.invalid != "" {
+				return s, nil
+			}
+			s.saving = true
+			s.err = ""
+			return s, setUserClaims(s.authSvc, s.user.UID, s.claims)
+		case key.Matches(msg, s.keys.Preset):
+			return s.applyPreset(), nil
+		}
+	}
+
+	var cmd tea.Cmd
+	s.editor, cmd = s.editor.Update(msg)
+	if _, ok := msg.(tea.KeyMsg); ok {
+		s = s.validate()
+	}
+	return s, cmd
+}
+
+// applyPreset gives the edited claims the next role preset. The claims being
+// edited must be valid so that other claims can be kept.
+func (s claimsEditorScreen) applyPreset() claimsEditorScreen {
+	if s.invalid != "" {
+		return s
+	}
+	s.preset = (s.preset + 1) % len(claimsPresets)
+	claims := firebase.MergeCustomClaims(s.claims, claimsPresets[s.preset].changes())
+	s.editor.SetValue(formatClaims(claims))
+	return s.validate()
+}
+
+// validate parses the edited claims, keeping the problem to show below the editor
+func (s claimsEditorScreen) validate() claimsEditorScreen {
+	claims, err := parseClaims(s.editor.Value())
+	if err != nil {
+		s.invalid = err.Error()
+		return s
+	}
+	s.invalid = ""
+	s.claims = claims
+	return s
+}
+
+// View shows the editor in a modal
+func (s claimsEditorScreen) View() string {
+	name := s.user.Email
+	if name == "" {
+		name = s.user.UID
+	}
+
+	presets := make([]string, len(claimsPresets))
+	for i, p := range claimsPresets {
+		presets[i] = p.name
+		if i == s.preset {
+			presets[i] = highlightStyle.Render(p.name)
+		}
+	}
+
+	body := s.editor.View() + "\n\n"
+	if s.invalid != "" {
+		body += errorStyle.UnsetMarginLeft().Width(60).Render(s.invalid)
+	} else {
+		body += successStyle.UnsetMarginLeft().Render("Valid · " + claimsSize(s.claims))
+	}
+	body += "\n" + labelStyle.Render("Role presets") + strings.Join(presets, " · ")
+
+	if s.saving {
+		body += "\n\n" + loadingStyle.UnsetMarginLeft().Render(s.spinner()+" Saving...")
+	} else if s.err != "" {
+		body += "\n\n" + errorStyle.UnsetMarginLeft().Width(60).Render(s.err)
+	}
+
+	return renderModal(s.width-8, s.height-12, "Custom claims of "+name, body, newHelp().View(s.KeyMap()))
+}
+
+// setUserClaims replaces the claims of a user and loads the updated record
+func setUserClaims(authSvc firebase.UserStore, uid string, claims map[string]interface{}) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		if err := authSvc.SetCustomUserClaims(ctx, uid, claims); err != nil {
+			return userSaveErrorMsg{uid: uid, err: err}
+		}
+		user, err := authSvc.GetUser(ctx, uid)
+		if err != nil {
+			return userSaveErrorMsg{uid: uid, err: fmt.Errorf("claims were saved but the user couldn't be reloaded: %w", err)}
+		}
+		return userSavedMsg{user: user}
+	}
+}
+
+// RolesGrantedMsg is sent when a role has been granted to several users
+type rolesGrantedMsg struct {
+	role    string
+	summary *firebase.GrantSummary
+}
+
+// grantRole grants a role preset to every user in uids
+func grantRole(authSvc firebase.UserStore, uids []string, preset claimsPreset) tea.Cmd {
+	return func() tea.Msg {
+		summary := firebase.GrantClaims(context.Background(), authSvc, uids, preset.changes(), nil)
+		return rolesGrantedMsg{role: preset.name, summary: summary}
+	}
+}
+
+// grantSummaryText describes the outcome of granting a role
+func grantSummaryText(msg rolesGrantedMsg) string {
+	text := fmt.Sprintf("Granted %s to %d %s", msg.role, len(msg.summary.Updated), plural(len(msg.summary.Updated), "user", "users"))
+	if len(msg.summary.Failed) == 0 {
+		return text
+	}
+
+	uids := make([]string, 0, len(msg.summary.Failed))
+	for uid := range msg.summary.Failed {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	failures := make([]string, len(uids))
+	for i, uid := range uids {
+		failures[i] = fmt.Sprintf("%s: %v", uid, msg.summary.Failed[uid])
+	}
+	return text + fmt.Sprintf(", %d failed (%s)", len(uids), strings.Join(failures, "; "))
+}
+
+// plural returns one or many depending on n
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// grantRoleKeyMap holds the keys of the grant role dialog
+type grantRoleKeyMap struct {
+	Up     key.Binding
+	Down   key.Binding
+	Submit key.Binding
+	Cancel key.Binding
+}
+
+var grantRoleKeys = grantRoleKeyMap{
+	Up:     key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "up")),
+	Down:   key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "down")),
+	Submit: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "grant")),
+	Cancel: key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel")),
+}
+
+// ShortHelp returns the keys shown in the footer
+func (k grantRoleKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Up, k.Down, k.Submit, k.Cancel}
+}
+
+// FullHelp returns every key of the dialog
+func (k grantRoleKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+// grantRoleScreen picks a role preset and grants it to several users
+type grantRoleScreen struct {
+	frame
+	authSvc firebase.UserStore
+	keys    grantRoleKeyMap
+
+	uids    []string
+	cursor  int
+	running bool
+}
+
+// newGrantRoleScreen creates the dialog granting a role to the users in uids
+func newGrantRoleScreen(authSvc firebase.UserStore, uids []string) grantRoleScreen {
+	return grantRoleScreen{authSvc: authSvc, keys: grantRoleKeys, uids: uids}
+}
+
+// Init has nothing to load
+func (s grantRoleScreen) Init() tea.Cmd {
+	return nil
+}
+
+// KeyMap returns the keys of the dialog
+func (s grantRoleScreen) KeyMap() help.KeyMap {
+	return s.keys
+}
+
+// Loading reports whether the role is being granted
+func (s grantRoleScreen) Loading() bool {
+	return s.running
+}
+
+// CapturesInput keeps every key on the dialog, which is closed with its cancel key
+func (s grantRoleScreen) CapturesInput() bool {
+	return true
+}
+
+// Update picks a preset and grants it, closing once every user is updated
+func (s grantRoleScreen) Update(msg tea.Msg) (Screen, tea.Cmd) {
+	s.track(msg)
+
+	switch msg := msg.(type) {
+	case rolesGrantedMsg:
+		// The users screen reports the outcome
+		if s.running {
+			return s, popScreen
+		}
+
+	case tea.KeyMsg:
+		if s.running {
+			return s, nil
+		}
+		switch {
+		case key.Matches(msg, s.keys.Cancel):
+			return s, popScreen
+		case key.Matches(msg, s.keys.Up):
+			s.cursor = (s.cursor + len(claimsPresets) - 1) % len(claimsPresets)
+		case key.Matches(msg, s.keys.Down):
+			s.cursor = (s.cursor + 1) % len(claimsPresets)
+		case key.Matches(msg, s.keys.Submit):
+			s.running = true
+			return s, grantRole(s.authSvc, s.uids, claimsPresets[s.cursor])
+		}
+	}
+
+	return s, nil
+}
+
+// View shows the presets in a modal
+func (s grantRoleScreen) View() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Grant a role to %d %s. Other claims are kept.\n\n", len(s.uids), plural(len(s.uids), "user", "users")))
+	for i, p := range claimsPresets {
+		line := fmt.Sprintf("  %-8s %s", p.name, formatClaimsInline(p.claims))
+		if i == s.cursor {
+			line = highlightStyle.Render(fmt.Sprintf("> %-8s %s", p.name, formatClaimsInline(p.claims)))
+		}
+		sb.WriteString(line + "\n")
+	}
+	if s.running {
+		sb.WriteString("\n" + loadingStyle.UnsetMarginLeft().Render(s.spinner()+" Granting..."))
+	}
+
+	return renderModal(s.width-8, s.height-12, "Grant role", strings.TrimSuffix(sb.String(), "\n"), newHelp().View(s.keys))
+}
+
+// formatClaimsInline formats claims on a single line
+func formatClaimsInline(claims map[string]interface{}) string {
+	if len(claims) == 0 {
+		return "no role claims"
+	}
+	formatted, err := json.Marshal(claims)
+	if err != nil {
+		return fmt.Sprint(claims)
+	}
+	return string(formatted)
+}
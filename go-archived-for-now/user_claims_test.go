@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TestEditClaims tests validating and saving custom claims from the user detail screen
+func TestEditClaims(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, UsersTab)
+	const uid = "3xKq9PzR1vT7bN2mYc5LwA8dFe4H"
+
+	// Open Maya, who's an admin
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if view := m.View(); !strings.Contains(view, "14 / 1000 bytes") {
+		t.Errorf("Expected the claims size in the detail:\n%s", view)
+	}
+
+	m = settle(t, m, keyRunes("c"))
+	if _, ok := m.router.top().(claimsEditorScreen); !ok {
+		t.Fatalf("Expected the claims editor, got %T", m.router.top())
+	}
+
+	// Invalid JSON can't be saved
+	m = settle(t, m, keyRunes("x"))
+	if s := m.router.top().(claimsEditorScreen); !strings.Contains(s.invalid, "not a JSON object") {
+		t.Errorf("Expected invalid JSON to be reported, got %q", s.invalid)
+	}
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyCtrlS})
+	if _, ok := m.router.top().(claimsEditorScreen); !ok {
+		t.Fatal("Expected the editor to stay open with invalid claims")
+	}
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyBackspace})
+
+	// The second preset replaces the admin claim with the coach role
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyCtrlP})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyCtrlP})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyCtrlS})
+	if _, ok := m.router.top().(userDetailScreen); !ok {
+		t.Fatalf("Expected to return to the user detail, got %T", m.router.top())
+	}
+	user, _ := authSvc.GetUser(context.Background(), uid)
+	if len(user.CustomClaims) != 1 || user.CustomClaims["role"] != "coach" {
+		t.Errorf("CustomClaims = %v, want only the coach role", user.CustomClaims)
+	}
+	if view := m.View(); !strings.Contains(view, `"role": "coach"`) {
+		t.Errorf("Expected the detail to show the new claims:\n%s", view)
+	}
+}
+
+// TestGrantRole tests granting a role to the selected users
+func TestGrantRole(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, UsersTab)
+
+	// Select Oscar and Maya
+	m = settle(t, m, keyRunes(" "))
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	m = settle(t, m, keyRunes(" "))
+	users := m.router.top().(usersScreen)
+	if len(users.marked) != 2 || !strings.Contains(users.pageStatus(), "2 selected") {
+		t.Fatalf("Expected 2 selected users, got %v", users.marked)
+	}
+
+	m = settle(t, m, keyRunes("g"))
+	if view := m.View(); !strings.Contains(view, "Grant a role to 2 users") {
+		t.Errorf("Expected the grant dialog:\n%s", view)
+	}
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	users, ok := m.router.top().(usersScreen)
+	if !ok {
+		t.Fatalf("Expected to return to the users table, got %T", m.router.top())
+	}
+	if users.notice != "Granted coach to 2 users" || len(users.marked) != 0 {
+		t.Errorf("Unexpected notice %q with %d still selected", users.notice, len(users.marked))
+	}
+	for _, u := range users.shown[:2] {
+		user, _ := authSvc.GetUser(context.Background(), u.UID)
+		if len(user.CustomClaims) != 1 || user.CustomClaims["role"] != "coach" {
+			t.Errorf("CustomClaims of %s = %v, want only the coach role", u.UID, user.CustomClaims)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"arrogance/firebase"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DeleteProgressMsg is sent while a user's data is being deleted
+type deleteProgressMsg struct {
+	uid      string
+	progress firebase.DeleteProgress
+	updates  <-chan tea.Msg
+}
+
+// UserDeletedMsg is sent when a cascading delete finishes or fails
+type userDeletedMsg struct {
+	uid     string
+	summary *firebase.DeleteSummary
+	err     error
+}
+
+// deleteConfirmation returns the text the user must type to confirm deleting user
+func (s userDetailScreen) deleteConfirmation() string {
+	if s.user != nil && s.user.Email != "" {
+		return s.user.Email
+	}
+	return s.uid
+}
+
+// openDeleteModal asks for typed confirmation before deleting the open user
+func (s userDetailScreen) openDeleteModal() userDetailScreen {
+	input := textinput.New()
+	input.Placeholder = s.deleteConfirmation()
+	input.CharLimit = 256
+	input.Width = 40
+	input.Focus()
+
+	s.deleteModal = true
+	s.deleteInput = input
+	s.deleteError = ""
+	return s
+}
+
+// updateDeleteModal handles keys while the delete confirmation is open
+func (s userDetailScreen) updateDeleteModal(msg tea.KeyMsg) (Screen, tea.Cmd) {
+	switch {
+	case key.Matches(msg, s.confirmKeys.Cancel):
+		// Only close the modal
+		s.deleteModal = false
+		return s, nil
+	case key.Matches(msg, s.confirmKeys.Submit):
+		if strings.TrimSpace(s.deleteInput.Value()) != s.deleteConfirmation() {
+			s.deleteError = "Confirmation does not match"
+			return s, nil
+		}
+		s.deleteModal = false
+		s.deleting = true
+		s.deleteError = ""
+		s.deleteProgress = firebase.DeleteProgress{Stage: "Starting"}
+		return s, startUserDelete(s.authSvc, s.storeSvc, s.uid)
+	}
+
+	var cmd tea.Cmd
+	s.deleteInput, cmd = s.deleteInput.Update(msg)
+	return s, cmd
+}
+
+// startUserDelete deletes a user and all their data in the background,
+// reporting progress through messages
+func startUserDelete(authSvc firebase.UserStore, storeSvc firebase.DocumentStore, uid string) tea.Cmd {
+	updates := make(chan tea.Msg, 1)
+
+	go func() {
+		defer close(updates)
+		summary, err := firebase.DeleteUserAndData(context.Background(), authSvc, storeSvc, uid, func(p firebase.DeleteProgress) {
+			updates <- deleteProgressMsg{uid: uid, progress: p, updates: updates}
+		})
+		updates <- userDeletedMsg{uid: uid, summary: summary, err: err}
+	}()
+
+	return waitForDelete(updates)
+}
+
+// waitForDelete waits for the next update from a running delete
+func waitForDelete(updates <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-updates
+	}
+}
+
+// deleteModalView renders the typed confirmation dialog
+func (s userDetailScreen) deleteModalView(width, height int) string {
+	body := fmt.Sprintf(
+		"This permanently deletes %s, their profiles and records,\nexercises, histories and routines.\n\nType %s to confirm:\n\n%s",
+		s.uid,
+		highlightStyle.Render(s.deleteConfirmation()),
+		s.deleteInput.View(),
+	)
+	if s.deleteError != "" {
+		body += "\n\n" + errorStyle.UnsetMarginLeft().Render(s.deleteError)
+	}
+
+	return renderModal(width, height, "Delete user and all data", body, newHelp().View(s.confirmKeys))
+}
+
+// deleteProgressView renders the progress of a running delete
+func (s userDetailScreen) deleteProgressView() string {
+	p := s.deleteProgress
+	status := fmt.Sprintf("%s Deleting user %s... %s", s.spinner(), s.uid, p.Stage)
+	if p.Total > 0 {
+		status += fmt.Sprintf(" (%d/%d documents)", p.Deleted, p.Total)
+	}
+	return loadingStyle.Render(status)
+}
+
+// deleteSummaryText describes a finished delete for the users list
+func deleteSummaryText(uid string, summary *firebase.DeleteSummary) string {
+	var parts []string
+	for _, collection := range firebase.UserCollections {
+		if n := summary.Documents[collection]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, collection))
+		}
+		for _, sub := range firebase.UserSubcollections[collection] {
+			if n := summary.Documents[sub]; n > 0 {
+				parts = append(parts, fmt.Sprintf("%d %s", n, sub))
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("Deleted user %s, who had no documents", uid)
+	}
+	return fmt.Sprintf("Deleted user %s and %s", uid, strings.Join(parts, ", "))
+}
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"arrogance/firebase"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	sectionStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("#FF5F87"))
+
+	labelStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240")).
+			Width(18)
+)
+
+// UserDetailLoadedMsg is sent when a user and their documents are loaded
+type userDetailLoadedMsg struct {
+	user  *auth.UserRecord
+	items map[string][]collectionItem // collection -> items
+}
+
+// UserDetailErrorMsg is sent when there's an error loading a user's details
+type userDetailErrorMsg struct {
+	uid string
+	err error
+}
+
+// fetchUserDetail fetches a user record and their documents from every user collection
+func fetchUserDetail(authSvc firebase.UserStore, storeSvc firebase.DocumentStore, uid string) tea.Cmd {
+	return func() tea.Msg {
+		if authSvc == nil || storeSvc == nil {
+			return userDetailErrorMsg{uid: uid, err: errors.New("firebase services not initialized")}
+		}
+
+		ctx := context.Background()
+
+		user, err := authSvc.GetUser(ctx, uid)
+		if err != nil {
+			return userDetailErrorMsg{uid: uid, err: fmt.Errorf("failed to fetch user: %w", err)}
+		}
+
+		loaders := map[string]func() ([]collectionItem, error){
+			"profiles": func() ([]collectionItem, error) {
+				return userItems(ctx, storeSvc, uid, firebase.ProfilesByUser, profileItem)
+			},
+			"exercises": func() ([]collectionItem, error) {
+				return userItems(ctx, storeSvc, uid, firebase.ExercisesByUser, exerciseItem)
+			},
+			"histories": func() ([]collectionItem, error) {
+				return userItems(ctx, storeSvc, uid, firebase.HistoriesByUser, historyItem)
+			},
+			"routines": func() ([]collectionItem, error) {
+				return userItems(ctx, storeSvc, uid, firebase.RoutinesByUser, routineItem)
+			},
+		}
+		items := make(map[string][]collectionItem, len(loaders))
+		for collection, load := range loaders {
+			if items[collection], err = load(); err != nil {
+				return userDetailErrorMsg{uid: uid, err: err}
+			}
+		}
+		for i, profile := range items["profiles"] {
+			if items["profiles"][i].records, err = firebase.RecordsOfProfile(ctx, storeSvc, profile.id); err != nil {
+				return userDetailErrorMsg{uid: uid, err: err}
+			}
+		}
+
+		return userDetailLoadedMsg{user: user, items: items}
+	}
+}
+
+// userItems loads the models a user owns with byUser and converts them to items
+func userItems[T any](ctx context.Context, storeSvc firebase.DocumentStore, uid string, byUser func(context.Context, firebase.DocumentStore, string) ([]T, error), item func(T) collectionItem) ([]collectionItem, error) {
+	models, err := byUser(ctx, storeSvc, uid)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]collectionItem, len(models))
+	for i, m := range models {
+		items[i] = item(m)
+	}
+	return items, nil
+}
+
+// userDetailScreen shows a single user with their linked Firestore data
+type userDetailScreen struct {
+	frame
+	authSvc  firebase.UserStore
+	storeSvc firebase.DocumentStore
+	keys     userDetailKeyMap
+
+	// Keys of the delete confirmation
+	confirmKeys inputKeyMap
+
+	uid      string
+	user     *auth.UserRecord
+	items    map[string][]collectionItem
+	loading  bool
+	err      string
+	viewport viewport.Model
+
+	// Cascading delete state
+	deleteModal    bool
+	deleteInput    textinput.Model
+	deleting       bool
+	deleteProgress firebase.DeleteProgress
+	deleteError    string
+}
+
+// userDetailKeyMap holds the keys of the user detail screen
+type userDetailKeyMap struct {
+	Up      key.Binding
+	Down    key.Binding
+	Delete  key.Binding
+	Edit    key.Binding
+	Disable key.Binding
+	Revoke  key.Binding
+	Claims  key.Binding
+	Links   key.Binding
+}
+
+var userDetailKeys = userDetailKeyMap{
+	Up:      key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "scroll up")),
+	Down:    key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "scroll down")),
+	Delete:  key.NewBinding(key.WithKeys("ctrl+d"), key.WithHelp("ctrl+d", "delete user and data")),
+	Edit:    key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit user")),
+	Disable: key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "disable/enable user")),
+	Revoke:  key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "sign out everywhere")),
+	Claims:  key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "edit claims")),
+	Links:   key.NewBinding(key.WithKeys("l"), key.WithHelp("l", "email links")),
+}
+
+// ShortHelp returns the keys shown in the footer
+func (k userDetailKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Up, k.Down, k.Edit, k.Claims, k.Disable, k.Delete}
+}
+
+// FullHelp returns every key of the screen
+func (k userDetailKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{{k.Up, k.Down}, {k.Edit, k.Claims, k.Links, k.Disable, k.Revoke, k.Delete}}
+}
+
+// newUserDetailScreen creates the detail screen for a user
+func newUserDetailScreen(authSvc firebase.UserStore, storeSvc firebase.DocumentStore, uid string) userDetailScreen {
+	return userDetailScreen{
+		authSvc:     authSvc,
+		storeSvc:    storeSvc,
+		keys:        userDetailKeys,
+		confirmKeys: deleteConfirmKeys,
+		uid:         uid,
+		loading:     true,
+	}
+}
+
+// Init loads the user and their documents
+func (s userDetailScreen) Init() tea.Cmd {
+	return fetchUserDetail(s.authSvc, s.storeSvc, s.uid)
+}
+
+// KeyMap returns the keys of the user detail screen, or those of the delete
+// confirmation while it's open
+func (s userDetailScreen) KeyMap() help.KeyMap {
+	if s.deleteModal {
+		return s.confirmKeys
+	}
+	k := s.keys
+	k.Up.SetEnabled(!s.deleting && s.user != nil)
+	k.Down.SetEnabled(!s.deleting && s.user != nil)
+	k.Delete.SetEnabled(!s.deleting && s.user != nil)
+	k.Edit.SetEnabled(!s.deleting && s.user != nil)
+	k.Disable.SetEnabled(!s.deleting && s.user != nil)
+	k.Revoke.SetEnabled(!s.deleting && s.user != nil)
+	k.Claims.SetEnabled(!s.deleting && s.user != nil)
+	k.Links.SetEnabled(!s.deleting && s.user != nil && s.user.Email != "")
+	if s.user != nil {
+		k.Disable.SetHelp(k.Disable.Help().Key, strings.ToLower(toggleDisabledAction(s.user).title()))
+	}
+	return k
+}
+
+// Loading reports whether the user is loading or being deleted
+func (s userDetailScreen) Loading() bool {
+	return s.loading || s.deleting
+}
+
+// CapturesInput keeps every key on the screen while confirming or running a delete
+func (s userDetailScreen) CapturesInput() bool {
+	return s.deleteModal || s.deleting
+}
+
+// Update handles loading the user, scrolling and deleting
+func (s userDetailScreen) Update(msg tea.Msg) (Screen, tea.Cmd) {
+	s.track(msg)
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.viewport.Width = s.width - 8
+		s.viewport.Height = s.height - 10
+
+	case userDetailLoadedMsg:
+		// Ignore details of another user
+		if msg.user.UID != s.uid {
+			return s, nil
+		}
+		s.loading = false
+		s.user = msg.user
+		s.items = msg.items
+		s.viewport = withScrollKeys(viewport.New(s.width-8, s.height-10), s.keys.Up, s.keys.Down)
+		s.viewport.SetContent(renderUserDetail(msg.user, msg.items))
+
+	case userDetailErrorMsg:
+		if msg.uid != s.uid {
+			return s, nil
+		}
+		s.loading = false
+		s.err = msg.err.Error()
+
+	case userSavedMsg:
+		// Show the edited user without reloading their documents
+		if msg.user.UID != s.uid || s.user == nil {
+			return s, nil
+		}
+		s.user = msg.user
+		s.viewport.SetContent(renderUserDetail(s.user, s.items))
+
+	case userActionDoneMsg:
+		if msg.user.UID != s.uid || s.user == nil {
+			return s, nil
+		}
+		s.user = msg.user
+		s.viewport.SetContent(renderUserDetail(s.user, s.items))
+
+	case deleteProgressMsg:
+		if msg.uid != s.uid {
+			return s, nil
+		}
+		s.deleteProgress = msg.progress
+		return s, waitForDelete(msg.updates)
+
+	case userDeletedMsg:
+		if msg.uid != s.uid {
+			return s, nil
+		}
+		s.deleting = false
+		if msg.err != nil {
+			s.deleteError = msg.err.Error()
+			return s, nil
+		}
+
+		// Go back to the user list, which refreshes itself
+		return s, popScreen
+
+	case tea.KeyMsg:
+		if s.deleteModal {
+			return s.updateDeleteModal(msg)
+		}
+		if s.deleting {
+			return s, nil
+		}
+		if key.Matches(msg, s.keys.Delete) {
+			if s.user != nil {
+				return s.openDeleteModal(), textinput.Blink
+			}
+			return s, nil
+		}
+		if s.user != nil {
+			switch {
+			case key.Matches(msg, s.keys.Edit):
+				return s, pushScreen(newUserFormScreen(s.authSvc, s.user))
+			case key.Matches(msg, s.keys.Claims):
+				return s, pushScreen(newClaimsEditorScreen(s.authSvc, s.user))
+			case key.Matches(msg, s.keys.Links):
+				// Links are sent to the email address, so there must be one
+				if s.user.Email != "" {
+					return s, pushScreen(newUserLinksScreen(s.authSvc, s.user, linkSettings))
+				}
+				return s, nil
+			case key.Matches(msg, s.keys.Disable):
+				return s, pushScreen(newUserActionScreen(s.authSvc, s.user, toggleDisabledAction(s.user)))
+			case key.Matches(msg, s.keys.Revoke):
+				return s, pushScreen(newUserActionScreen(s.authSvc, s.user, actionRevoke))
+			}
+		}
+
+		var cmd tea.Cmd
+		s.viewport, cmd = s.viewport.Update(msg)
+		return s, cmd
+	}
+
+	return s, nil
+}
+
+// View shows the user and their linked Firestore data
+func (s userDetailScreen) View() string {
+	var content string
+	if s.deleteModal {
+		content = s.deleteModalView(s.width-8, s.height-12)
+	} else if s.deleting {
+		content = s.deleteProgressView()
+	} else if s.loading {
+		content = loadingStyle.Render(s.spinner() + " Loading user " + s.uid + "...")
+	} else if s.err != "" {
+		content = errorStyle.Render("Error loading user: " + s.err)
+	} else if s.deleteError != "" {
+		content = errorStyle.Render("Delete failed: "+s.deleteError) + "\n\n" + s.viewport.View()
+	} else {
+		content = s.viewport.View()
+	}
+
+	return lipgloss.NewStyle().
+		Height(s.height-12).
+		Padding(0, 2).
+		Render(content)
+}
+
+// renderUserDetail renders the scrollable body of the user detail screen
+func renderUserDetail(user *auth.UserRecord, items map[string][]collectionItem) string {
+	var sb strings.Builder
+
+	field := func(label, value string) {
+		if value == "" {
+			value = "-"
+		}
+		sb.WriteString(labelStyle.Render(label) + value + "\n")
+	}
+
+	sb.WriteString(sectionStyle.Render("Account"))
+	if user.Disabled {
+		sb.WriteString(" " + disabledBadgeStyle.Render("DISABLED"))
+	}
+	sb.WriteString("\n")
+	field("UID", user.UID)
+	field("Email", user.Email)
+	field("Display name", user.DisplayName)
+	field("Phone", user.PhoneNumber)
+	field("Photo URL", user.PhotoURL)
+	field("Email verified", yesNo(user.EmailVerified))
+	field("Disabled", yesNo(user.Disabled))
+	if user.TenantID != "" {
+		field("Tenant", user.TenantID)
+	}
+
+	sb.WriteString("\n" + sectionStyle.Render("Metadata") + "\n")
+	if user.UserMetadata != nil {
+		field("Created", formatMillis(user.UserMetadata.CreationTimestamp))
+		field("Last sign in", formatMillis(user.UserMetadata.LastLogInTimestamp))
+		field("Last activity", formatMillis(user.UserMetadata.LastRefreshTimestamp))
+	}
+	field("Tokens valid after", formatMillis(user.TokensValidAfterMillis))
+
+	sb.WriteString("\n" + sectionStyle.Render("Providers") + "\n")
+	if len(user.ProviderUserInfo) == 0 {
+		sb.WriteString("-\n")
+	}
+	for _, provider := range user.ProviderUserInfo {
+		identity := provider.Email
+		if identity == "" {
+			identity = provider.PhoneNumber
+		}
+		if identity == "" {
+			identity = provider.UID
+		}
+		field(provider.ProviderID, identity)
+	}
+
+	sb.WriteString("\n" + sectionStyle.Render("Custom claims"))
+	sb.WriteString(labelStyle.UnsetWidth().Render("  "+claimsSize(user.CustomClaims)) + "\n")
+	if len(user.CustomClaims) == 0 {
+		sb.WriteString("-\n")
+	} else {
+		sb.WriteString(formatClaims(user.CustomClaims) + "\n")
+	}
+
+	for _, collection := range firebase.UserCollections {
+		owned := items[collection]
+		title := strings.ToUpper(collection[:1]) + collection[1:]
+		sb.WriteString(fmt.Sprintf("\n%s\n", sectionStyle.Render(fmt.Sprintf("%s (%d)", title, len(owned)))))
+		if len(owned) == 0 {
+			sb.WriteString("-\n")
+			continue
+		}
+
+		sorted := make([]collectionItem, len(owned))
+		copy(sorted, owned)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].createdAt.Before(sorted[j].createdAt)
+		})
+
+		for i, it := range sorted {
+			sb.WriteString(fmt.Sprintf("%d. [%s] %s", i+1, it.id, it.title()))
+			if !it.createdAt.IsZero() {
+				sb.WriteString(labelStyle.UnsetWidth().Render("  created " + it.createdAt.Format("02 Jan 2006, 15:04")))
+			}
+			sb.WriteString("\n")
+			for _, r := range it.records {
+				sb.WriteString("   - " + recordSummary(r) + "\n")
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// formatMillis formats a Unix millisecond timestamp, or "-" when unset
+func formatMillis(ms int64) string {
+	if ms <= 0 {
+		return "-"
+	}
+	return time.UnixMilli(ms).Format("02 Jan 2006, 15:04")
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// openUserDetail opens the second oldest user, who owns data in every collection
+func openUserDetail(t *testing.T, m Model) (Model, userDetailScreen) {
+	t.Helper()
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	s, ok := m.router.top().(userDetailScreen)
+	if !ok {
+		t.Fatalf("Expected enter to open the user detail screen, got %T", m.router.top())
+	}
+	return m, s
+}
+
+// TestUserDetail tests opening a user from the table and returning to the list
+func TestUserDetail(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, UsersTab)
+
+	m, s := openUserDetail(t, m)
+	if s.user == nil || s.err != "" {
+		t.Fatalf("Expected user details to load, got error %q", s.err)
+	}
+
+	body := renderUserDetail(s.user, s.items)
+	for _, want := range []string{s.uid, "Profiles (2)", "Exercises (4)", "Histories (2)", "Routines (2)", "Push Day", "70 × 5 reps", `"admin": true`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("User detail does not contain %q", want)
+		}
+	}
+
+	// q goes back to the list instead of quitting
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+	m = updated.(Model)
+	if _, ok := m.router.top().(usersScreen); !ok || cmd != nil {
+		t.Errorf("Expected q to return to the user list, got %T", m.router.top())
+	}
+}
+
+// TestUserDelete tests deleting a user and their data from the detail view
+func TestUserDelete(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, UsersTab)
+
+	m, s := openUserDetail(t, m)
+	uid := s.uid
+
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyCtrlD})
+	if s = m.router.top().(userDetailScreen); !s.deleteModal {
+		t.Fatal("Expected ctrl+d to open the delete confirmation")
+	}
+
+	// A wrong confirmation keeps the modal open
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if s = m.router.top().(userDetailScreen); !s.deleteModal || s.deleting || s.deleteError == "" {
+		t.Fatal("Expected an empty confirmation to be rejected")
+	}
+
+	// The delete runs to completion and returns to the list
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s.deleteConfirmation())})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	users, ok := m.router.top().(usersScreen)
+	if !ok {
+		t.Fatalf("Expected to return to the user list, got %T", m.router.top())
+	}
+	if !strings.Contains(users.notice, "2 profiles, 3 records") {
+		t.Errorf("Unexpected notice %q", users.notice)
+	}
+	if _, err := authSvc.GetUser(context.Background(), uid); err == nil {
+		t.Error("Expected the user to be deleted")
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// userFilter narrows the loaded users. Plain words match the UID, email,
+// display name or phone number; field:value terms match account state:
+//
+//	disabled:true verified:false provider:google.com created:<2024-01-01
+//
+// A user must match every term.
+type userFilter struct {
+	words []string
+	terms []userFilterTerm
+}
+
+// userFilterTerm is a single field:value term of a user filter
+type userFilterTerm struct {
+	match func(u *auth.UserRecord) bool
+}
+
+// parseUserFilter parses the text typed into the users filter prompt
+func parseUserFilter(input string) (userFilter, error) {
+	var f userFilter
+	for _, word := range strings.Fields(input) {
+		field, value, ok := strings.Cut(word, ":")
+		if !ok {
+			f.words = append(f.words, strings.ToLower(word))
+			continue
+		}
+
+		term, err := parseUserFilterTerm(strings.ToLower(field), value)
+		if err != nil {
+			return userFilter{}, err
+		}
+		f.terms = append(f.terms, term)
+	}
+	return f, nil
+}
+
+// parseUserFilterTerm parses the value of a field:value term
+func parseUserFilterTerm(field, value string) (userFilterTerm, error) {
+	switch field {
+	case "disabled", "verified":
+		want, err := strconv.ParseBool(value)
+		if err != nil {
+			return userFilterTerm{}, fmt.Errorf("%s must be true or false, got %q", field, value)
+		}
+		if field == "disabled" {
+			return userFilterTerm{match: func(u *auth.UserRecord) bool { return u.Disabled == want }}, nil
+		}
+		return userFilterTerm{match: func(u *auth.UserRecord) bool { return u.EmailVerified == want }}, nil
+
+	case "provider":
+		if value == "" {
+			return userFilterTerm{}, fmt.Errorf("provider needs a provider ID such as google.com")
+		}
+		return userFilterTerm{match: func(u *auth.UserRecord) bool {
+			for _, p := range u.ProviderUserInfo {
+				if strings.EqualFold(p.ProviderID, value) {
+					return true
+				}
+			}
+			return false
+		}}, nil
+
+	case "created":
+		return parseCreatedTerm(value)
+	}
+	return userFilterTerm{}, fmt.Errorf("unknown filter field %q, use disabled, verified, provider or created", field)
+}
+
+// parseCreatedTerm parses a creation date comparison such as <2024-01-01 or >=2024-06-01.
+// A bare date matches users created on that day.
+func parseCreatedTerm(value string) (userFilterTerm, error) {
+	op := ""
+	for _, candidate := range []string{"<=", ">=", "<", ">", "="} {
+		if strings.HasPrefix(value, candidate) {
+			op = candidate
+			value = strings.TrimPrefix(value, candidate)
+			break
+		}
+	}
+
+	// Dates are in local time, like the Created column
+	day, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return userFilterTerm{}, fmt.Errorf("created needs a date such as <2024-01-01, got %q", value)
+	}
+	start := day.UnixMilli()
+	end := day.AddDate(0, 0, 1).UnixMilli()
+
+	match := func(u *auth.UserRecord) bool {
+		created := int64(0)
+		if u.UserMetadata != nil {
+			created = u.UserMetadata.CreationTimestamp
+		}
+		switch op {
+		case "<":
+			return created < start
+		case "<=":
+			return created < end
+		case ">":
+			return created >= end
+		case ">=":
+			return created >= start
+		default:
+			return created >= start && created < end
+		}
+	}
+	return userFilterTerm{match: match}, nil
+}
+
+// empty reports whether the filter matches every user
+func (f userFilter) empty() bool {
+	return len(f.words) == 0 && len(f.terms) == 0
+}
+
+// matches reports whether a user matches every word and term of the filter
+func (f userFilter) matches(u *auth.UserRecord) bool {
+	for _, term := range f.terms {
+		if !term.match(u) {
+			return false
+		}
+	}
+
+	if len(f.words) == 0 {
+		return true
+	}
+	fields := strings.ToLower(strings.Join([]string{u.UID, u.Email, u.DisplayName, u.PhoneNumber}, "\n"))
+	for _, word := range f.words {
+		if !strings.Contains(fields, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply returns the users matching the filter
+func (f userFilter) apply(users []*auth.UserRecord) []*auth.UserRecord {
+	if f.empty() {
+		return users
+	}
+	matched := make([]*auth.UserRecord, 0, len(users))
+	for _, u := range users {
+		if f.matches(u) {
+			matched = append(matched, u)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"firebase.google.com/go/v4/auth"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TestUserFilter tests plain and structured filters against the demo users
+func TestUserFilter(t *testing.T) {
+	authSvc, _ := CreateDemoBackend(t)
+	page, err := authSvc.ListUsers(context.Background(), 100, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := make([]*auth.UserRecord, len(page.Users))
+	for i, u := range page.Users {
+		users[i] = u.UserRecord
+	}
+
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"", []string{"dimas.pratama@example.com", "lena.hartono@example.com", "maya.santoso@example.com", "oscar.wijaya@example.com", "rina.kusuma@example.com", ""}},
+		{"MAYA", []string{"maya.santoso@example.com"}},
+		{"+6281234567890", []string{"maya.santoso@example.com"}},
+		{"disabled:true", []string{"oscar.wijaya@example.com"}},
+		{"verified:false", []string{"lena.hartono@example.com", ""}},
+		{"provider:google.com", []string{"dimas.pratama@example.com", "rina.kusuma@example.com"}},
+		{"provider:google.com rina", []string{"rina.kusuma@example.com"}},
+		{"created:<2024-01-01", []string{"oscar.wijaya@example.com"}},
+		{"created:>=2025-01-01 verified:true", []string{"rina.kusuma@example.com"}},
+		{"created:2024-02-14", []string{"dimas.pratama@example.com"}},
+	}
+	for _, tt := range tests {
+		f, err := parseUserFilter(tt.filter)
+		if err != nil {
+			t.Errorf("parseUserFilter(%q) failed: %v", tt.filter, err)
+			continue
+		}
+		var got []string
+		for _, u := range f.apply(users) {
+			got = append(got, u.Email)
+		}
+		sort.Strings(got)
+		want := append([]string(nil), tt.want...)
+		sort.Strings(want)
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("Filter %q matched %v, want %v", tt.filter, got, want)
+		}
+	}
+
+	for _, invalid := range []string{"disabled:maybe", "created:<yesterday", "provider:", "role:admin"} {
+		if _, err := parseUserFilter(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+// TestUsersFilterPrompt tests narrowing the users table while typing
+func TestUsersFilterPrompt(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, UsersTab)
+	users := func() usersScreen {
+		return m.router.top().(usersScreen)
+	}
+
+	m = settle(t, m, keyRunes("/"))
+	if !users().filtering {
+		t.Fatal("Expected / to open the filter prompt")
+	}
+
+	// Rows narrow with every key
+	m = settle(t, m, keyRunes("provider:google.com"))
+	if n := len(users().table.Rows()); n != 2 {
+		t.Fatalf("Expected 2 Google users, got %d", n)
+	}
+
+	// An incomplete term keeps the last valid rows and explains the problem
+	m = settle(t, m, keyRunes(" created:<2024"))
+	if s := users(); len(s.table.Rows()) != 2 || s.filterErr == "" {
+		t.Fatalf("Expected the previous rows and an error, got %d rows and %q", len(s.table.Rows()), s.filterErr)
+	}
+	m = settle(t, m, keyRunes("-03-01"))
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	s := users()
+	if s.filtering || len(s.shown) != 1 || s.shown[0].Email != "dimas.pratama@example.com" {
+		t.Fatalf("Expected only Dimas after applying the filter, got %d users", len(s.shown))
+	}
+	if !strings.Contains(m.View(), "1 match the filter") {
+		t.Error("Expected the number of matches in the page status")
+	}
+
+	// Enter opens the matching user
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if d, ok := m.router.top().(userDetailScreen); !ok || d.user == nil || d.user.Email != "dimas.pratama@example.com" {
+		t.Fatalf("Expected the filtered user to open, got %T", m.router.top())
+	}
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+
+	// Cancelling an edit keeps the applied filter
+	m = settle(t, m, keyRunes("/"))
+	m = settle(t, m, keyRunes(" x"))
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	if s := users(); s.filtering || len(s.shown) != 1 {
+		t.Errorf("Expected esc to restore the applied filter, got %d users", len(s.shown))
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+
+	"arrogance/firebase"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Labels of the user form fields
+const (
+	fieldEmail         = "Email"
+	fieldPassword      = "Password"
+	fieldDisplayName   = "Display name"
+	fieldPhone         = "Phone"
+	fieldPhotoURL      = "Photo URL"
+	fieldEmailVerified = "Email verified"
+)
+
+// UserSavedMsg is sent when a user has been created or updated
+type userSavedMsg struct {
+	user    *auth.UserRecord
+	created bool
+}
+
+// UserSaveErrorMsg is sent when creating or updating a user fails
+type userSaveErrorMsg struct {
+	uid string
+	err error
+}
+
+// userFormScreen creates a user, or edits one when user is set
+type userFormScreen struct {
+	frame
+	authSvc firebase.UserStore
+
+	user   *auth.UserRecord
+	form   form
+	saving bool
+	err    string
+}
+
+// newUserFormScreen creates the form for a new user, or for editing user when it isn't nil
+func newUserFormScreen(authSvc firebase.UserStore, user *auth.UserRecord) userFormScreen {
+	if user == nil {
+		return userFormScreen{
+			authSvc: authSvc,
+			form: newForm(
+				textField(fieldEmail, "", "name@example.com", validateEmail(true)),
+				passwordField(fieldPassword, "at least 6 characters", validatePassword(true)),
+				textField(fieldDisplayName, "", "", nil),
+				textField(fieldPhone, "", "+14155550100", validatePhone),
+				textField(fieldPhotoURL, "", "https://", validatePhotoURL),
+				checkboxField(fieldEmailVerified, false),
+			),
+		}
+	}
+
+	return userFormScreen{
+		authSvc: authSvc,
+		user:    user,
+		form: newForm(
+			textField(fieldEmail, user.Email, "name@example.com", validateEmail(user.Email != "")),
+			passwordField(fieldPassword, "unchanged", validatePassword(false)),
+			textField(fieldDisplayName, user.DisplayName, "", nil),
+			textField(fieldPhone, user.PhoneNumber, "+14155550100", validatePhone),
+			textField(fieldPhotoURL, user.PhotoURL, "https://", validatePhotoURL),
+			checkboxField(fieldEmailVerified, user.EmailVerified),
+		),
+	}
+}
+
+// Init starts the cursor blinking
+func (s userFormScreen) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// KeyMap returns the keys of the form
+func (s userFormScreen) KeyMap() help.KeyMap {
+	return s.form.KeyMap()
+}
+
+// Loading reports whether the user is being saved
+func (s userFormScreen) Loading() bool {
+	return s.saving
+}
+
+// CapturesInput keeps every key on the form, which is closed with its cancel key
+func (s userFormScreen) CapturesInput() bool {
+	return true
+}
+
+// Update handles editing the fields and saving the user
+func (s userFormScreen) Update(msg tea.Msg) (Screen, tea.Cmd) {
+	s.track(msg)
+
+	switch msg := msg.(type) {
+	case userSavedMsg:
+		// Go back to the screen the form was opened from, which refreshes itself
+		if !s.saving || s.user != nil && msg.user.UID != s.user.UID {
+			return s, nil
+		}
+		return s, popScreen
+
+	case userSaveErrorMsg:
+		if !s.saving || s.user != nil && msg.uid != s.user.UID {
+			return s, nil
+		}
+		s.saving = false
+		s = s.showSaveError(msg.err)
+		return s, nil
+
+	case tea.KeyMsg:
+		if s.saving {
+			return s, nil
+		}
+		switch {
+		case key.Matches(msg, s.form.keys.Cancel):
+			return s, popScreen
+		case key.Matches(msg, s.form.keys.Submit):
+			return s.submit()
+		}
+	}
+
+	var cmd tea.Cmd
+	s.form, cmd = s.form.Update(msg)
+	return s, cmd
+}
+
+// submit validates the form and saves the user
+func (s userFormScreen) submit() (Screen, tea.Cmd) {
+	var ok bool
+	s.err = ""
+	if s.form, ok = s.form.validate(); !ok {
+		return s, nil
+	}
+
+	if s.user == nil {
+		params := &firebase.UserToCreate{
+			Email:         s.form.value(fieldEmail),
+			Password:      s.form.value(fieldPassword),
+			DisplayName:   s.form.value(fieldDisplayName),
+			PhoneNumber:   s.form.value(fieldPhone),
+			PhotoURL:      s.form.value(fieldPhotoURL),
+			EmailVerified: s.form.checked(fieldEmailVerified),
+		}
+		s.saving = true
+		return s, createUser(s.authSvc, params)
+	}
+
+	params := s.changes()
+	if params == (firebase.UserToUpdate{}) {
+		// Nothing to save
+		return s, popScreen
+	}
+	s.saving = true
+	return s, updateUser(s.authSvc, s.user.UID, &params)
+}
+
+// changes returns the fields that differ from the user being edited
+func (s userFormScreen) changes() firebase.UserToUpdate {
+	var params firebase.UserToUpdate
+	changed := func(label, current string) *string {
+		if value := s.form.value(label); value != current {
+			return firebase.String(value)
+		}
+		return nil
+	}
+
+	params.Email = changed(fieldEmail, s.user.Email)
+	params.DisplayName = changed(fieldDisplayName, s.user.DisplayName)
+	params.PhoneNumber = changed(fieldPhone, s.user.PhoneNumber)
+	params.PhotoURL = changed(fieldPhotoURL, s.user.PhotoURL)
+	if password := s.form.value(fieldPassword); password != "" {
+		params.Password = firebase.String(password)
+	}
+	if verified := s.form.checked(fieldEmailVerified); verified != s.user.EmailVerified {
+		params.EmailVerified = firebase.Bool(verified)
+	}
+	return params
+}
+
+// showSaveError shows an error from the backend next to the field it's about,
+// or below the form
+func (s userFormScreen) showSaveError(err error) userFormScreen {
+	switch {
+	case errors.Is(err, firebase.ErrEmailExists):
+		s.form = s.form.setError(fieldEmail, "Another user has this email")
+	case errors.Is(err, firebase.ErrPhoneNumberExists):
+		s.form = s.form.setError(fieldPhone, "Another user has this phone number")
+	default:
+		s.err = err.Error()
+	}
+	return s
+}
+
+// View shows the form in a modal
+func (s userFormScreen) View() string {
+	title := "Create user"
+	if s.user != nil {
+		title = "Edit user " + s.user.UID
+	}
+
+	body := s.form.View()
+	if s.saving {
+		body += "\n\n" + loadingStyle.Render(s.spinner()+" Saving...")
+	} else if s.err != "" {
+		body += "\n\n" + errorStyle.UnsetMarginLeft().Width(60).Render(s.err)
+	}
+
+	return renderModal(s.width-8, s.height-12, title, body, newHelp().View(s.form.KeyMap()))
+}
+
+// createUser creates a user and loads the new record
+func createUser(authSvc firebase.UserStore, params *firebase.UserToCreate) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		uid, err := authSvc.CreateUser(ctx, params)
+		if err != nil {
+			return userSaveErrorMsg{err: err}
+		}
+		user, err := authSvc.GetUser(ctx, uid)
+		if err != nil {
+			return userSaveErrorMsg{err: fmt.Errorf("user %s was created but couldn't be loaded: %w", uid, err)}
+		}
+		return userSavedMsg{user: user, created: true}
+	}
+}
+
+// updateUser updates a user and loads the updated record
+func updateUser(authSvc firebase.UserStore, uid string, params *firebase.UserToUpdate) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		if err := authSvc.UpdateUser(ctx, uid, params); err != nil {
+			return userSaveErrorMsg{uid: uid, err: err}
+		}
+		user, err := authSvc.GetUser(ctx, uid)
+		if err != nil {
+			return userSaveErrorMsg{uid: uid, err: fmt.Errorf("user was updated but couldn't be reloaded: %w", err)}
+		}
+		return userSavedMsg{user: user}
+	}
+}
+
+// E.164 phone numbers, as required by Firebase Authentication
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// validateEmail checks an email address, which may be left empty unless required
+func validateEmail(required bool) func(string) error {
+	return func(value string) error {
+		if value == "" {
+			if required {
+				return errors.New("email is required")
+			}
+			return nil
+		}
+		addr, err := mail.ParseAddress(value)
+		if err != nil || addr.Address != value {
+			return errors.New("not a valid email address")
+		}
+		return nil
+	}
+}
+
+// validatePassword checks a password, which may be left empty unless required
+func validatePassword(required bool) func(string) error {
+	return func(value string) error {
+		if value == "" && !required {
+			return nil
+		}
+		if len(value) < 6 {
+			return errors.New("password must be at least 6 characters")
+		}
+		return nil
+	}
+}
+
+// validatePhone checks that a phone number is in E.164 format
+func validatePhone(value string) error {
+	if value != "" && !phonePattern.MatchString(value) {
+		return errors.New("phone number must be in E.164 format, such as +14155550100")
+	}
+	return nil
+}
+
+// validatePhotoURL checks that a photo URL is an absolute http or https URL
+func validatePhotoURL(value string) error {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("photo URL must be an http or https URL")
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// formScreen returns the user form at the top of the active tab
+func formScreen(t *testing.T, m Model) userFormScreen {
+	t.Helper()
+	s, ok := m.router.top().(userFormScreen)
+	if !ok {
+		t.Fatalf("Expected the user form, got %T", m.router.top())
+	}
+	return s
+}
+
+// typeField types into the focused field and moves to the next one
+func typeField(t *testing.T, m Model, value string) Model {
+	t.Helper()
+	if value != "" {
+		m = settle(t, m, keyRunes(value))
+	}
+	return settle(t, m, tea.KeyMsg{Type: tea.KeyTab})
+}
+
+// TestCreateUser tests validating and creating a user from the users table
+func TestCreateUser(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, UsersTab)
+
+	m = settle(t, m, keyRunes("c"))
+	formScreen(t, m)
+
+	// Invalid fields are reported next to them
+	m = typeField(t, m, "not-an-email")
+	m = typeField(t, m, "123")
+	m = typeField(t, m, "New Admin")
+	m = typeField(t, m, "0812")
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	s := formScreen(t, m)
+	view := s.View()
+	for _, want := range []string{"not a valid email address", "at least 6 characters", "E.164"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Form does not show %q", want)
+		}
+	}
+	if s.saving || s.form.focus != 0 {
+		t.Fatal("Expected the form to stay open on the first invalid field")
+	}
+
+	// An email that's taken is reported by the backend next to the field
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyCtrlU})
+	m = typeField(t, m, "maya.santoso@example.com")
+	m = typeField(t, m, "456789")
+	m = typeField(t, m, "")
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyCtrlU})
+	m = typeField(t, m, "+14155550100")
+	m = typeField(t, m, "")
+	m = settle(t, m, keyRunes(" "))
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if view := formScreen(t, m).View(); !strings.Contains(view, "Another user has this email") {
+		t.Fatalf("Expected the duplicate email next to the field, got:\n%s", view)
+	}
+
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyCtrlU})
+	m = settle(t, m, keyRunes("new.admin@example.com"))
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	users, ok := m.router.top().(usersScreen)
+	if !ok {
+		t.Fatalf("Expected to return to the users table, got %T", m.router.top())
+	}
+	user, err := authSvc.GetUserByEmail(context.Background(), "new.admin@example.com")
+	if err != nil {
+		t.Fatalf("Expected the user to be created: %v", err)
+	}
+	if user.DisplayName != "New Admin" || user.PhoneNumber != "+14155550100" || !user.EmailVerified {
+		t.Errorf("Unexpected user %+v", user.UserInfo)
+	}
+	if len(users.users) != 7 || !strings.Contains(users.notice, user.UID) {
+		t.Errorf("Expected the table to show 7 users and a notice, got %d users and %q", len(users.users), users.notice)
+	}
+}
+
+// TestEditUser tests editing the selected user and updating the table in place
+func TestEditUser(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, UsersTab)
+
+	// The second oldest user is Maya
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	m = settle(t, m, keyRunes("e"))
+	s := formScreen(t, m)
+	if s.form.value(fieldEmail) != "maya.santoso@example.com" || !s.form.checked(fieldEmailVerified) {
+		t.Fatalf("Expected the form to be filled in, got email %q", s.form.value(fieldEmail))
+	}
+
+	// Removing the email of a user who has one isn't allowed
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyCtrlU})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(formScreen(t, m).View(), "email is required") {
+		t.Fatal("Expected the email to be required")
+	}
+	m = settle(t, m, keyRunes("maya.santoso@example.com"))
+
+	// Change the display name and save
+	m = typeField(t, m, "")
+	m = typeField(t, m, "")
+	m = settle(t, m, keyRunes(" S."))
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	users, ok := m.router.top().(usersScreen)
+	if !ok {
+		t.Fatalf("Expected to return to the users table, got %T", m.router.top())
+	}
+	if users.table.Cursor() != 1 || users.table.SelectedRow()[2] != "Maya Santoso S." {
+		t.Errorf("Expected the edited row to stay selected, got row %v", users.table.SelectedRow())
+	}
+	user, _ := authSvc.GetUser(context.Background(), users.shown[1].UID)
+	if user.DisplayName != "Maya Santoso S." || user.Email != "maya.santoso@example.com" {
+		t.Errorf("Unexpected user %+v", user.UserInfo)
+	}
+
+	// esc leaves without saving
+	m = settle(t, m, keyRunes("e"))
+	m = settle(t, m, keyRunes("x"))
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	if _, ok := m.router.top().(usersScreen); !ok {
+		t.Fatalf("Expected esc to close the form, got %T", m.router.top())
+	}
+	if user, _ := authSvc.GetUser(context.Background(), users.shown[1].UID); user.Email != "maya.santoso@example.com" {
+		t.Errorf("Expected esc not to save, got email %q", user.Email)
+	}
+}
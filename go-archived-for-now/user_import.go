@@ -0,0 +1,476 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"arrogance/firebase"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// importHash describes the password hashes of imported files, set from the
+// config file before the app starts
+var importHash HashSettings
+
+// importColumns maps the accepted column names, in lower case, to the field
+// they fill. Firebase CLI export names are accepted alongside ours, and the
+// metadata columns of our exports are ignored.
+var importColumns = map[string]string{
+	"uid":              "uid",
+	"localid":          "uid",
+	"email":            "email",
+	"emailverified":    "emailVerified",
+	"displayname":      "displayName",
+	"phonenumber":      "phoneNumber",
+	"photourl":         "photoURL",
+	"disabled":         "disabled",
+	"customclaims":     "customClaims",
+	"customattributes": "customClaims",
+	"passwordhash":     "passwordHash",
+	"passwordsalt":     "passwordSalt",
+	"salt":             "passwordSalt",
+	"providers":        "",
+	"createdat":        "",
+	"lastsigninat":     "",
+	"lastrefreshat":    "",
+}
+
+// importAction is what importing a row does to the existing accounts
+type importAction int
+
+const (
+	importCreate importAction = iota
+	importUpdate
+	importUnchanged
+)
+
+// importRow is a user read from an import file
+type importRow struct {
+	line   int // line of a CSV file or position in a JSON file, from 1
+	user   *firebase.ImportUser
+	errs   []string
+	action importAction
+	// changes describes how an existing account differs from the row
+	changes []string
+}
+
+// valid reports whether the row can be imported
+func (r importRow) valid() bool {
+	return len(r.errs) == 0
+}
+
+// importFormat returns the format of an import file from its extension
+// unless one is given
+func importFormat(path, format string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	switch format {
+	case "csv", "json":
+		return format, nil
+	}
+	return "", fmt.Errorf("can't tell the format of %s, use csv or json", path)
+}
+
+// parseImportFile reads the users of a CSV or JSON import file. Problems with
+// single rows are kept on the rows, so only unreadable files are errors.
+func parseImportFile(r io.Reader, format string) ([]importRow, error) {
+	var records []map[string]string
+	var err error
+	switch format {
+	case "csv":
+		records, err = readImportCSV(r)
+	case "json":
+		records, err = readImportJSON(r)
+	default:
+		return nil, fmt.Errorf("unknown import format %q, use csv or json", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]importRow, len(records))
+	for i, record := range records {
+		line := i + 1
+		if format == "csv" {
+			line = i + 2 // After the header
+		}
+		rows[i] = parseImportRecord(line, record)
+	}
+	return checkImportDuplicates(rows), nil
+}
+
+// readImportCSV reads the records of a CSV file with a header row
+func readImportCSV(r io.Reader) ([]map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the header: %w", err)
+	}
+	fields := make([]string, len(header))
+	for i, name := range header {
+		field, ok := importColumns[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		fields[i] = field
+	}
+
+	var records []map[string]string
+	for {
+		values, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := make(map[string]string, len(values))
+		for i, value := range values {
+			if fields[i] != "" {
+				record[fields[i]] = strings.TrimSpace(value)
+			}
+		}
+		records = append(records, record)
+	}
+}
+
+// readImportJSON reads the records of a JSON file holding an array of users,
+// or an object with a users array like the Firebase CLI exports
+func readImportJSON(r io.Reader) ([]map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &users); err != nil {
+		var export struct {
+			Users []map[string]json.RawMessage `json:"users"`
+		}
+		if err := json.Unmarshal(data, &export); err != nil {
+			return nil, fmt.Errorf("not a JSON array of users or an object with a users array: %w", err)
+		}
+		users = export.Users
+	}
+
+	records := make([]map[string]string, len(users))
+	for i, user := range users {
+		record := make(map[string]string, len(user))
+		for name, raw := range user {
+			field, ok := importColumns[strings.ToLower(name)]
+			if !ok || field == "" {
+				// Other exported fields, such as providerUserInfo, aren't imported
+				continue
+			}
+			// Strings are unquoted, everything else is kept as JSON
+			var s string
+			if err := json.Unmarshal(raw, &s); err == nil {
+				record[field] = s
+			} else {
+				record[field] = string(raw)
+			}
+		}
+		records[i] = record
+	}
+	return records, nil
+}
+
+// parseImportRecord converts and validates the fields of a single user
+func parseImportRecord(line int, record map[string]string) importRow {
+	row := importRow{line: line, user: &firebase.ImportUser{}}
+	u := row.user
+	fail := func(format string, args ...interface{}) {
+		row.errs = append(row.errs, fmt.Sprintf(format, args...))
+	}
+	check := func(err error) {
+		if err != nil {
+			row.errs = append(row.errs, err.Error())
+		}
+	}
+	boolean := func(field string) bool {
+		value := record[field]
+		if value == "" {
+			return false
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			fail("%s must be true or false, got %q", field, value)
+		}
+		return b
+	}
+	bytes := func(field string) []byte {
+		value := record[field]
+		if value == "" {
+			return nil
+		}
+		// Firebase exports use URL-safe base64
+		for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+			if b, err := enc.DecodeString(value); err == nil {
+				return b
+			}
+		}
+		fail("%s must be base64", field)
+		return nil
+	}
+
+	u.UID = record["uid"]
+	switch {
+	case u.UID == "":
+		fail("uid is required")
+	case len(u.UID) > 128:
+		fail("uid must be at most 128 characters")
+	}
+	u.Email = record["email"]
+	check(validateEmail(false)(u.Email))
+	u.EmailVerified = boolean("emailVerified")
+	u.DisplayName = record["displayName"]
+	u.PhoneNumber = record["phoneNumber"]
+	check(validatePhone(u.PhoneNumber))
+	u.PhotoURL = record["photoURL"]
+	check(validatePhotoURL(u.PhotoURL))
+	u.Disabled = boolean("disabled")
+
+	if claims := record["customClaims"]; claims != "" {
+		if err := json.Unmarshal([]byte(claims), &u.CustomClaims); err != nil || u.CustomClaims == nil {
+			fail("customClaims must be a JSON object")
+		} else {
+			check(firebase.ValidateCustomClaims(u.CustomClaims))
+		}
+	}
+
+	u.PasswordHash = bytes("passwordHash")
+	u.PasswordSalt = bytes("passwordSalt")
+	if len(u.PasswordSalt) > 0 && len(u.PasswordHash) == 0 {
+		fail("passwordSalt needs a passwordHash")
+	}
+	return row
+}
+
+// checkImportDuplicates marks rows that repeat the UID, email or phone number of an earlier row
+func checkImportDuplicates(rows []importRow) []importRow {
+	seen := map[string]int{}
+	for i := range rows {
+		u := rows[i].user
+		for _, id := range []struct{ field, value string }{{"uid", u.UID}, {"email", strings.ToLower(u.Email)}, {"phone number", u.PhoneNumber}} {
+			if id.value == "" {
+				continue
+			}
+			k := id.field + "\x00" + id.value
+			if line, ok := seen[k]; ok {
+				rows[i].errs = append(rows[i].errs, fmt.Sprintf("%s is also on line %d", id.field, line))
+				continue
+			}
+			seen[k] = rows[i].line
+		}
+	}
+	return rows
+}
+
+// needsHash reports whether any valid row has a password hash
+func needsHash(rows []importRow) bool {
+	for _, row := range rows {
+		if row.valid() && len(row.user.PasswordHash) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// planImport compares the valid rows with the existing accounts, looking
+// them up by UID and email MaxGetUsersBatchSize at a time. Rows whose email
+// belongs to another account become invalid, since Firebase doesn't check and
+// would create a duplicate.
+func planImport(ctx context.Context, users firebase.UserStore, rows []importRow) ([]importRow, error) {
+	planned := make([]importRow, len(rows))
+	copy(planned, rows)
+
+	var identifiers []auth.UserIdentifier
+	for _, row := range planned {
+		if !row.valid() {
+			continue
+		}
+		identifiers = append(identifiers, auth.UIDIdentifier{UID: row.user.UID})
+		if row.user.Email != "" {
+			identifiers = append(identifiers, auth.EmailIdentifier{Email: row.user.Email})
+		}
+	}
+
+	byUID := make(map[string]*auth.UserRecord)
+	byEmail := make(map[string]*auth.UserRecord)
+	for start := 0; start < len(identifiers); start += firebase.MaxGetUsersBatchSize {
+		end := min(start+firebase.MaxGetUsersBatchSize, len(identifiers))
+		result, err := users.GetUsers(ctx, identifiers[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up users: %w", err)
+		}
+		for _, user := range result.Users {
+			byUID[user.UID] = user
+			if user.Email != "" {
+				byEmail[strings.ToLower(user.Email)] = user
+			}
+		}
+	}
+
+	for i := range planned {
+		row := &planned[i]
+		if !row.valid() {
+			continue
+		}
+
+		if existing, ok := byUID[row.user.UID]; ok {
+			row.changes = importChanges(existing, row.user)
+			row.action = importUpdate
+			if len(row.changes) == 0 {
+				row.action = importUnchanged
+			}
+		} else {
+			row.action = importCreate
+		}
+
+		if row.user.Email == "" {
+			continue
+		}
+		if owner, ok := byEmail[strings.ToLower(row.user.Email)]; ok && owner.UID != row.user.UID {
+			row.errs = append(row.errs, fmt.Sprintf("email belongs to existing user %s", owner.UID))
+		}
+	}
+	return planned, nil
+}
+
+// importChanges describes the fields of an existing account that an import replaces
+func importChanges(existing *auth.UserRecord, u *firebase.ImportUser) []string {
+	var changes []string
+	text := func(field, from, to string) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s: %q → %q", field, from, to))
+		}
+	}
+	boolean := func(field string, from, to bool) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s: %v → %v", field, from, to))
+		}
+	}
+
+	text("email", existing.Email, u.Email)
+	boolean("emailVerified", existing.EmailVerified, u.EmailVerified)
+	text("displayName", existing.DisplayName, u.DisplayName)
+	text("phoneNumber", existing.PhoneNumber, u.PhoneNumber)
+	text("photoURL", existing.PhotoURL, u.PhotoURL)
+	boolean("disabled", existing.Disabled, u.Disabled)
+	text("customClaims", formatClaimsInline(existing.CustomClaims), formatClaimsInline(u.CustomClaims))
+	if len(u.PasswordHash) > 0 {
+		changes = append(changes, "password replaced")
+	}
+	return changes
+}
+
+// importDiff describes what importing the rows does, a line per row
+func importDiff(rows []importRow) []string {
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		u := row.user
+		switch {
+		case !row.valid():
+			lines = append(lines, fmt.Sprintf("! line %d %s: %s", row.line, u.UID, strings.Join(row.errs, "; ")))
+		case row.action == importCreate:
+			lines = append(lines, fmt.Sprintf("+ %s %s", u.UID, u.Email))
+		case row.action == importUpdate:
+			lines = append(lines, fmt.Sprintf("~ %s %s", u.UID, strings.Join(row.changes, ", ")))
+		default:
+			lines = append(lines, fmt.Sprintf("= %s unchanged", u.UID))
+		}
+	}
+	return lines
+}
+
+// importCounts summarizes the rows of an import
+func importCounts(rows []importRow) string {
+	var create, update, unchanged, invalid int
+	for _, row := range rows {
+		switch {
+		case !row.valid():
+			invalid++
+		case row.action == importCreate:
+			create++
+		case row.action == importUpdate:
+			update++
+		default:
+			unchanged++
+		}
+	}
+	return fmt.Sprintf("%d to create, %d to update, %d unchanged, %d invalid", create, update, unchanged, invalid)
+}
+
+// importFailureLines describes the rows an import failed on
+func importFailureLines(imported []importRow, result *firebase.ImportResult) []string {
+	lines := make([]string, len(result.Failures))
+	for i, f := range result.Failures {
+		lines[i] = fmt.Sprintf("line %d %s: %s", imported[f.Index].line, f.UID, f.Reason)
+	}
+	return lines
+}
+
+// checkImportHash reports an error when valid rows have password hashes but no
+// hash algorithm is set, since Firebase would reject every chunk
+func checkImportHash(rows []importRow, hash *firebase.HashConfig) error {
+	if hash == nil && needsHash(rows) {
+		return errors.New("the file has password hashes, set their algorithm with import.hash in the config file")
+	}
+	return nil
+}
+
+// importable returns the valid rows that change accounts
+func importable(rows []importRow) []importRow {
+	var todo []importRow
+	for _, row := range rows {
+		if row.valid() && row.action != importUnchanged {
+			todo = append(todo, row)
+		}
+	}
+	return todo
+}
+
+// runImport imports rows in chunks, returning failures by the position of
+// the row in rows. progress may be nil.
+func runImport(ctx context.Context, users firebase.UserStore, rows []importRow, hash *firebase.HashConfig, progress func(firebase.ImportProgress)) *firebase.ImportResult {
+	records := make([]*firebase.ImportUser, len(rows))
+	for i, row := range rows {
+		records[i] = row.user
+	}
+	return firebase.ImportUsersInChunks(ctx, users, records, hash, progress)
+}
+
+// countValid counts the rows that can be imported
+func countValid(rows []importRow) int {
+	n := 0
+	for _, row := range rows {
+		if row.valid() {
+			n++
+		}
+	}
+	return n
+}
+
+// importResultText summarizes a finished import
+func importResultText(result *firebase.ImportResult) string {
+	text := fmt.Sprintf("Imported %d %s", result.Imported, plural(result.Imported, "user", "users"))
+	if len(result.Failures) > 0 {
+		text += fmt.Sprintf(", %d failed", len(result.Failures))
+	}
+	return text
+}
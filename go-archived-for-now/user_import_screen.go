@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"arrogance/firebase"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ImportPlannedMsg is sent when an import file has been read and compared with the existing accounts
+type importPlannedMsg struct {
+	path string
+	rows []importRow
+	err  error
+}
+
+// ImportProgressMsg is sent while users are being imported
+type importProgressMsg struct {
+	progress firebase.ImportProgress
+	updates  <-chan tea.Msg
+}
+
+// UsersImportedMsg is sent when an import finishes. Failures are indexed by
+// the position of the user in rows.
+type usersImportedMsg struct {
+	rows   []importRow
+	result *firebase.ImportResult
+}
+
+// planImportFile reads an import file and compares it with the existing accounts
+func planImportFile(authSvc firebase.UserStore, path string) tea.Cmd {
+	return func() tea.Msg {
+		format, err := importFormat(path, "")
+		if err != nil {
+			return importPlannedMsg{path: path, err: err}
+		}
+		rows, err := readImportPath(path, format)
+		if err == nil {
+			rows, err = planImport(context.Background(), authSvc, rows)
+		}
+		return importPlannedMsg{path: path, rows: rows, err: err}
+	}
+}
+
+// startUserImport imports rows in the background, reporting progress through messages
+func startUserImport(authSvc firebase.UserStore, rows []importRow, hash *firebase.HashConfig) tea.Cmd {
+	updates := make(chan tea.Msg, 1)
+
+	go func() {
+		defer close(updates)
+		result := runImport(context.Background(), authSvc, rows, hash, func(p firebase.ImportProgress) {
+			updates <- importProgressMsg{progress: p, updates: updates}
+		})
+		updates <- usersImportedMsg{rows: rows, result: result}
+	}()
+
+	return waitForImport(updates)
+}
+
+// waitForImport waits for the next update from a running import
+func waitForImport(updates <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-updates
+	}
+}
+
+// importStage is a step of the import dialog
+type importStage int
+
+const (
+	importPickFile importStage = iota
+	importPlanning
+	importReview
+	importRunning
+	importDone
+)
+
+// userImportKeyMap holds the keys of the import dialog
+type userImportKeyMap struct {
+	Up     key.Binding
+	Down   key.Binding
+	Submit key.Binding
+	Cancel key.Binding
+}
+
+var userImportKeys = userImportKeyMap{
+	Up:     key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "scroll up")),
+	Down:   key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "scroll down")),
+	Submit: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "continue")),
+	Cancel: key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "close")),
+}
+
+// ShortHelp returns the keys shown in the footer
+func (k userImportKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Up, k.Down, k.Submit, k.Cancel}
+}
+
+// FullHelp returns every key of the dialog
+func (k userImportKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+// userImportScreen imports users from a CSV or JSON file, showing what
+// changes before anything is imported
+type userImportScreen struct {
+	frame
+	authSvc firebase.UserStore
+	keys    userImportKeyMap
+	hash    HashSettings
+
+	stage    importStage
+	input    textinput.Model
+	viewport viewport.Model
+	path     string
+	rows     []importRow
+	progress firebase.ImportProgress
+	result   *firebase.ImportResult
+	err      string
+}
+
+// newUserImportScreen creates the import dialog, asking for a file first
+func newUserImportScreen(authSvc firebase.UserStore, hash HashSettings) userImportScreen {
+	input := textinput.New()
+	input.Placeholder = "users.csv or users.json"
+	input.CharLimit = 1024
+	input.Width = 60
+	input.Focus()
+
+	return userImportScreen{
+		authSvc:  authSvc,
+		keys:     userImportKeys,
+		hash:     hash,
+		input:    input,
+		viewport: withScrollKeys(viewport.New(0, 0), userImportKeys.Up, userImportKeys.Down),
+	}
+}
+
+// Init starts the cursor blinking
+func (s userImportScreen) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// KeyMap returns the keys that apply to the current step
+func (s userImportScreen) KeyMap() help.KeyMap {
+	k := s.keys
+	scrolls := s.stage == importReview || s.stage == importDone
+	k.Up.SetEnabled(scrolls)
+	k.Down.SetEnabled(scrolls)
+	k.Submit.SetEnabled(s.stage == importPickFile || (s.stage == importReview && len(importable(s.rows)) > 0))
+	switch s.stage {
+	case importPickFile:
+		k.Submit.SetHelp("enter", "review")
+	case importReview:
+		k.Submit.SetHelp("enter", "import")
+		k.Cancel.SetHelp("esc", "back")
+	}
+	return k
+}
+
+// Loading reports whether the file is being read or imported
+func (s userImportScreen) Loading() bool {
+	return s.stage == importPlanning || s.stage == importRunning
+}
+
+// CapturesInput keeps every key on the dialog, which is closed with its cancel key
+func (s userImportScreen) CapturesInput() bool {
+	return true
+}
+
+// Update moves through picking a file, reviewing the changes and importing
+func (s userImportScreen) Update(msg tea.Msg) (Screen, tea.Cmd) {
+	s.track(msg)
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.viewport.Width = s.width - 16
+		s.viewport.Height = s.height - 24
+		s.viewport.SetContent(s.details())
+
+	case importPlannedMsg:
+		if s.stage != importPlanning || msg.path != s.path {
+			return s, nil
+		}
+		if msg.err != nil {
+			s.stage = importPickFile
+			s.err = msg.err.Error()
+			return s, nil
+		}
+		s.stage = importReview
+		s.rows = msg.rows
+		s.viewport.SetContent(s.details())
+		s.viewport.GotoTop()
+
+	case importProgressMsg:
+		s.progress = msg.progress
+		return s, waitForImport(msg.updates)
+
+	case usersImportedMsg:
+		if s.stage == importRunning {
+			s.stage = importDone
+			s.result = msg.result
+			s.viewport.SetContent(strings.Join(importFailureLines(msg.rows, msg.result), "\n"))
+			s.viewport.GotoTop()
+		}
+
+	case tea.KeyMsg:
+		return s.handleKey(msg)
+	}
+
+	return s, nil
+}
+
+// handleKey handles the keys of the current step
+func (s userImportScreen) handleKey(msg tea.KeyMsg) (Screen, tea.Cmd) {
+	switch s.stage {
+	case importPickFile:
+		switch {
+		case key.Matches(msg, s.keys.Cancel):
+			return s, popScreen
+		case key.Matches(msg, s.keys.Submit):
+			path := strings.TrimSpace(s.input.Value())
+			if path == "" {
+				s.err = "Enter the path of a CSV or JSON file"
+				return s, nil
+			}
+			s.stage = importPlanning
+			s.path = path
+			s.err = ""
+			return s, planImportFile(s.authSvc, path)
+		}
+		var cmd tea.Cmd
+		s.input, cmd = s.input.Update(msg)
+		return s, cmd
+
+	case importReview:
+		switch {
+		case key.Matches(msg, s.keys.Cancel):
+			// Pick another file
+			s.stage = importPickFile
+			s.rows = nil
+			return s, textinput.Blink
+		case key.Matches(msg, s.keys.Submit):
+			todo := importable(s.rows)
+			if len(todo) == 0 {
+				return s, nil
+			}
+			hash, err := s.hash.config()
+			if err == nil {
+				err = checkImportHash(s.rows, hash)
+			}
+			if err != nil {
+				s.err = err.Error()
+				return s, nil
+			}
+			s.stage = importRunning
+			s.err = ""
+			s.progress = firebase.ImportProgress{Total: len(todo)}
+			return s, startUserImport(s.authSvc, todo, hash)
+		}
+
+	case importDone:
+		if key.Matches(msg, s.keys.Cancel) || key.Matches(msg, s.keys.Submit) {
+			return s, popScreen
+		}
+
+	default:
+		// Nothing to do until the file is read or imported
+		return s, nil
+	}
+
+	var cmd tea.Cmd
+	s.viewport, cmd = s.viewport.Update(msg)
+	return s, cmd
+}
+
+// details lists what importing each row does, invalid rows first
+func (s userImportScreen) details() string {
+	var invalid, valid []string
+	for i, line := range importDiff(s.rows) {
+		if s.rows[i].valid() {
+			valid = append(valid, line)
+		} else {
+			invalid = append(invalid, errorStyle.UnsetMarginLeft().Render(line))
+		}
+	}
+	return strings.Join(append(invalid, valid...), "\n")
+}
+
+// View shows the current step in a modal
+func (s userImportScreen) View() string {
+	var sb strings.Builder
+
+	switch s.stage {
+	case importPickFile, importPlanning:
+		sb.WriteString("CSV files need a header row with columns such as uid, email, displayName,\n")
+		sb.WriteString("phoneNumber, disabled, customClaims and passwordHash. JSON files hold an\n")
+		sb.WriteString("array of users or a Firebase CLI export.\n\n")
+		sb.WriteString(labelStyle.Render("File") + s.input.View())
+		if s.stage == importPlanning {
+			sb.WriteString("\n\n" + loadingStyle.UnsetMarginLeft().Render(s.spinner()+" Checking "+s.path+"..."))
+		}
+
+	case importReview:
+		sb.WriteString(highlightStyle.Render(s.path) + ": " + importCounts(s.rows) + "\n\n")
+		sb.WriteString(s.viewport.View())
+		if invalid := len(s.rows) - countValid(s.rows); invalid > 0 {
+			sb.WriteString("\n\n" + fmt.Sprintf("%d invalid %s will be skipped.", invalid, plural(invalid, "row", "rows")))
+		}
+
+	case importRunning:
+		sb.WriteString(loadingStyle.UnsetMarginLeft().Render(fmt.Sprintf("%s Importing users... %d/%d", s.spinner(), s.progress.Done, s.progress.Total)))
+
+	case importDone:
+		sb.WriteString(successStyle.UnsetMarginLeft().Render(importResultText(s.result)))
+		if len(s.result.Failures) > 0 {
+			sb.WriteString("\n\n" + s.viewport.View())
+		}
+	}
+
+	if s.err != "" {
+		sb.WriteString("\n\n" + errorStyle.UnsetMarginLeft().Width(70).Render(s.err))
+	}
+
+	return renderModal(s.width-8, s.height-12, "Import users", sb.String(), newHelp().View(s.KeyMap()))
+}
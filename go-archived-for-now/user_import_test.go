@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"arrogance/firebase"
+
+	"firebase.google.com/go/v4/auth"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// importCSV has a new user, an update to Maya and three invalid rows
+const importCSV = `uid,email,displayName,emailVerified,customClaims
+new-1,new1@example.com,New One,true,"{""role"":""coach""}"
+3xKq9PzR1vT7bN2mYc5LwA8dFe4H,maya.santoso@example.com,Maya S.,true,"{""admin"":true}"
+bad,not-an-email,,maybe,
+clash,dimas.pratama@example.com,,,
+new-1,dup@example.com,,,
+`
+
+// TestPlanImport tests validating an import file and comparing it with the existing accounts
+func TestPlanImport(t *testing.T) {
+	authSvc, _ := CreateDemoBackend(t)
+
+	rows, err := parseImportFile(strings.NewReader(importCSV), "csv")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	rows, err = planImport(context.Background(), authSvc, rows)
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+
+	diff := importDiff(rows)
+	want := []string{
+		"+ new-1 new1@example.com",
+		`~ 3xKq9PzR1vT7bN2mYc5LwA8dFe4H displayName: "Maya Santoso" → "Maya S.", phoneNumber: "+6281234567890" → ""`,
+		"! line 4 bad: not a valid email address; emailVerified must be true or false",
+		"! line 5 clash: email belongs to existing user 7hGt2WsL9qNc4XvB1mZp6RyK3eDu",
+		"! line 6 new-1: uid is also on line 2",
+	}
+	if len(diff) != len(want) {
+		t.Fatalf("Diff has %d lines, want %d:\n%s", len(diff), len(want), strings.Join(diff, "\n"))
+	}
+	for i := range want {
+		if !strings.HasPrefix(diff[i], want[i]) {
+			t.Errorf("Line %d = %q, want %q", i, diff[i], want[i])
+		}
+	}
+	if got := importCounts(rows); got != "1 to create, 1 to update, 0 unchanged, 3 invalid" {
+		t.Errorf("importCounts = %q", got)
+	}
+
+	// Firebase CLI exports use their own names and URL-safe base64
+	export := `{"users": [{"localId": "cli-1", "email": "cli@example.com", "passwordHash": "aGFzaA", "salt": "c2FsdA==", "customAttributes": "{\"admin\":true}"}]}`
+	rows, err = parseImportFile(strings.NewReader(export), "json")
+	if err != nil || len(rows) != 1 || !rows[0].valid() {
+		t.Fatalf("Failed to parse a Firebase export: %v %+v", err, rows)
+	}
+	if u := rows[0].user; u.UID != "cli-1" || string(u.PasswordHash) != "hash" || string(u.PasswordSalt) != "salt" || u.CustomClaims["admin"] != true {
+		t.Errorf("Parsed %+v", u)
+	}
+	if err := checkImportHash(rows, nil); err == nil {
+		t.Error("Expected password hashes to need an algorithm")
+	}
+
+	if _, err := parseImportFile(strings.NewReader("id,mail\n"), "csv"); err == nil || !strings.Contains(err.Error(), `unknown column "id"`) {
+		t.Errorf("Expected unknown columns to be rejected, got %v", err)
+	}
+}
+
+// lookupCountingAuth counts the GetUsers calls made to a MemoryAuth
+type lookupCountingAuth struct {
+	*firebase.MemoryAuth
+	lookups []int
+}
+
+func (c *lookupCountingAuth) GetUsers(ctx context.Context, identifiers []auth.UserIdentifier) (*auth.GetUsersResult, error) {
+	c.lookups = append(c.lookups, len(identifiers))
+	return c.MemoryAuth.GetUsers(ctx, identifiers)
+}
+
+// TestPlanImportBatches tests that existing accounts are looked up in batches
+func TestPlanImportBatches(t *testing.T) {
+	authSvc, _ := CreateDemoBackend(t)
+	users := &lookupCountingAuth{MemoryAuth: authSvc}
+
+	var csv strings.Builder
+	csv.WriteString("uid,email\n")
+	for i := 0; i < 120; i++ {
+		fmt.Fprintf(&csv, "batch-%03d,batch%d@example.com\n", i, i)
+	}
+	csv.WriteString("clash,MAYA.SANTOSO@example.com\n")
+
+	rows, err := parseImportFile(strings.NewReader(csv.String()), "csv")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	rows, err = planImport(context.Background(), users, rows)
+	if err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+
+	if fmt.Sprint(users.lookups) != "[100 100 42]" {
+		t.Errorf("Looked up users in batches of %v, want [100 100 42]", users.lookups)
+	}
+	if got := importCounts(rows); got != "120 to create, 0 to update, 0 unchanged, 1 invalid" {
+		t.Errorf("importCounts = %q", got)
+	}
+	if errs := rows[120].errs; len(errs) != 1 || errs[0] != "email belongs to existing user 3xKq9PzR1vT7bN2mYc5LwA8dFe4H" {
+		t.Errorf("Expected the email clash to ignore case, got %v", errs)
+	}
+}
+
+// TestImportCommand tests the import users command
+func TestImportCommand(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	path := filepath.Join(t.TempDir(), "users.csv")
+	if err := os.WriteFile(path, []byte(importCSV), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		c := cli{stdout: &stdout, stderr: &stderr, connect: func() (firebase.UserStore, firebase.DocumentStore, func(), error) {
+			return authSvc, storeSvc, func() {}, nil
+		}}
+		return c.run(args), stdout.String(), stderr.String()
+	}
+
+	if code, _, _ := run("import", "users"); code != exitUsage {
+		t.Errorf("Expected a usage error without a file, got %d", code)
+	}
+	code, stdout, stderr := run("import", "users", "--dry-run", path)
+	if code != exitFailure || !strings.Contains(stdout, "+ new-1") || !strings.Contains(stderr, "3 invalid rows") {
+		t.Errorf("Expected the dry run to fail on invalid rows, got %d\n%s\n%s", code, stdout, stderr)
+	}
+	if _, err := authSvc.GetUser(context.Background(), "new-1"); err == nil {
+		t.Fatal("Expected the dry run to import nothing")
+	}
+
+	code, stdout, stderr = run("import", "users", path, "--skip-invalid")
+	if code != exitOK || !strings.Contains(stdout, "Imported 2 users") {
+		t.Fatalf("Expected the valid rows to be imported, got %d\n%s\n%s", code, stdout, stderr)
+	}
+	user, err := authSvc.GetUser(context.Background(), "new-1")
+	if err != nil || user.DisplayName != "New One" || user.CustomClaims["role"] != "coach" {
+		t.Errorf("Expected new-1 to be imported, got %+v, %v", user, err)
+	}
+
+	// Password hashes need an algorithm, from the config file or flags
+	jsonPath := filepath.Join(t.TempDir(), "users.json")
+	if err := os.WriteFile(jsonPath, []byte(`[{"uid": "hashed", "passwordHash": "aGFzaA=="}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, stderr := run("import", "users", jsonPath); code != exitFailure || !strings.Contains(stderr, "--hash-algorithm") {
+		t.Errorf("Expected the import to need a hash algorithm, got %d: %s", code, stderr)
+	}
+	if code, _, stderr := run("import", "users", "--hash-algorithm", "BCRYPT", jsonPath); code != exitOK {
+		t.Errorf("Expected the BCRYPT import to succeed, got %d: %s", code, stderr)
+	}
+}
+
+// TestImportScreen tests importing users from the users tab
+func TestImportScreen(t *testing.T) {
+	authSvc, storeSvc := CreateDemoBackend(t)
+	path := filepath.Join(t.TempDir(), "users.csv")
+	if err := os.WriteFile(path, []byte(importCSV), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newTestApp(t, authSvc, storeSvc, UsersTab)
+	m = settle(t, m, keyRunes("I"))
+	if _, ok := m.router.top().(userImportScreen); !ok {
+		t.Fatalf("Expected the import dialog, got %T", m.router.top())
+	}
+
+	m = settle(t, m, keyRunes(path))
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	view := m.View()
+	for _, want := range []string{"1 to create, 1 to update", "3 invalid rows will be skipped", "line 5 clash"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the review:\n%s", want, view)
+		}
+	}
+
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if view := m.View(); !strings.Contains(view, "Imported 2 users") {
+		t.Errorf("Expected the import result:\n%s", view)
+	}
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	if _, ok := m.router.top().(usersScreen); !ok {
+		t.Fatalf("Expected to return to the users, got %T", m.router.top())
+	}
+	if view := m.View(); !strings.Contains(view, "Imported 2 users") || !strings.Contains(view, "new-1") {
+		t.Errorf("Expected the users to be reloaded with the notice:\n%s", view)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"arrogance/firebase"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// linkSettings are the settings of generated email action links, set from
+// the config file before the app starts
+var linkSettings firebase.ActionCodeSettings
+
+// linkKind is a kind of email action link
+type linkKind int
+
+const (
+	linkPasswordReset linkKind = iota
+	linkEmailVerification
+	linkEmailSignIn
+)
+
+// linkKinds are the links that can be generated, in the order they're listed
+var linkKinds = []linkKind{linkPasswordReset, linkEmailVerification, linkEmailSignIn}
+
+// title names the kind of link
+func (k linkKind) title() string {
+	switch k {
+	case linkPasswordReset:
+		return "Password reset"
+	case linkEmailVerification:
+		return "Email verification"
+	default:
+		return "Email sign-in"
+	}
+}
+
+// LinkGeneratedMsg is sent with a generated link
+type linkGeneratedMsg struct {
+	uid  string
+	kind linkKind
+	link string
+}
+
+// LinkErrorMsg is sent when a link can't be generated
+type linkErrorMsg struct {
+	uid string
+	err error
+}
+
+// generateLink generates a link of the given kind for a user
+func generateLink(authSvc firebase.UserStore, user *auth.UserRecord, kind linkKind, settings firebase.ActionCodeSettings) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+
+		var link string
+		var err error
+		switch kind {
+		case linkPasswordReset:
+			link, err = authSvc.PasswordResetLink(ctx, user.Email, &settings)
+		case linkEmailVerification:
+			link, err = authSvc.EmailVerificationLink(ctx, user.Email, &settings)
+		case linkEmailSignIn:
+			link, err = authSvc.EmailSignInLink(ctx, user.Email, &settings)
+		}
+		if errors.Is(err, firebase.ErrContinueURLRequired) {
+			err = errors.New("email sign-in links need a continue URL, set links.continue_url in the config file")
+		}
+		if err != nil {
+			return linkErrorMsg{uid: user.UID, err: err}
+		}
+		return linkGeneratedMsg{uid: user.UID, kind: kind, link: link}
+	}
+}
+
+// userLinksKeyMap holds the keys of the links dialog
+type userLinksKeyMap struct {
+	Up       key.Binding
+	Down     key.Binding
+	Generate key.Binding
+	Copy     key.Binding
+	Close    key.Binding
+}
+
+var userLinksKeys = userLinksKeyMap{
+	Up:       key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "up")),
+	Down:     key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "down")),
+	Generate: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "generate")),
+	Copy:     key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "copy link")),
+	Close:    key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "close")),
+}
+
+// ShortHelp returns the keys shown in the footer
+func (k userLinksKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Up, k.Down, k.Generate, k.Copy, k.Close}
+}
+
+// FullHelp returns every key of the dialog
+func (k userLinksKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+// userLinksScreen generates email action links for a user, to send them by hand
+type userLinksScreen struct {
+	frame
+	authSvc  firebase.UserStore
+	keys     userLinksKeyMap
+	settings firebase.ActionCodeSettings
+
+	user       *auth.UserRecord
+	cursor     int
+	generating bool
+	link       string
+	copied     bool
+	err        string
+}
+
+// newUserLinksScreen creates the links dialog for a user, who must have an email
+func newUserLinksScreen(authSvc firebase.UserStore, user *auth.UserRecord, settings firebase.ActionCodeSettings) userLinksScreen {
+	return userLinksScreen{authSvc: authSvc, keys: userLinksKeys, settings: settings, user: user}
+}
+
+// Init has nothing to load
+func (s userLinksScreen) Init() tea.Cmd {
+	return nil
+}
+
+// KeyMap returns the keys of the dialog, leaving out copying before there's a link
+func (s userLinksScreen) KeyMap() help.KeyMap {
+	k := s.keys
+	k.Copy.SetEnabled(s.link != "")
+	return k
+}
+
+// Loading reports whether a link is being generated
+func (s userLinksScreen) Loading() bool {
+	return s.generating
+}
+
+// CapturesInput keeps every key on the dialog, which is closed with its close key
+func (s userLinksScreen) CapturesInput() bool {
+	return true
+}
+
+// Update picks the kind of link, generates it and copies it
+func (s userLinksScreen) Update(msg tea.Msg) (Screen, tea.Cmd) {
+	s.track(msg)
+
+	switch msg := msg.(type) {
+	case linkGeneratedMsg:
+		if s.generating && msg.uid == s.user.UID && msg.kind == linkKinds[s.cursor] {
+			s.generating = false
+			s.link = msg.link
+		}
+
+	case linkErrorMsg:
+		if s.generating && msg.uid == s.user.UID {
+			s.generating = false
+			s.err = msg.err.Error()
+		}
+
+	case clipboardCopiedMsg:
+		if s.link == "" {
+			return s, nil
+		}
+		if msg.err != nil {
+			s.err = "Failed to copy the link: " + msg.err.Error()
+			return s, nil
+		}
+		s.copied = true
+
+	case tea.KeyMsg:
+		if s.generating {
+			return s, nil
+		}
+		switch {
+		case key.Matches(msg, s.keys.Close):
+			return s, popScreen
+		case key.Matches(msg, s.keys.Up):
+			return s.moveCursor(-1), nil
+		case key.Matches(msg, s.keys.Down):
+			return s.moveCursor(1), nil
+		case key.Matches(msg, s.keys.Generate):
+			s.generating = true
+			s.link = ""
+			s.copied = false
+			s.err = ""
+			return s, generateLink(s.authSvc, s.user, linkKinds[s.cursor], s.settings)
+		case key.Matches(msg, s.keys.Copy):
+			if s.link != "" {
+				return s, copyToClipboard(s.link)
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// moveCursor picks another kind of link, clearing the one generated before
+func (s userLinksScreen) moveCursor(delta int) userLinksScreen {
+	s.cursor = (s.cursor + delta + len(linkKinds)) % len(linkKinds)
+	s.link = ""
+	s.copied = false
+	s.err = ""
+	return s
+}
+
+// View shows the kinds of link and the generated link in a modal
+func (s userLinksScreen) View() string {
+	var sb strings.Builder
+	sb.WriteString("Links for " + highlightStyle.Render(s.user.Email) + "\n\n")
+
+	for i, kind := range linkKinds {
+		line := "  " + kind.title()
+		if i == s.cursor {
+			line = highlightStyle.Render("> " + kind.title())
+		}
+		if kind == linkEmailSignIn && s.settings.ContinueURL == "" {
+			line += labelStyle.UnsetWidth().Render("  needs links.continue_url in the config file")
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	continueURL := s.settings.ContinueURL
+	if continueURL == "" {
+		continueURL = "default Firebase page"
+	}
+	sb.WriteString("\n" + labelStyle.Render("Continue URL") + continueURL)
+
+	switch {
+	case s.generating:
+		sb.WriteString("\n\n" + loadingStyle.UnsetMarginLeft().Render(s.spinner()+" Generating..."))
+	case s.err != "":
+		sb.WriteString("\n\n" + errorStyle.UnsetMarginLeft().Width(70).Render(s.err))
+	case s.link != "":
+		sb.WriteString("\n\n" + linkStyle.Width(70).Render(s.link))
+		if s.copied {
+			sb.WriteString("\n" + successStyle.UnsetMarginLeft().Render("Copied to the clipboard"))
+		}
+	}
+
+	return renderModal(s.width-8, s.height-12, "Email links", sb.String(), newHelp().View(s.KeyMap()))
+}
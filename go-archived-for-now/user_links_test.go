@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TestUserLinks tests generating and copying email action links from the user detail screen
+func TestUserLinks(t *testing.T) {
+	var clipboard bytes.Buffer
+	savedOutput, savedSettings := clipboardOutput, linkSettings
+	t.Cleanup(func() { clipboardOutput, linkSettings = savedOutput, savedSettings })
+	clipboardOutput = &clipboard
+
+	if err := (Config{Links: LinksConfig{ContinueURL: "app.example.com"}}).apply(); err == nil {
+		t.Error("Expected an error for a continue URL without a scheme")
+	}
+	if err := (Config{}).apply(); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+
+	authSvc, storeSvc := CreateDemoBackend(t)
+	m := newTestApp(t, authSvc, storeSvc, UsersTab)
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	m = settle(t, m, keyRunes("l"))
+	if _, ok := m.router.top().(userLinksScreen); !ok {
+		t.Fatalf("Expected the links dialog, got %T", m.router.top())
+	}
+
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	s := m.router.top().(userLinksScreen)
+	if !strings.Contains(s.link, "mode=resetPassword") {
+		t.Fatalf("Expected a password reset link, got %q (error %q)", s.link, s.err)
+	}
+
+	m = settle(t, m, keyRunes("y"))
+	if !strings.Contains(m.View(), "Copied to the clipboard") {
+		t.Error("Expected the link to be reported as copied")
+	}
+	if want := base64.StdEncoding.EncodeToString([]byte(s.link)); !strings.Contains(clipboard.String(), want) {
+		t.Errorf("Expected an OSC52 sequence with the link, got %q", clipboard.String())
+	}
+
+	// Sign-in links need a continue URL from the config
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if s := m.router.top().(userLinksScreen); s.link != "" || !strings.Contains(s.err, "links.continue_url") {
+		t.Errorf("Expected sign-in to ask for a continue URL, got link %q, error %q", s.link, s.err)
+	}
+
+	if err := (Config{Links: LinksConfig{ContinueURL: "https://app.example.com/finish"}}).apply(); err != nil {
+		t.Fatalf("Failed to apply config: %v", err)
+	}
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	m = settle(t, m, keyRunes("l"))
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyUp})
+	m = settle(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if s := m.router.top().(userLinksScreen); !strings.Contains(s.link, "mode=signIn") || !strings.Contains(s.link, "app.example.com%2Ffinish") {
+		t.Errorf("Expected a sign-in link continuing to the app, got %q (error %q)", s.link, s.err)
+	}
+}
@@ -0,0 +1,687 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"arrogance/firebase"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Number of users loaded per page
+const usersPageSize = 100
+
+// UsersLoadedMsg is sent when a page of users is loaded from Firebase
+type usersLoadedMsg struct {
+	users         []*auth.UserRecord
+	pageToken     string
+	nextPageToken string
+}
+
+// UsersErrorMsg is sent when there's an error loading users
+type usersErrorMsg struct {
+	err error
+}
+
+// usersScreen lists the Firebase Auth users a page at a time
+type usersScreen struct {
+	frame
+	authSvc  firebase.UserStore
+	storeSvc firebase.DocumentStore
+	keys     usersKeyMap
+
+	table   table.Model
+	users   []*auth.UserRecord
+	shown   []*auth.UserRecord // users matching the filter, in table order
+	sort    tableSort
+	loading bool
+	err     string
+	notice  string
+
+	// Users selected for bulk actions, by UID
+	marked map[string]bool
+
+	// Filter over the loaded users, applied live while typing
+	filterKeys  inputKeyMap
+	filterInput textinput.Model
+	filtering   bool
+	filterText  string
+	filter      userFilter
+	filterErr   string
+
+	// Pagination, pageTokens[i] is the token that loads page i
+	page          int
+	pageTokens    []string
+	nextPageToken string
+}
+
+// usersKeyMap holds the keys of the users screen
+type usersKeyMap struct {
+	Up       key.Binding
+	Down     key.Binding
+	Open     key.Binding
+	NextPage key.Binding
+	PrevPage key.Binding
+	Filter   key.Binding
+	Sort     key.Binding
+	Reverse  key.Binding
+	Create   key.Binding
+	Edit     key.Binding
+	Disable  key.Binding
+	Revoke   key.Binding
+	Mark     key.Binding
+	MarkAll  key.Binding
+	Grant    key.Binding
+	Import   key.Binding
+	Export   key.Binding
+}
+
+var usersKeys = usersKeyMap{
+	Up:       key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "up")),
+	Down:     key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "down")),
+	Open:     key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "open")),
+	NextPage: key.NewBinding(key.WithKeys("n", "pgdown"), key.WithHelp("n", "next page")),
+	PrevPage: key.NewBinding(key.WithKeys("p", "pgup"), key.WithHelp("p", "previous page")),
+	Filter:   key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "filter")),
+	Sort:     key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "sort column")),
+	Reverse:  key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "reverse sort")),
+	Create:   key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "create user")),
+	Edit:     key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit user")),
+	Disable:  key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "disable/enable user")),
+	Revoke:   key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "sign out everywhere")),
+	Mark:     key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "select")),
+	MarkAll:  key.NewBinding(key.WithKeys("A"), key.WithHelp("A", "select all shown")),
+	Grant:    key.NewBinding(key.WithKeys("g"), key.WithHelp("g", "grant role")),
+	Import:   key.NewBinding(key.WithKeys("I"), key.WithHelp("I", "import users")),
+	Export:   key.NewBinding(key.WithKeys("E"), key.WithHelp("E", "export shown")),
+}
+
+// ShortHelp returns the keys shown in the footer
+func (k usersKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Open, k.Create, k.Edit, k.Filter, k.Sort, k.NextPage, k.PrevPage}
+}
+
+// FullHelp returns every key of the screen
+func (k usersKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{{k.Up, k.Down, k.Open}, {k.Create, k.Edit, k.Disable, k.Revoke}, {k.Import, k.Export}, {k.Mark, k.MarkAll, k.Grant}, {k.Filter, k.Sort, k.Reverse}, {k.NextPage, k.PrevPage}}
+}
+
+// newUsersScreen creates the users screen. Users are loaded when the tab is shown.
+func newUsersScreen(authSvc firebase.UserStore, storeSvc firebase.DocumentStore) usersScreen {
+	return usersScreen{
+		authSvc:    authSvc,
+		storeSvc:   storeSvc,
+		keys:       usersKeys,
+		filterKeys: filterKeys,
+		table:      withRowKeys(initUserTable(), usersKeys.Up, usersKeys.Down),
+		sort:       tableSort{col: userCreatedColumn},
+	}
+}
+
+// Init has nothing to load until the tab is shown
+func (s usersScreen) Init() tea.Cmd {
+	return nil
+}
+
+// KeyMap returns the keys of the users screen, leaving out the ones that
+// do nothing right now, or those of the filter prompt while it's open
+func (s usersScreen) KeyMap() help.KeyMap {
+	if s.filtering {
+		return s.filterKeys
+	}
+	k := s.keys
+	hasRows := !s.loading && s.err == "" && len(s.shown) > 0
+	k.Up.SetEnabled(hasRows)
+	k.Down.SetEnabled(hasRows)
+	k.Open.SetEnabled(hasRows)
+	k.Edit.SetEnabled(hasRows)
+	k.Disable.SetEnabled(hasRows)
+	k.Revoke.SetEnabled(hasRows)
+	if user, ok := s.selected(); ok {
+		k.Disable.SetHelp(k.Disable.Help().Key, strings.ToLower(toggleDisabledAction(user).title()))
+	}
+	k.Mark.SetEnabled(hasRows)
+	k.MarkAll.SetEnabled(hasRows)
+	k.Grant.SetEnabled(hasRows)
+	if n := len(s.marked); n > 0 {
+		k.Grant.SetHelp(k.Grant.Help().Key, fmt.Sprintf("grant role to %d selected", n))
+	}
+	k.Create.SetEnabled(!s.loading)
+	k.Sort.SetEnabled(hasRows)
+	k.Reverse.SetEnabled(hasRows)
+	k.Filter.SetEnabled(!s.loading && s.err == "" && len(s.users) > 0)
+	k.NextPage.SetEnabled(s.nextPageToken != "")
+	k.PrevPage.SetEnabled(s.page > 0)
+	return k
+}
+
+// Loading reports whether a page of users is loading
+func (s usersScreen) Loading() bool {
+	return s.loading
+}
+
+// CapturesInput keeps every key on the screen while typing a filter
+func (s usersScreen) CapturesInput() bool {
+	return s.filtering
+}
+
+// Update handles loading users, paging and opening a user
+func (s usersScreen) Update(msg tea.Msg) (Screen, tea.Cmd) {
+	s.track(msg)
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.table.SetHeight(s.height - 18) // Leave room for the page status and filter prompt
+
+	case screenActivatedMsg:
+		// Reload the current page whenever the tab is shown
+		s.loading = true
+		return s, fetchUsers(s.authSvc, s.pageToken())
+
+	case usersLoadedMsg:
+		// Ignore pages that are no longer the current one
+		if msg.pageToken != s.pageToken() {
+			return s, nil
+		}
+		s.users = msg.users
+		s.nextPageToken = msg.nextPageToken
+		s.loading = false
+		s.err = ""
+		s = s.refreshRows()
+
+	case usersErrorMsg:
+		s.loading = false
+		s.err = fmt.Sprintf("Failed to load users: %v", msg.err)
+
+	case userSavedMsg:
+		if msg.created {
+			// The new user may belong on any page, so reload the current one
+			s.notice = "Created user " + msg.user.UID
+			s.loading = true
+			return s, fetchUsers(s.authSvc, s.pageToken())
+		}
+		s.notice = "Saved user " + msg.user.UID
+		s = s.replaceUser(msg.user)
+
+	case userActionDoneMsg:
+		s.notice = msg.action.past() + " user " + msg.user.UID
+		s = s.replaceUser(msg.user)
+
+	case rolesGrantedMsg:
+		s.notice = grantSummaryText(msg)
+		marked := make(map[string]bool, len(s.marked))
+		for uid := range s.marked {
+			marked[uid] = true
+		}
+		for _, uid := range msg.summary.Updated {
+			delete(marked, uid)
+		}
+		s.marked = marked
+		s = s.refreshRows()
+
+	case usersImportedMsg:
+		// Imported users may belong on any page, so reload the current one
+		s.notice = importResultText(msg.result)
+		s.loading = true
+		return s, fetchUsers(s.authSvc, s.pageToken())
+
+	case userDeletedMsg:
+		// Refresh the page a deleted user was on
+		if msg.err == nil {
+			s.notice = deleteSummaryText(msg.uid, msg.summary)
+			s.loading = true
+			return s, fetchUsers(s.authSvc, s.pageToken())
+		}
+
+	case tea.KeyMsg:
+		return s.handleKey(msg)
+	}
+
+	return s, nil
+}
+
+// handleKey handles the keys of the users screen
+func (s usersScreen) handleKey(msg tea.KeyMsg) (Screen, tea.Cmd) {
+	if s.filtering {
+		return s.updateFilter(msg)
+	}
+
+	switch {
+	case key.Matches(msg, s.keys.Sort):
+		// Sort by the next column
+		if !s.loading {
+			s.sort = s.sort.nextColumn(len(userColumns))
+			s = s.refreshRows()
+		}
+		return s, nil
+	case key.Matches(msg, s.keys.Reverse):
+		// Reverse the sort order
+		if !s.loading {
+			s.sort = s.sort.reversed()
+			s = s.refreshRows()
+		}
+		return s, nil
+	case key.Matches(msg, s.keys.Create):
+		if !s.loading {
+			return s, pushScreen(newUserFormScreen(s.authSvc, nil))
+		}
+		return s, nil
+	case key.Matches(msg, s.keys.Import):
+		if !s.loading {
+			return s, pushScreen(newUserImportScreen(s.authSvc, importHash))
+		}
+		return s, nil
+	case key.Matches(msg, s.keys.Export):
+		if !s.loading && len(s.shown) > 0 {
+			records := make([]map[string]interface{}, len(s.shown))
+			for i, u := range s.shown {
+				records[i] = firebase.ExportUser(&auth.ExportedUserRecord{UserRecord: u}, false)
+			}
+			return s, pushScreen(newExportScreen("users", records, firebase.ExportUserFields))
+		}
+		return s, nil
+	case key.Matches(msg, s.keys.Edit):
+		if user, ok := s.selected(); ok && !s.loading {
+			return s, pushScreen(newUserFormScreen(s.authSvc, user))
+		}
+		return s, nil
+	case key.Matches(msg, s.keys.Disable):
+		if user, ok := s.selected(); ok && !s.loading {
+			return s, pushScreen(newUserActionScreen(s.authSvc, user, toggleDisabledAction(user)))
+		}
+		return s, nil
+	case key.Matches(msg, s.keys.Revoke):
+		if user, ok := s.selected(); ok && !s.loading {
+			return s, pushScreen(newUserActionScreen(s.authSvc, user, actionRevoke))
+		}
+		return s, nil
+	case key.Matches(msg, s.keys.Mark):
+		if user, ok := s.selected(); ok && !s.loading {
+			s = s.setMarked([]*auth.UserRecord{user}, !s.marked[user.UID])
+		}
+		return s, nil
+	case key.Matches(msg, s.keys.MarkAll):
+		// Select every user shown, or clear the selection when they all are
+		if !s.loading && len(s.shown) > 0 {
+			all := true
+			for _, u := range s.shown {
+				all = all && s.marked[u.UID]
+			}
+			s = s.setMarked(s.shown, !all)
+		}
+		return s, nil
+	case key.Matches(msg, s.keys.Grant):
+		// Grant a role to the selected users, or the one under the cursor
+		if user, ok := s.selected(); ok && !s.loading {
+			uids := s.markedUIDs()
+			if len(uids) == 0 {
+				uids = []string{user.UID}
+			}
+			return s, pushScreen(newGrantRoleScreen(s.authSvc, uids))
+		}
+		return s, nil
+	case key.Matches(msg, s.keys.Filter):
+		// Filter the loaded users
+		if s.loading || len(s.users) == 0 {
+			return s, nil
+		}
+		input := textinput.New()
+		input.Prompt = "/"
+		input.Placeholder = "email, name, disabled:true, provider:google.com, created:<2024-01-01"
+		input.SetValue(s.filterText)
+		input.CharLimit = 256
+		input.Width = 60
+		input.Focus()
+		s.filterInput = input
+		s.filtering = true
+		return s, textinput.Blink
+	case key.Matches(msg, s.keys.NextPage):
+		// Load the next page of users
+		if !s.loading && s.nextPageToken != "" {
+			tokens := make([]string, s.page+1, s.page+2)
+			copy(tokens, s.pageTokens)
+			s.pageTokens = append(tokens, s.nextPageToken)
+			s.page++
+			s.loading = true
+			return s, fetchUsers(s.authSvc, s.pageToken())
+		}
+		return s, nil
+	case key.Matches(msg, s.keys.PrevPage):
+		// Load the previous page of users
+		if !s.loading && s.page > 0 {
+			s.page--
+			s.loading = true
+			return s, fetchUsers(s.authSvc, s.pageToken())
+		}
+		return s, nil
+	case key.Matches(msg, s.keys.Open):
+		// Open the selected user
+		if user, ok := s.selected(); ok && !s.loading {
+			return s, pushScreen(newUserDetailScreen(s.authSvc, s.storeSvc, user.UID))
+		}
+		return s, nil
+	}
+
+	var cmd tea.Cmd
+	s.table, cmd = s.table.Update(msg)
+	return s, cmd
+}
+
+// updateFilter handles keys while the filter prompt is open, narrowing the
+// table as the filter is typed
+func (s usersScreen) updateFilter(msg tea.KeyMsg) (Screen, tea.Cmd) {
+	switch {
+	case key.Matches(msg, s.filterKeys.Cancel):
+		// Go back to the filter that was applied before
+		s.filtering = false
+		s.filterErr = ""
+		s.filter, _ = parseUserFilter(s.filterText)
+		return s.refreshRows(), nil
+	case key.Matches(msg, s.filterKeys.Submit):
+		if s.filterErr != "" {
+			return s, nil
+		}
+		s.filtering = false
+		s.filterText = strings.TrimSpace(s.filterInput.Value())
+		return s, nil
+	}
+
+	var cmd tea.Cmd
+	s.filterInput, cmd = s.filterInput.Update(msg)
+
+	// Keep showing the last valid filter while the current one is incomplete
+	filter, err := parseUserFilter(s.filterInput.Value())
+	if err != nil {
+		s.filterErr = err.Error()
+		return s, cmd
+	}
+	s.filterErr = ""
+	s.filter = filter
+	return s.refreshRows(), cmd
+}
+
+// refreshRows shows the loaded users that match the filter, in sort order
+func (s usersScreen) refreshRows() usersScreen {
+	shown := append([]*auth.UserRecord(nil), s.filter.apply(s.users)...)
+	sortItems(shown, s.sort, userSortKey, func(u *auth.UserRecord) string { return u.UID })
+	s.shown = shown
+
+	s.table = setSortedRows(s.table, s.sort.headers(userColumns), userRows(shown, s.marked))
+	s.table.GotoTop()
+	return s
+}
+
+// setMarked selects or unselects users for bulk actions, keeping the cursor
+func (s usersScreen) setMarked(users []*auth.UserRecord, marked bool) usersScreen {
+	set := make(map[string]bool, len(s.marked)+len(users))
+	for uid := range s.marked {
+		set[uid] = true
+	}
+	for _, u := range users {
+		if marked {
+			set[u.UID] = true
+		} else {
+			delete(set, u.UID)
+		}
+	}
+	s.marked = set
+
+	cursor := s.table.Cursor()
+	s = s.refreshRows()
+	s.table.SetCursor(cursor)
+	return s
+}
+
+// markedUIDs returns the UIDs of the users selected for bulk actions, sorted
+func (s usersScreen) markedUIDs() []string {
+	uids := make([]string, 0, len(s.marked))
+	for uid := range s.marked {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	return uids
+}
+
+// replaceUser shows the saved record of a loaded user, keeping it selected
+func (s usersScreen) replaceUser(user *auth.UserRecord) usersScreen {
+	users := make([]*auth.UserRecord, len(s.users))
+	for i, u := range s.users {
+		users[i] = u
+		if u.UID == user.UID {
+			users[i] = user
+		}
+	}
+	s.users = users
+
+	s = s.refreshRows()
+	for i, u := range s.shown {
+		if u.UID == user.UID {
+			s.table.SetCursor(i)
+		}
+	}
+	return s
+}
+
+// selected returns the user under the table cursor
+func (s usersScreen) selected() (*auth.UserRecord, bool) {
+	i := s.table.Cursor()
+	if i < 0 || i >= len(s.shown) {
+		return nil, false
+	}
+	return s.shown[i], true
+}
+
+// pageToken returns the page token of the current page
+func (s usersScreen) pageToken() string {
+	if s.page < len(s.pageTokens) {
+		return s.pageTokens[s.page]
+	}
+	return ""
+}
+
+// pageStatus describes the current page and how many users were loaded
+func (s usersScreen) pageStatus() string {
+	loaded := s.page*usersPageSize + len(s.users)
+	status := fmt.Sprintf("Page %d · %d users on this page · %d loaded", s.page+1, len(s.users), loaded)
+	if s.nextPageToken != "" {
+		status += " · more available"
+	}
+	if !s.filter.empty() {
+		status += fmt.Sprintf(" · %d match the filter", len(s.shown))
+	}
+	if len(s.marked) > 0 {
+		status += fmt.Sprintf(" · %d selected", len(s.marked))
+	}
+	return status
+}
+
+// View shows the users table
+func (s usersScreen) View() string {
+	if s.loading {
+		return s.statusBox(loadingStyle.Render(s.spinner() + " Loading users..."))
+	}
+	if s.err != "" {
+		return s.statusBox(errorStyle.Render("Error loading users: " + s.err))
+	}
+	if len(s.users) == 0 {
+		return s.statusBox("No users found in Firebase Authentication.\n\nTo add users, use the Firebase Console or Authentication SDK.")
+	}
+
+	// Adjust table dimensions based on terminal size, leaving room for the
+	// box padding and the padding of every cell
+	contentWidth := s.width - 12 - 2*len(s.table.Columns())
+
+	// Calculate column widths
+	totalWidth := 0
+	for _, col := range s.table.Columns() {
+		totalWidth += col.Width
+	}
+
+	// Adjust column widths if necessary
+	if totalWidth > contentWidth {
+		ratio := float64(contentWidth) / float64(totalWidth)
+		columns := s.table.Columns()
+		for i := range columns {
+			columns[i].Width = int(float64(columns[i].Width) * ratio)
+		}
+		s.table.SetColumns(columns)
+	}
+
+	tableView := s.table.View()
+	pageStatus := "\n" + s.pageStatus()
+	if s.filtering {
+		pageStatus += "\n\n" + s.filterInput.View()
+		if s.filterErr != "" {
+			pageStatus += "\n" + errorStyle.UnsetMarginLeft().Render(s.filterErr)
+		}
+	} else if s.filterText != "" {
+		pageStatus += "\nFilter: " + s.filterText
+	}
+	if s.notice != "" {
+		pageStatus += "\n" + successStyle.UnsetMarginLeft().Render(s.notice)
+	}
+
+	return lipgloss.NewStyle().
+		Width(s.width-8).
+		Padding(1, 2).
+		Render(tableView + pageStatus)
+}
+
+// userColumns are the columns of the users table
+var userColumns = []table.Column{
+	{Title: "UID", Width: 25},
+	{Title: "Email", Width: 30},
+	{Title: "Display Name", Width: 20},
+	{Title: "Disabled", Width: 10},
+	{Title: "Created", Width: 20},
+	{Title: "Last Sign In", Width: 20},
+	{Title: "Last Activity", Width: 20},
+}
+
+// Users are sorted oldest first until another column is chosen
+const userCreatedColumn = 4
+
+// userSortKey returns the key a user is sorted by in column col of the users table
+func userSortKey(u *auth.UserRecord, col int) string {
+	var meta auth.UserMetadata
+	if u.UserMetadata != nil {
+		meta = *u.UserMetadata
+	}
+
+	switch col {
+	case 0:
+		return u.UID
+	case 1:
+		return strings.ToLower(u.Email)
+	case 2:
+		return strings.ToLower(u.DisplayName)
+	case 3:
+		return yesNo(u.Disabled)
+	case 4:
+		return timestampKey(meta.CreationTimestamp)
+	case 5:
+		return timestampKey(meta.LastLogInTimestamp)
+	default:
+		return timestampKey(meta.LastRefreshTimestamp)
+	}
+}
+
+// userRows converts users to table rows, ticking the marked ones
+func userRows(users []*auth.UserRecord, marked map[string]bool) []table.Row {
+	rows := []table.Row{}
+	for _, user := range users {
+		created := time.Unix(user.UserMetadata.CreationTimestamp/1000, 0).Format("02 Jan 2006, 15:04")
+		lastLogin := "-"
+		if user.UserMetadata.LastLogInTimestamp > 0 {
+			lastLogin = time.Unix(user.UserMetadata.LastLogInTimestamp/1000, 0).Format("02 Jan 2006, 15:04")
+		}
+		lastActivity := "-"
+		if user.UserMetadata.LastRefreshTimestamp > 0 {
+			lastActivity = time.Unix(user.UserMetadata.LastRefreshTimestamp/1000, 0).Format("02 Jan 2006, 15:04")
+		}
+
+		disabled := ""
+		if user.Disabled {
+			disabled = "disabled"
+		}
+
+		uid := user.UserInfo.UID
+		if marked[uid] {
+			uid = "✓ " + uid
+		}
+
+		rows = append(rows, table.Row{
+			uid,
+			user.UserInfo.Email,
+			user.UserInfo.DisplayName,
+			disabled,
+			created,
+			lastLogin,
+			lastActivity,
+		})
+	}
+	return rows
+}
+
+// initUserTable initializes the user table with appropriate columns
+func initUserTable() table.Model {
+	t := table.New(
+		table.WithColumns(append([]table.Column(nil), userColumns...)),
+		table.WithHeight(10),
+		table.WithFocused(true),
+	)
+
+	s := table.DefaultStyles()
+	s.Header = s.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(highlightColor).
+		BorderBottom(true).
+		Bold(true)
+	s.Selected = s.Selected.
+		Foreground(lipgloss.Color("229")).
+		Background(highlightColor).
+		Bold(true)
+
+	t.SetStyles(s)
+
+	return t
+}
+
+// fetchUsers fetches a single page of users from Firebase and returns a tea.Cmd
+func fetchUsers(authSvc firebase.UserStore, pageToken string) tea.Cmd {
+	return func() tea.Msg {
+		if authSvc == nil {
+			return usersErrorMsg{err: errors.New("auth service not initialized")}
+		}
+
+		// Fetch users from Firebase Auth
+		ctx := context.Background()
+
+		page, err := authSvc.ListUsers(ctx, usersPageSize, pageToken)
+		if err != nil {
+			return usersErrorMsg{err: fmt.Errorf("failed to fetch users: %w", err)}
+		}
+
+		users := make([]*auth.UserRecord, 0, len(page.Users))
+		for _, user := range page.Users {
+			users = append(users, user.UserRecord)
+		}
+
+		return usersLoadedMsg{
+			users:         users,
+			pageToken:     pageToken,
+			nextPageToken: page.NextPageToken,
+		}
+	}
+}
@@ -1,28 +1,87 @@
 package main
 
 import (
+	"arrogance/firebase"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 )
 
 func main() {
+	demo := flag.Bool("demo", false, "run against built-in sample data instead of Firebase")
+	emulator := flag.Bool("emulator", false, "run against a local Firebase Emulator Suite")
+	project := flag.String("project", "", "project ID to use with the emulators")
+	configPath := flag.String("config", "", "path to a JSON config file (default "+defaultConfigPath()+")")
+	flag.Usage = usage
+	flag.Parse()
+
+	// Key bindings must be remapped before any screen is created
+	path := *configPath
+	if path == "" {
+		path = defaultConfigPath()
+	}
+	cfg, err := loadConfig(path, *configPath != "")
+	if err == nil {
+		err = cfg.apply()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Config error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Commands print their results instead of starting the TUI
+	switch {
+	case flag.NArg() == 1 && flag.Arg(0) == "tui":
+	case flag.NArg() > 0:
+		os.Exit(runCommand(flag.Args(), backendOptions{demo: *demo, emulator: *emulator, project: *project}))
+	}
+
+	// Demo mode needs no credentials
+	if *demo {
+		realMain(true)
+		return
+	}
+
+	if err := prepareFirebase(*emulator, *project, os.Stdout); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Call the real main function
+	realMain(false)
+}
+
+// prepareFirebase points the Firebase Admin SDK at the emulators or at a
+// validated service account, reporting which to w
+func prepareFirebase(emulator bool, project string, w io.Writer) error {
 	// Enable verbose logging
 	os.Setenv("FIREBASE_DEBUG", "true")
 
+	if emulator {
+		firebase.UseEmulators()
+	}
+
+	// The emulators need no service account
+	if firebase.EmulatorEnabled() {
+		if project != "" {
+			os.Setenv("FIREBASE_PROJECT_ID", project)
+		}
+		fmt.Fprintf(w, "Using Firebase emulators for project: %s\n", firebase.EmulatorProjectID())
+		return nil
+	}
+
 	// Check if the service account file exists and is valid
 	serviceAccountPath, err := CheckServiceAccount()
 	if err != nil {
-		fmt.Printf("Service account validation error: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("Service account validation error: %v", err)
 	}
 
 	// Set the service account path in environment
-	fmt.Printf("Using validated service account at: %s\n", serviceAccountPath)
+	fmt.Fprintf(w, "Using validated service account at: %s\n", serviceAccountPath)
 	os.Setenv("FIREBASE_SERVICE_ACCOUNT", serviceAccountPath)
 
 	// Also set GOOGLE_APPLICATION_CREDENTIALS which is used by the Firebase Admin SDK
 	os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", serviceAccountPath)
-
-	// Call the real main function
-	realMain()
+	return nil
 }